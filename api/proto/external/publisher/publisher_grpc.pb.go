@@ -23,6 +23,9 @@ const _ = grpc.SupportPackageIsVersion7
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type PublisherServiceClient interface {
 	PublishContent(ctx context.Context, in *PublishRequest, opts ...grpc.CallOption) (*PublishResponse, error)
+	// DeleteContent removes a published post. Deleting a post that is already
+	// gone succeeds with status "not_found".
+	DeleteContent(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*DeleteResponse, error)
 }
 
 type publisherServiceClient struct {
@@ -42,11 +45,23 @@ func (c *publisherServiceClient) PublishContent(ctx context.Context, in *Publish
 	return out, nil
 }
 
+func (c *publisherServiceClient) DeleteContent(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*DeleteResponse, error) {
+	out := new(DeleteResponse)
+	err := c.cc.Invoke(ctx, "/publisher.PublisherService/DeleteContent", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // PublisherServiceServer is the server API for PublisherService service.
 // All implementations must embed UnimplementedPublisherServiceServer
 // for forward compatibility
 type PublisherServiceServer interface {
 	PublishContent(context.Context, *PublishRequest) (*PublishResponse, error)
+	// DeleteContent removes a published post. Deleting a post that is already
+	// gone succeeds with status "not_found".
+	DeleteContent(context.Context, *DeleteRequest) (*DeleteResponse, error)
 	mustEmbedUnimplementedPublisherServiceServer()
 }
 
@@ -57,6 +72,9 @@ type UnimplementedPublisherServiceServer struct {
 func (UnimplementedPublisherServiceServer) PublishContent(context.Context, *PublishRequest) (*PublishResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method PublishContent not implemented")
 }
+func (UnimplementedPublisherServiceServer) DeleteContent(context.Context, *DeleteRequest) (*DeleteResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteContent not implemented")
+}
 func (UnimplementedPublisherServiceServer) mustEmbedUnimplementedPublisherServiceServer() {}
 
 // UnsafePublisherServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -88,6 +106,24 @@ func _PublisherService_PublishContent_Handler(srv interface{}, ctx context.Conte
 	return interceptor(ctx, in, info, handler)
 }
 
+func _PublisherService_DeleteContent_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PublisherServiceServer).DeleteContent(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/publisher.PublisherService/DeleteContent",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PublisherServiceServer).DeleteContent(ctx, req.(*DeleteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // PublisherService_ServiceDesc is the grpc.ServiceDesc for PublisherService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -99,6 +135,10 @@ var PublisherService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "PublishContent",
 			Handler:    _PublisherService_PublishContent_Handler,
 		},
+		{
+			MethodName: "DeleteContent",
+			Handler:    _PublisherService_DeleteContent_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "api/proto/external/publisher/publisher.proto",
@@ -23,6 +23,48 @@ const _ = grpc.SupportPackageIsVersion7
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type OrchestratorServiceClient interface {
 	RunPipeline(ctx context.Context, in *PipelineRequest, opts ...grpc.CallOption) (*PipelineResponse, error)
+	// ValidateAccount checks that a flow could run with the given params
+	// without generating or publishing anything.
+	ValidateAccount(ctx context.Context, in *PipelineRequest, opts ...grpc.CallOption) (*ValidateAccountResponse, error)
+	// Run history.
+	GetRun(ctx context.Context, in *GetRunRequest, opts ...grpc.CallOption) (*RunRecord, error)
+	ListRuns(ctx context.Context, in *ListRunsRequest, opts ...grpc.CallOption) (*ListRunsResponse, error)
+	// ResumeRun reruns a run that a server shutdown interrupted, as a new run.
+	ResumeRun(ctx context.Context, in *ResumeRunRequest, opts ...grpc.CallOption) (*PipelineResponse, error)
+	// UnpublishRun deletes every post a run published, including posts
+	// delivered from the outbox, and cancels its outbox entries still waiting.
+	UnpublishRun(ctx context.Context, in *UnpublishRunRequest, opts ...grpc.CallOption) (*UnpublishRunResponse, error)
+	// Credential store administration. Secret values are write-only.
+	PutSecret(ctx context.Context, in *PutSecretRequest, opts ...grpc.CallOption) (*PutSecretResponse, error)
+	DeleteSecret(ctx context.Context, in *DeleteSecretRequest, opts ...grpc.CallOption) (*DeleteSecretResponse, error)
+	ListSecrets(ctx context.Context, in *ListSecretsRequest, opts ...grpc.CallOption) (*ListSecretsResponse, error)
+	// OAuth token lifecycle.
+	ExchangeMetaToken(ctx context.Context, in *ExchangeMetaTokenRequest, opts ...grpc.CallOption) (*AccountToken, error)
+	RefreshAccountToken(ctx context.Context, in *RefreshAccountTokenRequest, opts ...grpc.CallOption) (*AccountToken, error)
+	ListAccountTokens(ctx context.Context, in *ListAccountTokensRequest, opts ...grpc.CallOption) (*ListAccountTokensResponse, error)
+	// Tenants and their platform accounts.
+	ListAccounts(ctx context.Context, in *ListAccountsRequest, opts ...grpc.CallOption) (*ListAccountsResponse, error)
+	// AI usage and budgets for the current day or month.
+	GetUsage(ctx context.Context, in *GetUsageRequest, opts ...grpc.CallOption) (*GetUsageResponse, error)
+	// Circuit breakers guarding downstream services.
+	ListCircuitBreakers(ctx context.Context, in *ListCircuitBreakersRequest, opts ...grpc.CallOption) (*ListCircuitBreakersResponse, error)
+	// Shared rate limiters by model provider, platform and account.
+	ListRateLimits(ctx context.Context, in *ListRateLimitsRequest, opts ...grpc.CallOption) (*ListRateLimitsResponse, error)
+	// Posts deferred by publishing policies, delivered once they are allowed.
+	ListOutbox(ctx context.Context, in *ListOutboxRequest, opts ...grpc.CallOption) (*ListOutboxResponse, error)
+	CancelOutboxEntry(ctx context.Context, in *CancelOutboxEntryRequest, opts ...grpc.CallOption) (*OutboxEntry, error)
+	// ApproveOutboxEntry releases a draft held while publishing was paused.
+	ApproveOutboxEntry(ctx context.Context, in *ApproveOutboxEntryRequest, opts ...grpc.CallOption) (*OutboxEntry, error)
+	// Publishing kill switch: pauses persist until resumed.
+	PausePublishing(ctx context.Context, in *PausePublishingRequest, opts ...grpc.CallOption) (*PublishingPause, error)
+	ResumePublishing(ctx context.Context, in *ResumePublishingRequest, opts ...grpc.CallOption) (*PublishingPause, error)
+	ListPublishingPauses(ctx context.Context, in *ListPublishingPausesRequest, opts ...grpc.CallOption) (*ListPublishingPausesResponse, error)
+	// Items that failed within runs. Items that keep failing are quarantined:
+	// runs skip them until they are retried or discarded.
+	ListDeadLetters(ctx context.Context, in *ListDeadLettersRequest, opts ...grpc.CallOption) (*ListDeadLettersResponse, error)
+	// RetryDeadLetter processes the item again as a new run.
+	RetryDeadLetter(ctx context.Context, in *RetryDeadLetterRequest, opts ...grpc.CallOption) (*PipelineResponse, error)
+	DiscardDeadLetter(ctx context.Context, in *DiscardDeadLetterRequest, opts ...grpc.CallOption) (*DeadLetter, error)
 }
 
 type orchestratorServiceClient struct {
@@ -42,11 +84,269 @@ func (c *orchestratorServiceClient) RunPipeline(ctx context.Context, in *Pipelin
 	return out, nil
 }
 
+func (c *orchestratorServiceClient) ValidateAccount(ctx context.Context, in *PipelineRequest, opts ...grpc.CallOption) (*ValidateAccountResponse, error) {
+	out := new(ValidateAccountResponse)
+	err := c.cc.Invoke(ctx, "/orchestrator.OrchestratorService/ValidateAccount", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orchestratorServiceClient) GetRun(ctx context.Context, in *GetRunRequest, opts ...grpc.CallOption) (*RunRecord, error) {
+	out := new(RunRecord)
+	err := c.cc.Invoke(ctx, "/orchestrator.OrchestratorService/GetRun", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orchestratorServiceClient) ListRuns(ctx context.Context, in *ListRunsRequest, opts ...grpc.CallOption) (*ListRunsResponse, error) {
+	out := new(ListRunsResponse)
+	err := c.cc.Invoke(ctx, "/orchestrator.OrchestratorService/ListRuns", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orchestratorServiceClient) ResumeRun(ctx context.Context, in *ResumeRunRequest, opts ...grpc.CallOption) (*PipelineResponse, error) {
+	out := new(PipelineResponse)
+	err := c.cc.Invoke(ctx, "/orchestrator.OrchestratorService/ResumeRun", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orchestratorServiceClient) UnpublishRun(ctx context.Context, in *UnpublishRunRequest, opts ...grpc.CallOption) (*UnpublishRunResponse, error) {
+	out := new(UnpublishRunResponse)
+	err := c.cc.Invoke(ctx, "/orchestrator.OrchestratorService/UnpublishRun", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orchestratorServiceClient) PutSecret(ctx context.Context, in *PutSecretRequest, opts ...grpc.CallOption) (*PutSecretResponse, error) {
+	out := new(PutSecretResponse)
+	err := c.cc.Invoke(ctx, "/orchestrator.OrchestratorService/PutSecret", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orchestratorServiceClient) DeleteSecret(ctx context.Context, in *DeleteSecretRequest, opts ...grpc.CallOption) (*DeleteSecretResponse, error) {
+	out := new(DeleteSecretResponse)
+	err := c.cc.Invoke(ctx, "/orchestrator.OrchestratorService/DeleteSecret", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orchestratorServiceClient) ListSecrets(ctx context.Context, in *ListSecretsRequest, opts ...grpc.CallOption) (*ListSecretsResponse, error) {
+	out := new(ListSecretsResponse)
+	err := c.cc.Invoke(ctx, "/orchestrator.OrchestratorService/ListSecrets", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orchestratorServiceClient) ExchangeMetaToken(ctx context.Context, in *ExchangeMetaTokenRequest, opts ...grpc.CallOption) (*AccountToken, error) {
+	out := new(AccountToken)
+	err := c.cc.Invoke(ctx, "/orchestrator.OrchestratorService/ExchangeMetaToken", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orchestratorServiceClient) RefreshAccountToken(ctx context.Context, in *RefreshAccountTokenRequest, opts ...grpc.CallOption) (*AccountToken, error) {
+	out := new(AccountToken)
+	err := c.cc.Invoke(ctx, "/orchestrator.OrchestratorService/RefreshAccountToken", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orchestratorServiceClient) ListAccountTokens(ctx context.Context, in *ListAccountTokensRequest, opts ...grpc.CallOption) (*ListAccountTokensResponse, error) {
+	out := new(ListAccountTokensResponse)
+	err := c.cc.Invoke(ctx, "/orchestrator.OrchestratorService/ListAccountTokens", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orchestratorServiceClient) ListAccounts(ctx context.Context, in *ListAccountsRequest, opts ...grpc.CallOption) (*ListAccountsResponse, error) {
+	out := new(ListAccountsResponse)
+	err := c.cc.Invoke(ctx, "/orchestrator.OrchestratorService/ListAccounts", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orchestratorServiceClient) GetUsage(ctx context.Context, in *GetUsageRequest, opts ...grpc.CallOption) (*GetUsageResponse, error) {
+	out := new(GetUsageResponse)
+	err := c.cc.Invoke(ctx, "/orchestrator.OrchestratorService/GetUsage", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orchestratorServiceClient) ListCircuitBreakers(ctx context.Context, in *ListCircuitBreakersRequest, opts ...grpc.CallOption) (*ListCircuitBreakersResponse, error) {
+	out := new(ListCircuitBreakersResponse)
+	err := c.cc.Invoke(ctx, "/orchestrator.OrchestratorService/ListCircuitBreakers", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orchestratorServiceClient) ListRateLimits(ctx context.Context, in *ListRateLimitsRequest, opts ...grpc.CallOption) (*ListRateLimitsResponse, error) {
+	out := new(ListRateLimitsResponse)
+	err := c.cc.Invoke(ctx, "/orchestrator.OrchestratorService/ListRateLimits", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orchestratorServiceClient) ListOutbox(ctx context.Context, in *ListOutboxRequest, opts ...grpc.CallOption) (*ListOutboxResponse, error) {
+	out := new(ListOutboxResponse)
+	err := c.cc.Invoke(ctx, "/orchestrator.OrchestratorService/ListOutbox", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orchestratorServiceClient) CancelOutboxEntry(ctx context.Context, in *CancelOutboxEntryRequest, opts ...grpc.CallOption) (*OutboxEntry, error) {
+	out := new(OutboxEntry)
+	err := c.cc.Invoke(ctx, "/orchestrator.OrchestratorService/CancelOutboxEntry", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orchestratorServiceClient) ApproveOutboxEntry(ctx context.Context, in *ApproveOutboxEntryRequest, opts ...grpc.CallOption) (*OutboxEntry, error) {
+	out := new(OutboxEntry)
+	err := c.cc.Invoke(ctx, "/orchestrator.OrchestratorService/ApproveOutboxEntry", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orchestratorServiceClient) PausePublishing(ctx context.Context, in *PausePublishingRequest, opts ...grpc.CallOption) (*PublishingPause, error) {
+	out := new(PublishingPause)
+	err := c.cc.Invoke(ctx, "/orchestrator.OrchestratorService/PausePublishing", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orchestratorServiceClient) ResumePublishing(ctx context.Context, in *ResumePublishingRequest, opts ...grpc.CallOption) (*PublishingPause, error) {
+	out := new(PublishingPause)
+	err := c.cc.Invoke(ctx, "/orchestrator.OrchestratorService/ResumePublishing", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orchestratorServiceClient) ListPublishingPauses(ctx context.Context, in *ListPublishingPausesRequest, opts ...grpc.CallOption) (*ListPublishingPausesResponse, error) {
+	out := new(ListPublishingPausesResponse)
+	err := c.cc.Invoke(ctx, "/orchestrator.OrchestratorService/ListPublishingPauses", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orchestratorServiceClient) ListDeadLetters(ctx context.Context, in *ListDeadLettersRequest, opts ...grpc.CallOption) (*ListDeadLettersResponse, error) {
+	out := new(ListDeadLettersResponse)
+	err := c.cc.Invoke(ctx, "/orchestrator.OrchestratorService/ListDeadLetters", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orchestratorServiceClient) RetryDeadLetter(ctx context.Context, in *RetryDeadLetterRequest, opts ...grpc.CallOption) (*PipelineResponse, error) {
+	out := new(PipelineResponse)
+	err := c.cc.Invoke(ctx, "/orchestrator.OrchestratorService/RetryDeadLetter", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orchestratorServiceClient) DiscardDeadLetter(ctx context.Context, in *DiscardDeadLetterRequest, opts ...grpc.CallOption) (*DeadLetter, error) {
+	out := new(DeadLetter)
+	err := c.cc.Invoke(ctx, "/orchestrator.OrchestratorService/DiscardDeadLetter", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // OrchestratorServiceServer is the server API for OrchestratorService service.
 // All implementations must embed UnimplementedOrchestratorServiceServer
 // for forward compatibility
 type OrchestratorServiceServer interface {
 	RunPipeline(context.Context, *PipelineRequest) (*PipelineResponse, error)
+	// ValidateAccount checks that a flow could run with the given params
+	// without generating or publishing anything.
+	ValidateAccount(context.Context, *PipelineRequest) (*ValidateAccountResponse, error)
+	// Run history.
+	GetRun(context.Context, *GetRunRequest) (*RunRecord, error)
+	ListRuns(context.Context, *ListRunsRequest) (*ListRunsResponse, error)
+	// ResumeRun reruns a run that a server shutdown interrupted, as a new run.
+	ResumeRun(context.Context, *ResumeRunRequest) (*PipelineResponse, error)
+	// UnpublishRun deletes every post a run published, including posts
+	// delivered from the outbox, and cancels its outbox entries still waiting.
+	UnpublishRun(context.Context, *UnpublishRunRequest) (*UnpublishRunResponse, error)
+	// Credential store administration. Secret values are write-only.
+	PutSecret(context.Context, *PutSecretRequest) (*PutSecretResponse, error)
+	DeleteSecret(context.Context, *DeleteSecretRequest) (*DeleteSecretResponse, error)
+	ListSecrets(context.Context, *ListSecretsRequest) (*ListSecretsResponse, error)
+	// OAuth token lifecycle.
+	ExchangeMetaToken(context.Context, *ExchangeMetaTokenRequest) (*AccountToken, error)
+	RefreshAccountToken(context.Context, *RefreshAccountTokenRequest) (*AccountToken, error)
+	ListAccountTokens(context.Context, *ListAccountTokensRequest) (*ListAccountTokensResponse, error)
+	// Tenants and their platform accounts.
+	ListAccounts(context.Context, *ListAccountsRequest) (*ListAccountsResponse, error)
+	// AI usage and budgets for the current day or month.
+	GetUsage(context.Context, *GetUsageRequest) (*GetUsageResponse, error)
+	// Circuit breakers guarding downstream services.
+	ListCircuitBreakers(context.Context, *ListCircuitBreakersRequest) (*ListCircuitBreakersResponse, error)
+	// Shared rate limiters by model provider, platform and account.
+	ListRateLimits(context.Context, *ListRateLimitsRequest) (*ListRateLimitsResponse, error)
+	// Posts deferred by publishing policies, delivered once they are allowed.
+	ListOutbox(context.Context, *ListOutboxRequest) (*ListOutboxResponse, error)
+	CancelOutboxEntry(context.Context, *CancelOutboxEntryRequest) (*OutboxEntry, error)
+	// ApproveOutboxEntry releases a draft held while publishing was paused.
+	ApproveOutboxEntry(context.Context, *ApproveOutboxEntryRequest) (*OutboxEntry, error)
+	// Publishing kill switch: pauses persist until resumed.
+	PausePublishing(context.Context, *PausePublishingRequest) (*PublishingPause, error)
+	ResumePublishing(context.Context, *ResumePublishingRequest) (*PublishingPause, error)
+	ListPublishingPauses(context.Context, *ListPublishingPausesRequest) (*ListPublishingPausesResponse, error)
+	// Items that failed within runs. Items that keep failing are quarantined:
+	// runs skip them until they are retried or discarded.
+	ListDeadLetters(context.Context, *ListDeadLettersRequest) (*ListDeadLettersResponse, error)
+	// RetryDeadLetter processes the item again as a new run.
+	RetryDeadLetter(context.Context, *RetryDeadLetterRequest) (*PipelineResponse, error)
+	DiscardDeadLetter(context.Context, *DiscardDeadLetterRequest) (*DeadLetter, error)
 	mustEmbedUnimplementedOrchestratorServiceServer()
 }
 
@@ -57,6 +357,78 @@ type UnimplementedOrchestratorServiceServer struct {
 func (UnimplementedOrchestratorServiceServer) RunPipeline(context.Context, *PipelineRequest) (*PipelineResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method RunPipeline not implemented")
 }
+func (UnimplementedOrchestratorServiceServer) ValidateAccount(context.Context, *PipelineRequest) (*ValidateAccountResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ValidateAccount not implemented")
+}
+func (UnimplementedOrchestratorServiceServer) GetRun(context.Context, *GetRunRequest) (*RunRecord, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRun not implemented")
+}
+func (UnimplementedOrchestratorServiceServer) ListRuns(context.Context, *ListRunsRequest) (*ListRunsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListRuns not implemented")
+}
+func (UnimplementedOrchestratorServiceServer) ResumeRun(context.Context, *ResumeRunRequest) (*PipelineResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ResumeRun not implemented")
+}
+func (UnimplementedOrchestratorServiceServer) UnpublishRun(context.Context, *UnpublishRunRequest) (*UnpublishRunResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UnpublishRun not implemented")
+}
+func (UnimplementedOrchestratorServiceServer) PutSecret(context.Context, *PutSecretRequest) (*PutSecretResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PutSecret not implemented")
+}
+func (UnimplementedOrchestratorServiceServer) DeleteSecret(context.Context, *DeleteSecretRequest) (*DeleteSecretResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteSecret not implemented")
+}
+func (UnimplementedOrchestratorServiceServer) ListSecrets(context.Context, *ListSecretsRequest) (*ListSecretsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListSecrets not implemented")
+}
+func (UnimplementedOrchestratorServiceServer) ExchangeMetaToken(context.Context, *ExchangeMetaTokenRequest) (*AccountToken, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ExchangeMetaToken not implemented")
+}
+func (UnimplementedOrchestratorServiceServer) RefreshAccountToken(context.Context, *RefreshAccountTokenRequest) (*AccountToken, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RefreshAccountToken not implemented")
+}
+func (UnimplementedOrchestratorServiceServer) ListAccountTokens(context.Context, *ListAccountTokensRequest) (*ListAccountTokensResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListAccountTokens not implemented")
+}
+func (UnimplementedOrchestratorServiceServer) ListAccounts(context.Context, *ListAccountsRequest) (*ListAccountsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListAccounts not implemented")
+}
+func (UnimplementedOrchestratorServiceServer) GetUsage(context.Context, *GetUsageRequest) (*GetUsageResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetUsage not implemented")
+}
+func (UnimplementedOrchestratorServiceServer) ListCircuitBreakers(context.Context, *ListCircuitBreakersRequest) (*ListCircuitBreakersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListCircuitBreakers not implemented")
+}
+func (UnimplementedOrchestratorServiceServer) ListRateLimits(context.Context, *ListRateLimitsRequest) (*ListRateLimitsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListRateLimits not implemented")
+}
+func (UnimplementedOrchestratorServiceServer) ListOutbox(context.Context, *ListOutboxRequest) (*ListOutboxResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListOutbox not implemented")
+}
+func (UnimplementedOrchestratorServiceServer) CancelOutboxEntry(context.Context, *CancelOutboxEntryRequest) (*OutboxEntry, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CancelOutboxEntry not implemented")
+}
+func (UnimplementedOrchestratorServiceServer) ApproveOutboxEntry(context.Context, *ApproveOutboxEntryRequest) (*OutboxEntry, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ApproveOutboxEntry not implemented")
+}
+func (UnimplementedOrchestratorServiceServer) PausePublishing(context.Context, *PausePublishingRequest) (*PublishingPause, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PausePublishing not implemented")
+}
+func (UnimplementedOrchestratorServiceServer) ResumePublishing(context.Context, *ResumePublishingRequest) (*PublishingPause, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ResumePublishing not implemented")
+}
+func (UnimplementedOrchestratorServiceServer) ListPublishingPauses(context.Context, *ListPublishingPausesRequest) (*ListPublishingPausesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListPublishingPauses not implemented")
+}
+func (UnimplementedOrchestratorServiceServer) ListDeadLetters(context.Context, *ListDeadLettersRequest) (*ListDeadLettersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListDeadLetters not implemented")
+}
+func (UnimplementedOrchestratorServiceServer) RetryDeadLetter(context.Context, *RetryDeadLetterRequest) (*PipelineResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RetryDeadLetter not implemented")
+}
+func (UnimplementedOrchestratorServiceServer) DiscardDeadLetter(context.Context, *DiscardDeadLetterRequest) (*DeadLetter, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DiscardDeadLetter not implemented")
+}
 func (UnimplementedOrchestratorServiceServer) mustEmbedUnimplementedOrchestratorServiceServer() {}
 
 // UnsafeOrchestratorServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -88,16 +460,544 @@ func _OrchestratorService_RunPipeline_Handler(srv interface{}, ctx context.Conte
 	return interceptor(ctx, in, info, handler)
 }
 
-// OrchestratorService_ServiceDesc is the grpc.ServiceDesc for OrchestratorService service.
-// It's only intended for direct use with grpc.RegisterService,
-// and not to be introspected or modified (even as a copy)
-var OrchestratorService_ServiceDesc = grpc.ServiceDesc{
-	ServiceName: "orchestrator.OrchestratorService",
-	HandlerType: (*OrchestratorServiceServer)(nil),
-	Methods: []grpc.MethodDesc{
-		{
-			MethodName: "RunPipeline",
-			Handler:    _OrchestratorService_RunPipeline_Handler,
+func _OrchestratorService_ValidateAccount_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PipelineRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrchestratorServiceServer).ValidateAccount(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/orchestrator.OrchestratorService/ValidateAccount",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrchestratorServiceServer).ValidateAccount(ctx, req.(*PipelineRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrchestratorService_GetRun_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRunRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrchestratorServiceServer).GetRun(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/orchestrator.OrchestratorService/GetRun",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrchestratorServiceServer).GetRun(ctx, req.(*GetRunRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrchestratorService_ListRuns_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListRunsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrchestratorServiceServer).ListRuns(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/orchestrator.OrchestratorService/ListRuns",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrchestratorServiceServer).ListRuns(ctx, req.(*ListRunsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrchestratorService_ResumeRun_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResumeRunRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrchestratorServiceServer).ResumeRun(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/orchestrator.OrchestratorService/ResumeRun",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrchestratorServiceServer).ResumeRun(ctx, req.(*ResumeRunRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrchestratorService_UnpublishRun_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UnpublishRunRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrchestratorServiceServer).UnpublishRun(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/orchestrator.OrchestratorService/UnpublishRun",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrchestratorServiceServer).UnpublishRun(ctx, req.(*UnpublishRunRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrchestratorService_PutSecret_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PutSecretRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrchestratorServiceServer).PutSecret(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/orchestrator.OrchestratorService/PutSecret",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrchestratorServiceServer).PutSecret(ctx, req.(*PutSecretRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrchestratorService_DeleteSecret_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteSecretRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrchestratorServiceServer).DeleteSecret(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/orchestrator.OrchestratorService/DeleteSecret",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrchestratorServiceServer).DeleteSecret(ctx, req.(*DeleteSecretRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrchestratorService_ListSecrets_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListSecretsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrchestratorServiceServer).ListSecrets(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/orchestrator.OrchestratorService/ListSecrets",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrchestratorServiceServer).ListSecrets(ctx, req.(*ListSecretsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrchestratorService_ExchangeMetaToken_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExchangeMetaTokenRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrchestratorServiceServer).ExchangeMetaToken(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/orchestrator.OrchestratorService/ExchangeMetaToken",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrchestratorServiceServer).ExchangeMetaToken(ctx, req.(*ExchangeMetaTokenRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrchestratorService_RefreshAccountToken_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RefreshAccountTokenRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrchestratorServiceServer).RefreshAccountToken(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/orchestrator.OrchestratorService/RefreshAccountToken",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrchestratorServiceServer).RefreshAccountToken(ctx, req.(*RefreshAccountTokenRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrchestratorService_ListAccountTokens_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListAccountTokensRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrchestratorServiceServer).ListAccountTokens(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/orchestrator.OrchestratorService/ListAccountTokens",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrchestratorServiceServer).ListAccountTokens(ctx, req.(*ListAccountTokensRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrchestratorService_ListAccounts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListAccountsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrchestratorServiceServer).ListAccounts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/orchestrator.OrchestratorService/ListAccounts",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrchestratorServiceServer).ListAccounts(ctx, req.(*ListAccountsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrchestratorService_GetUsage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetUsageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrchestratorServiceServer).GetUsage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/orchestrator.OrchestratorService/GetUsage",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrchestratorServiceServer).GetUsage(ctx, req.(*GetUsageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrchestratorService_ListCircuitBreakers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListCircuitBreakersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrchestratorServiceServer).ListCircuitBreakers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/orchestrator.OrchestratorService/ListCircuitBreakers",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrchestratorServiceServer).ListCircuitBreakers(ctx, req.(*ListCircuitBreakersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrchestratorService_ListRateLimits_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListRateLimitsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrchestratorServiceServer).ListRateLimits(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/orchestrator.OrchestratorService/ListRateLimits",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrchestratorServiceServer).ListRateLimits(ctx, req.(*ListRateLimitsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrchestratorService_ListOutbox_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListOutboxRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrchestratorServiceServer).ListOutbox(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/orchestrator.OrchestratorService/ListOutbox",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrchestratorServiceServer).ListOutbox(ctx, req.(*ListOutboxRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrchestratorService_CancelOutboxEntry_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CancelOutboxEntryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrchestratorServiceServer).CancelOutboxEntry(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/orchestrator.OrchestratorService/CancelOutboxEntry",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrchestratorServiceServer).CancelOutboxEntry(ctx, req.(*CancelOutboxEntryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrchestratorService_ApproveOutboxEntry_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ApproveOutboxEntryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrchestratorServiceServer).ApproveOutboxEntry(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/orchestrator.OrchestratorService/ApproveOutboxEntry",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrchestratorServiceServer).ApproveOutboxEntry(ctx, req.(*ApproveOutboxEntryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrchestratorService_PausePublishing_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PausePublishingRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrchestratorServiceServer).PausePublishing(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/orchestrator.OrchestratorService/PausePublishing",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrchestratorServiceServer).PausePublishing(ctx, req.(*PausePublishingRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrchestratorService_ResumePublishing_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResumePublishingRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrchestratorServiceServer).ResumePublishing(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/orchestrator.OrchestratorService/ResumePublishing",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrchestratorServiceServer).ResumePublishing(ctx, req.(*ResumePublishingRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrchestratorService_ListPublishingPauses_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListPublishingPausesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrchestratorServiceServer).ListPublishingPauses(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/orchestrator.OrchestratorService/ListPublishingPauses",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrchestratorServiceServer).ListPublishingPauses(ctx, req.(*ListPublishingPausesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrchestratorService_ListDeadLetters_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListDeadLettersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrchestratorServiceServer).ListDeadLetters(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/orchestrator.OrchestratorService/ListDeadLetters",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrchestratorServiceServer).ListDeadLetters(ctx, req.(*ListDeadLettersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrchestratorService_RetryDeadLetter_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RetryDeadLetterRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrchestratorServiceServer).RetryDeadLetter(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/orchestrator.OrchestratorService/RetryDeadLetter",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrchestratorServiceServer).RetryDeadLetter(ctx, req.(*RetryDeadLetterRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrchestratorService_DiscardDeadLetter_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DiscardDeadLetterRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrchestratorServiceServer).DiscardDeadLetter(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/orchestrator.OrchestratorService/DiscardDeadLetter",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrchestratorServiceServer).DiscardDeadLetter(ctx, req.(*DiscardDeadLetterRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// OrchestratorService_ServiceDesc is the grpc.ServiceDesc for OrchestratorService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var OrchestratorService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "orchestrator.OrchestratorService",
+	HandlerType: (*OrchestratorServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "RunPipeline",
+			Handler:    _OrchestratorService_RunPipeline_Handler,
+		},
+		{
+			MethodName: "ValidateAccount",
+			Handler:    _OrchestratorService_ValidateAccount_Handler,
+		},
+		{
+			MethodName: "GetRun",
+			Handler:    _OrchestratorService_GetRun_Handler,
+		},
+		{
+			MethodName: "ListRuns",
+			Handler:    _OrchestratorService_ListRuns_Handler,
+		},
+		{
+			MethodName: "ResumeRun",
+			Handler:    _OrchestratorService_ResumeRun_Handler,
+		},
+		{
+			MethodName: "UnpublishRun",
+			Handler:    _OrchestratorService_UnpublishRun_Handler,
+		},
+		{
+			MethodName: "PutSecret",
+			Handler:    _OrchestratorService_PutSecret_Handler,
+		},
+		{
+			MethodName: "DeleteSecret",
+			Handler:    _OrchestratorService_DeleteSecret_Handler,
+		},
+		{
+			MethodName: "ListSecrets",
+			Handler:    _OrchestratorService_ListSecrets_Handler,
+		},
+		{
+			MethodName: "ExchangeMetaToken",
+			Handler:    _OrchestratorService_ExchangeMetaToken_Handler,
+		},
+		{
+			MethodName: "RefreshAccountToken",
+			Handler:    _OrchestratorService_RefreshAccountToken_Handler,
+		},
+		{
+			MethodName: "ListAccountTokens",
+			Handler:    _OrchestratorService_ListAccountTokens_Handler,
+		},
+		{
+			MethodName: "ListAccounts",
+			Handler:    _OrchestratorService_ListAccounts_Handler,
+		},
+		{
+			MethodName: "GetUsage",
+			Handler:    _OrchestratorService_GetUsage_Handler,
+		},
+		{
+			MethodName: "ListCircuitBreakers",
+			Handler:    _OrchestratorService_ListCircuitBreakers_Handler,
+		},
+		{
+			MethodName: "ListRateLimits",
+			Handler:    _OrchestratorService_ListRateLimits_Handler,
+		},
+		{
+			MethodName: "ListOutbox",
+			Handler:    _OrchestratorService_ListOutbox_Handler,
+		},
+		{
+			MethodName: "CancelOutboxEntry",
+			Handler:    _OrchestratorService_CancelOutboxEntry_Handler,
+		},
+		{
+			MethodName: "ApproveOutboxEntry",
+			Handler:    _OrchestratorService_ApproveOutboxEntry_Handler,
+		},
+		{
+			MethodName: "PausePublishing",
+			Handler:    _OrchestratorService_PausePublishing_Handler,
+		},
+		{
+			MethodName: "ResumePublishing",
+			Handler:    _OrchestratorService_ResumePublishing_Handler,
+		},
+		{
+			MethodName: "ListPublishingPauses",
+			Handler:    _OrchestratorService_ListPublishingPauses_Handler,
+		},
+		{
+			MethodName: "ListDeadLetters",
+			Handler:    _OrchestratorService_ListDeadLetters_Handler,
+		},
+		{
+			MethodName: "RetryDeadLetter",
+			Handler:    _OrchestratorService_RetryDeadLetter_Handler,
+		},
+		{
+			MethodName: "DiscardDeadLetter",
+			Handler:    _OrchestratorService_DiscardDeadLetter_Handler,
 		},
 	},
 	Streams:  []grpc.StreamDesc{},
@@ -9,8 +9,8 @@ import (
 	"time"
 
 	pb "github.com/Optiq-CTO/orchestrator/api/proto"
+	"github.com/Optiq-CTO/orchestrator/internal/auth"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 	"gopkg.in/yaml.v3"
 )
 
@@ -21,9 +21,12 @@ type Config struct {
 }
 
 type User struct {
-	ID          string            `yaml:"id"`
-	Name        string            `yaml:"name"`
-	Platform    string            `yaml:"platform"`
+	ID       string `yaml:"id"`
+	Name     string `yaml:"name"`
+	Platform string `yaml:"platform"`
+	// AccountID names an account registered with the orchestrator; its
+	// params, credentials and model provider are kept server-side.
+	AccountID   string            `yaml:"account_id"`
 	Credentials map[string]string `yaml:"credentials"`
 	Pipelines   []Pipeline        `yaml:"pipelines"`
 }
@@ -47,9 +50,26 @@ type ExecutionResult struct {
 func main() {
 	configPath := flag.String("config", "../../users.yaml", "Path to users.yaml configuration file")
 	orchestratorAddr := flag.String("orchestrator", "localhost:50056", "Orchestrator service address")
-	modelProvider := flag.String("model", "gemini", "AI model provider (gemini or openai)")
+	modelProvider := flag.String("model", "", "AI model provider (gemini or openai); defaults to the account's provider for registered accounts, else gemini")
+	apiKey := flag.String("api_key", os.Getenv("ORCHESTRATOR_API_KEY"), "Orchestrator API key")
+	caCert := flag.String("ca_cert", os.Getenv("ORCHESTRATOR_CA_CERT"), "CA certificate for a TLS orchestrator endpoint")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] [validate]\n\n", os.Args[0])
+		fmt.Fprintln(flag.CommandLine.Output(), "Runs every enabled pipeline, or with \"validate\" only checks that each one is ready to run.")
+		flag.PrintDefaults()
+	}
 	flag.Parse()
 
+	validateOnly := false
+	switch flag.Arg(0) {
+	case "":
+	case "validate":
+		validateOnly = true
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+
 	// 1. Load configuration
 	log.Printf("Loading configuration from: %s", *configPath)
 	config, err := loadConfig(*configPath)
@@ -60,23 +80,28 @@ func main() {
 
 	// 2. Connect to Orchestrator
 	log.Printf("Connecting to Orchestrator at %s", *orchestratorAddr)
-	conn, err := grpc.Dial(*orchestratorAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	dialOpts, err := auth.ClientDialOptions(*apiKey, *caCert)
+	if err != nil {
+		log.Fatalf("Failed to configure connection: %v", err)
+	}
+	conn, err := grpc.Dial(*orchestratorAddr, dialOpts...)
 	if err != nil {
 		log.Fatalf("Failed to connect to orchestrator: %v", err)
 	}
 	defer conn.Close()
 	client := pb.NewOrchestratorServiceClient(conn)
 
+	if validateOnly {
+		validateAll(client, config, *modelProvider)
+		return
+	}
+
 	// 3. Execute pipelines for each user
 	results := []ExecutionResult{}
 
 	log.Println("\n===== Starting Batch Pipeline Execution =====")
+	// The orchestrator rate limits model providers and platforms itself.
 	for i, user := range config.Users {
-		if i > 0 {
-			log.Println("\n[Rate Limit] Waiting 15s between users to respect Gemini API limits...")
-			time.Sleep(15 * time.Second)
-		}
-
 		log.Printf("\n[%d/%d] Processing user: %s (ID: %s)", i+1, len(config.Users), user.Name, user.ID)
 
 		for _, pipeline := range user.Pipelines {
@@ -131,21 +156,8 @@ func executePipeline(client pb.OrchestratorServiceClient, user User, pipeline Pi
 	ctx, cancel := context.WithTimeout(context.Background(), 120*time.Second)
 	defer cancel()
 
-	// Map pipeline name to flow and prepare params
-	flowName := pipeline.Name
-	params := make(map[string]string)
-
-	// Copy all credentials to params
-	for k, v := range user.Credentials {
-		params[k] = v
-	}
-
 	// Execute pipeline
-	res, err := client.RunPipeline(ctx, &pb.PipelineRequest{
-		FlowName:      flowName,
-		Params:        params,
-		ModelProvider: modelProvider,
-	})
+	res, err := client.RunPipeline(ctx, pipelineRequest(user, pipeline, modelProvider))
 
 	if err != nil {
 		result.Status = "failed"
@@ -164,6 +176,80 @@ func executePipeline(client pb.OrchestratorServiceClient, user User, pipeline Pi
 	return result
 }
 
+// pipelineRequest maps a user's pipeline to a flow and its params.
+func pipelineRequest(user User, pipeline Pipeline, modelProvider string) *pb.PipelineRequest {
+	if user.AccountID != "" {
+		return &pb.PipelineRequest{
+			FlowName:      pipeline.Name,
+			AccountId:     user.AccountID,
+			Params:        map[string]string{"user_id": user.ID},
+			ModelProvider: modelProvider,
+			Priority:      "scheduled",
+		}
+	}
+	if modelProvider == "" {
+		modelProvider = "gemini"
+	}
+	params := make(map[string]string)
+
+	// Copy all credentials to params
+	for k, v := range user.Credentials {
+		params[k] = v
+	}
+	// Lets the orchestrator attribute logs and run records to this user
+	params["user_id"] = user.ID
+
+	return &pb.PipelineRequest{
+		FlowName:      pipeline.Name,
+		Params:        params,
+		ModelProvider: modelProvider,
+		Priority:      "scheduled",
+	}
+}
+
+// validateAll runs the orchestrator's preflight checks for every enabled
+// pipeline and reports readiness per account. Nothing is published.
+func validateAll(client pb.OrchestratorServiceClient, config *Config, modelProvider string) {
+	log.Println("\n===== Validating Accounts =====")
+	notReady := 0
+	for _, user := range config.Users {
+		for _, pipeline := range user.Pipelines {
+			if !pipeline.Enabled {
+				continue
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+			res, err := client.ValidateAccount(ctx, pipelineRequest(user, pipeline, modelProvider))
+			cancel()
+
+			if err != nil {
+				notReady++
+				log.Printf("❌ %s - %s: %v", user.Name, pipeline.Name, err)
+				continue
+			}
+			icon := "✅"
+			if !res.Ready {
+				notReady++
+				icon = "❌"
+			}
+			log.Printf("%s %s - %s", icon, user.Name, pipeline.Name)
+			for _, c := range res.Checks {
+				mark := "ok"
+				if !c.Passed {
+					mark = "FAIL"
+				}
+				log.Printf("   [%s] %s: %s", mark, c.Name, c.Message)
+			}
+		}
+	}
+
+	if notReady > 0 {
+		log.Printf("\nWARNING: %d pipeline(s) not ready.", notReady)
+		os.Exit(1)
+	}
+	log.Println("\nAll pipelines ready.")
+}
+
 func printSummary(results []ExecutionResult) {
 	log.Println("\n\n===== Execution Summary =====")
 
@@ -4,11 +4,12 @@ import (
 	"context"
 	"flag"
 	"log"
+	"os"
 	"time"
 
 	pb "github.com/Optiq-CTO/orchestrator/api/proto"
+	"github.com/Optiq-CTO/orchestrator/internal/auth"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 )
 
 func main() {
@@ -20,7 +21,11 @@ func main() {
 		log.Fatal("Usage: go run main.go -page_id=YOUR_PAGE_ID -access_token=YOUR_TOKEN")
 	}
 
-	conn, err := grpc.Dial("localhost:50056", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	dialOpts, err := auth.ClientDialOptions(os.Getenv("ORCHESTRATOR_API_KEY"), os.Getenv("ORCHESTRATOR_CA_CERT"))
+	if err != nil {
+		log.Fatalf("failed to configure connection: %v", err)
+	}
+	conn, err := grpc.Dial("localhost:50056", dialOpts...)
 	if err != nil {
 		log.Fatalf("did not connect: %v", err)
 	}
@@ -2,16 +2,25 @@ package main
 
 import (
 	"context"
+	"flag"
 	"log"
+	"os"
 	"time"
 
 	pb "github.com/Optiq-CTO/orchestrator/api/proto"
+	"github.com/Optiq-CTO/orchestrator/internal/auth"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 )
 
 func main() {
-	conn, err := grpc.Dial("localhost:50056", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	targetAccount := flag.String("target_account", "", "Credential store account to publish with (default: the publisher's own credentials)")
+	flag.Parse()
+
+	dialOpts, err := auth.ClientDialOptions(os.Getenv("ORCHESTRATOR_API_KEY"), os.Getenv("ORCHESTRATOR_CA_CERT"))
+	if err != nil {
+		log.Fatalf("failed to configure connection: %v", err)
+	}
+	conn, err := grpc.Dial("localhost:50056", dialOpts...)
 	if err != nil {
 		log.Fatalf("did not connect: %v", err)
 	}
@@ -24,12 +33,16 @@ func main() {
 	log.Println("--- Triggering Cross-Pollinator Pipeline ---")
 	log.Println("Goal: Fetch Reddit(golang) -> Analyze -> Remix -> Publish(Twitter)")
 
+	params := map[string]string{
+		"query":           "golang",
+		"target_platform": "twitter",
+	}
+	if *targetAccount != "" {
+		params["target_account"] = *targetAccount
+	}
 	res, err := c.RunPipeline(ctx, &pb.PipelineRequest{
 		FlowName: "cross_pollinator",
-		Params: map[string]string{
-			"query":           "golang",
-			"target_platform": "twitter",
-		},
+		Params:   params,
 	})
 
 	if err != nil {
@@ -1,88 +1,426 @@
 package main
 
 import (
-	"log"
+	"context"
+	"errors"
+	"flag"
+	"log/slog"
 	"net"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	analyzer "github.com/Optiq-CTO/analyzer/api/proto"
 	pb "github.com/Optiq-CTO/orchestrator/api/proto"
 	aicontext "github.com/Optiq-CTO/orchestrator/api/proto/external/aicontext"
 	creator "github.com/Optiq-CTO/orchestrator/api/proto/external/creator"
 	fetcher "github.com/Optiq-CTO/orchestrator/api/proto/external/fetcher"
 	publisher "github.com/Optiq-CTO/orchestrator/api/proto/external/publisher"
+	"github.com/Optiq-CTO/orchestrator/internal/admission"
+	"github.com/Optiq-CTO/orchestrator/internal/analysiscache"
+	"github.com/Optiq-CTO/orchestrator/internal/auth"
+	"github.com/Optiq-CTO/orchestrator/internal/breaker"
+	"github.com/Optiq-CTO/orchestrator/internal/config"
+	"github.com/Optiq-CTO/orchestrator/internal/deadletter"
+	"github.com/Optiq-CTO/orchestrator/internal/health"
+	"github.com/Optiq-CTO/orchestrator/internal/leader"
+	"github.com/Optiq-CTO/orchestrator/internal/lease"
+	"github.com/Optiq-CTO/orchestrator/internal/logging"
+	"github.com/Optiq-CTO/orchestrator/internal/metrics"
+	"github.com/Optiq-CTO/orchestrator/internal/pause"
+	"github.com/Optiq-CTO/orchestrator/internal/publishing"
+	"github.com/Optiq-CTO/orchestrator/internal/ratelimit"
+	"github.com/Optiq-CTO/orchestrator/internal/routing"
+	"github.com/Optiq-CTO/orchestrator/internal/runs"
+	"github.com/Optiq-CTO/orchestrator/internal/secrets"
 	"github.com/Optiq-CTO/orchestrator/internal/service"
+	"github.com/Optiq-CTO/orchestrator/internal/tenant"
+	"github.com/Optiq-CTO/orchestrator/internal/tokens"
+	"github.com/Optiq-CTO/orchestrator/internal/usage"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/reflection"
 )
 
 func main() {
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "50056"
+	configPath := flag.String("config", os.Getenv("CONFIG_FILE"), "Path to the server config file; environment variables override it")
+	printConfig := flag.Bool("print-config", false, "Print the effective config and exit")
+	flag.Parse()
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		slog.Error("invalid configuration", "error", err)
+		os.Exit(1)
+	}
+	if *printConfig {
+		out, err := cfg.Marshal()
+		if err != nil {
+			slog.Error("failed to encode configuration", "error", err)
+			os.Exit(1)
+		}
+		os.Stdout.Write(out)
+		return
 	}
 
-	// Connect to Fetcher
-	fetcherHost := os.Getenv("FETCHER_HOST")
-	if fetcherHost == "" {
-		fetcherHost = "localhost:50053"
+	logger, err := logging.New(os.Stderr, cfg.Logging.Level, cfg.Logging.Format)
+	if err != nil {
+		slog.Error("invalid logging configuration", "error", err)
+		os.Exit(1)
+	}
+	slog.SetDefault(logger)
+
+	// SIGINT or SIGTERM starts a graceful shutdown: in-flight runs get up to
+	// the drain timeout to finish before they are interrupted.
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// AI usage is always tracked; budgets are optional. The fetcher and
+	// creator connections count the calls, since both spend model tokens.
+	var budgets []usage.Budget
+	if path := cfg.Files.Budgets; path != "" {
+		budgetCfg, err := usage.LoadConfig(path)
+		if err != nil {
+			fatal(logger, "failed to load budgets", err)
+		}
+		budgets = budgetCfg.Budgets
+	}
+	usageTracker := usage.NewTracker(budgets)
+	if path := cfg.Files.Usage; path != "" {
+		if usageTracker, err = usage.OpenTracker(path, budgets); err != nil {
+			fatal(logger, "failed to open usage file", err)
+		}
 	}
-	connFetcher, err := grpc.Dial(fetcherHost, grpc.WithTransportCredentials(insecure.NewCredentials()))
+
+	// Every downstream call goes through a circuit breaker keyed by service
+	// and platform, or model provider for the AI-backed services.
+	breakers := breaker.NewSet(cfg.Breakers)
+
+	// Rate limits are shared by every run: AI calls wait on their model
+	// provider, platform API calls on their platform and account.
+	limiters := ratelimit.New(cfg.RateLimits)
+
+	// Connect to Fetcher
+	connFetcher, err := grpc.Dial(cfg.Downstream.Fetcher,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithChainUnaryInterceptor(
+			breakers.UnaryClientInterceptor("fetcher", breaker.PlatformKey),
+			limiters.ProviderInterceptor(),
+			limiters.PlatformInterceptor(),
+			metrics.UnaryClientInterceptor("fetcher"),
+			usageTracker.UnaryClientInterceptor()))
 	if err != nil {
-		log.Fatalf("failed to connect to fetcher: %v", err)
+		fatal(logger, "failed to connect to fetcher", err)
 	}
 	defer connFetcher.Close()
 	fetcherClient := fetcher.NewFetcherServiceClient(connFetcher)
 
 	// Connect to Creator
-	creatorHost := os.Getenv("CREATOR_HOST")
-	if creatorHost == "" {
-		creatorHost = "localhost:50054"
-	}
-	connCreator, err := grpc.Dial(creatorHost, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	connCreator, err := grpc.Dial(cfg.Downstream.Creator,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithChainUnaryInterceptor(
+			breakers.UnaryClientInterceptor("creator", breaker.ModelProviderKey),
+			limiters.ProviderInterceptor(),
+			metrics.UnaryClientInterceptor("creator"),
+			usageTracker.UnaryClientInterceptor()))
 	if err != nil {
-		log.Fatalf("failed to connect to creator: %v", err)
+		fatal(logger, "failed to connect to creator", err)
 	}
 	defer connCreator.Close()
 	creatorClient := creator.NewCreatorServiceClient(connCreator)
 
 	// Connect to Publisher
-	publisherHost := os.Getenv("PUBLISHER_HOST")
-	if publisherHost == "" {
-		publisherHost = "localhost:50055"
-	}
-	connPub, err := grpc.Dial(publisherHost, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	connPub, err := grpc.Dial(cfg.Downstream.Publisher,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithChainUnaryInterceptor(
+			breakers.UnaryClientInterceptor("publisher", breaker.PlatformKey),
+			limiters.PlatformInterceptor(),
+			metrics.UnaryClientInterceptor("publisher")))
 	if err != nil {
-		log.Fatalf("failed to connect to publisher: %v", err)
+		fatal(logger, "failed to connect to publisher", err)
 	}
 	defer connPub.Close()
 	pubClient := publisher.NewPublisherServiceClient(connPub)
 
 	// Connect to AIContext
-	aiContextHost := os.Getenv("AICONTEXT_HOST")
-	if aiContextHost == "" {
-		aiContextHost = "localhost:50057"
-	}
-	connAIContext, err := grpc.Dial(aiContextHost, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	connAIContext, err := grpc.Dial(cfg.Downstream.AIContext,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithChainUnaryInterceptor(
+			breakers.UnaryClientInterceptor("aicontext", breaker.PlatformKey),
+			metrics.UnaryClientInterceptor("aicontext")))
 	if err != nil {
-		log.Fatalf("failed to connect to aicontext: %v", err)
+		fatal(logger, "failed to connect to aicontext", err)
 	}
 	defer connAIContext.Close()
 	aiContextClient := aicontext.NewAIContextServiceClient(connAIContext)
 
+	// Connect to Analyzer, used to score best-of-N candidates
+	connAnalyzer, err := grpc.Dial(cfg.Downstream.Analyzer,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithChainUnaryInterceptor(
+			breakers.UnaryClientInterceptor("analyzer", breaker.ModelProviderKey),
+			limiters.ProviderInterceptor(),
+			metrics.UnaryClientInterceptor("analyzer"),
+			usageTracker.UnaryClientInterceptor()))
+	if err != nil {
+		fatal(logger, "failed to connect to analyzer", err)
+	}
+	defer connAnalyzer.Close()
+	analyzerClient := analyzer.NewAnalyzerServiceClient(connAnalyzer)
+
 	// Start Orchestrator
-	lis, err := net.Listen("tcp", ":"+port)
+	lis, err := net.Listen("tcp", ":"+cfg.Server.Port)
 	if err != nil {
-		log.Fatalf("failed to listen: %v", err)
+		fatal(logger, "failed to listen", err)
+	}
+
+	var runStore runs.Store = runs.NewMemoryStore(cfg.Runs.MemoryLimit)
+	if dir := cfg.Runs.Dir; dir != "" {
+		fs, err := runs.NewFileStore(dir)
+		if err != nil {
+			fatal(logger, "failed to open run store", err)
+		}
+		runStore = fs
+	}
+
+	// Replicas on one host share run leases and elect a leader through a
+	// locked lease file; alone, a replica leases in memory and always leads.
+	var leases lease.Store = lease.NewMemoryStore()
+	if path := cfg.Files.Leases; path != "" {
+		fileLeases, err := lease.OpenFileStore(path)
+		if err != nil {
+			fatal(logger, "failed to open lease file", err)
+		}
+		leases = fileLeases
+	}
+	replica := cfg.Leader.ID
+	if replica == "" {
+		replica = leader.DefaultID()
+	}
+	elector := leader.New(leases, replica, cfg.Leader.TTL, logger)
+
+	opts := []service.Option{
+		service.WithLogger(logger),
+		service.WithRunStore(runStore),
+		service.WithUsageTracker(usageTracker),
+		service.WithAnalyzer(analyzerClient),
+		service.WithFlows(cfg.Flows),
+		service.WithRetryPolicies(cfg.Retries),
+		service.WithBreakers(breakers),
+		service.WithRateLimits(limiters),
+		service.WithAdmission(admission.New(cfg.Admission)),
+		service.WithLeases(leases, cfg.Leases.TTL, cfg.Leases.OnConflict),
+	}
+	if path := cfg.Files.Secrets; path != "" {
+		key, err := secrets.MasterKeyFromEnv()
+		if err != nil {
+			fatal(logger, "failed to unlock credential store", err)
+		}
+		store, err := secrets.OpenFileStore(path, key)
+		if err != nil {
+			fatal(logger, "failed to open credential store", err)
+		}
+		opts = append(opts, service.WithSecretStore(store))
+
+		// Token lifecycle management needs somewhere to write refreshed tokens,
+		// so it is only available with a credential store.
+		var states tokens.StateStore = tokens.NewMemoryStateStore()
+		if statePath := cfg.Files.TokenState; statePath != "" {
+			fileStates, err := tokens.OpenFileStateStore(statePath)
+			if err != nil {
+				fatal(logger, "failed to open token state", err)
+			}
+			states = fileStates
+		}
+		tokenManager := tokens.NewManager(store, states, tokens.Config{
+			MetaGraphURL: cfg.Tokens.MetaGraphURL,
+			XAPIURL:      cfg.Tokens.XAPIURL,
+		})
+		elector.Duty("token refresh", func(ctx context.Context) {
+			tokenManager.Run(ctx, cfg.Tokens.RefreshInterval)
+		})
+		opts = append(opts, service.WithTokenManager(tokenManager))
+	}
+
+	// With an analysis cache the orchestrator analyzes fetched items itself,
+	// so items that stay the latest content are analyzed once.
+	if size := cfg.AnalysisCache.Size; size > 0 {
+		cache := analysiscache.New(analysiscache.NewMemoryBackend(size), cfg.AnalysisCache.TTL)
+		opts = append(opts, service.WithAnalysisCache(cache))
+	}
+
+	if path := cfg.Files.Routing; path != "" {
+		routingCfg, err := routing.LoadConfig(path)
+		if err != nil {
+			fatal(logger, "failed to load routing policies", err)
+		}
+		opts = append(opts, service.WithRouter(routing.NewRouter(routingCfg.Policies)))
+	}
+
+	if path := cfg.Files.Tenants; path != "" {
+		tenantCfg, err := tenant.LoadConfig(path)
+		if err != nil {
+			fatal(logger, "failed to load tenants", err)
+		}
+		registry, err := tenant.NewRegistry(tenantCfg)
+		if err != nil {
+			fatal(logger, "invalid tenants file", err)
+		}
+		opts = append(opts, service.WithTenants(registry))
+	}
+
+	// Publishing policies and pauses are checked right before every post;
+	// posts they hold back wait in the outbox.
+	outbox := publishing.NewStore()
+	if path := cfg.Files.PublishingState; path != "" {
+		if outbox, err = publishing.OpenStore(path); err != nil {
+			fatal(logger, "failed to open publishing state", err)
+		}
+	}
+	opts = append(opts, service.WithOutbox(outbox))
+	if path := cfg.Files.PublishingPolicies; path != "" {
+		policies, err := publishing.LoadConfig(path)
+		if err != nil {
+			fatal(logger, "failed to load publishing policies", err)
+		}
+		opts = append(opts, service.WithPublishingPolicies(policies))
+	}
+	pauses := pause.NewStore()
+	if path := cfg.Files.PauseState; path != "" {
+		if pauses, err = pause.OpenStore(path); err != nil {
+			fatal(logger, "failed to open pause state", err)
+		}
+	}
+	if active := pauses.List(); len(active) > 0 {
+		logger.Warn("publishing is paused", "pauses", len(active))
+	}
+	opts = append(opts, service.WithPauses(pauses, cfg.Pause.Drafts))
+
+	// Items that fail within runs are kept for retrying.
+	deadLetters := deadletter.NewStore(cfg.DeadLetters.QuarantineAfter)
+	if path := cfg.Files.DeadLetters; path != "" {
+		if deadLetters, err = deadletter.OpenStore(path, cfg.DeadLetters.QuarantineAfter); err != nil {
+			fatal(logger, "failed to open dead letters", err)
+		}
 	}
+	opts = append(opts, service.WithDeadLetters(deadLetters))
 
-	s := grpc.NewServer()
-	svc := service.NewOrchestratorService(fetcherClient, creatorClient, pubClient, aiContextClient)
+	// Without an auth config the server stays open, as before, for local
+	// development.
+	var serverOpts []grpc.ServerOption
+	enableReflection := true
+	if path := cfg.Files.Auth; path != "" {
+		authCfg, err := auth.LoadConfig(path)
+		if err != nil {
+			fatal(logger, "failed to load auth config", err)
+		}
+		creds, err := authCfg.ServerCredentials()
+		if err != nil {
+			fatal(logger, "failed to load TLS credentials", err)
+		}
+		if creds != nil {
+			serverOpts = append(serverOpts, grpc.Creds(creds))
+		}
+		authenticator := auth.NewAuthenticator(authCfg)
+		serverOpts = append(serverOpts,
+			grpc.ChainUnaryInterceptor(authenticator.UnaryServerInterceptor()),
+			grpc.ChainStreamInterceptor(authenticator.StreamServerInterceptor()))
+		enableReflection = authCfg.Reflection
+		logger.Info("API authentication enabled", "api_keys", len(authCfg.APIKeys), "tls", creds != nil, "mtls", authCfg.TLS.ClientCAFile != "")
+	} else {
+		logger.Warn("no auth config set; the API is unauthenticated")
+	}
+
+	// Readiness follows periodic probes of the downstream connections. The
+	// analyzer only backs optional features, so it does not gate readiness.
+	checker := health.NewChecker([]health.Dependency{
+		{Name: "fetcher", Probe: health.ConnProbe(connFetcher), Required: true},
+		{Name: "creator", Probe: health.ConnProbe(connCreator), Required: true},
+		{Name: "publisher", Probe: health.ConnProbe(connPub), Required: true},
+		{Name: "aicontext", Probe: health.ConnProbe(connAIContext), Required: true},
+		{Name: "analyzer", Probe: health.ConnProbe(connAnalyzer)},
+	}, cfg.Health.ProbeTimeout, logger)
+	go checker.Run(ctx, cfg.Health.Interval)
+
+	s := grpc.NewServer(serverOpts...)
+	svc := service.NewOrchestratorService(fetcherClient, creatorClient, pubClient, aiContextClient, opts...)
 	pb.RegisterOrchestratorServiceServer(s, svc)
-	reflection.Register(s)
+	// Only the leader refreshes tokens and delivers the outbox; every
+	// replica serves requests.
+	elector.Duty("outbox delivery", func(ctx context.Context) {
+		svc.DeliverOutbox(ctx, cfg.Outbox.Interval)
+	})
+	go elector.Run(ctx)
+
+	// Flow settings and their filters, rate limits and the pause settings
+	// reload on SIGHUP or when the config file changes.
+	if *configPath != "" {
+		watcher := config.NewWatcher(*configPath, cfg, logger)
+		watcher.OnReload(func(c *config.Config) {
+			svc.SetFlows(c.Flows)
+			limiters.Update(c.RateLimits)
+			svc.SetPauseDrafts(c.Pause.Drafts)
+		})
+		go watcher.Run(ctx, 10*time.Second)
+	}
+	checker.Register(s)
+	if enableReflection {
+		reflection.Register(s)
+	}
+
+	// Serve Prometheus metrics and HTTP health probes
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Default.Handler())
+	mux.Handle("/healthz", checker.LiveHandler())
+	mux.Handle("/readyz", checker.ReadyHandler())
+	httpServer := &http.Server{Addr: ":" + cfg.Server.HTTPPort, Handler: mux}
+	go func() {
+		logger.Info("metrics and health probes listening", "port", cfg.Server.HTTPPort)
+		if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fatal(logger, "failed to serve metrics", err)
+		}
+	}()
 
-	log.Printf("Orchestrator service listening on port %s", port)
-	if err := s.Serve(lis); err != nil {
-		log.Fatalf("failed to serve: %v", err)
+	logger.Info("orchestrator service listening", "port", cfg.Server.Port)
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- s.Serve(lis) }()
+	select {
+	case err := <-serveErr:
+		fatal(logger, "failed to serve", err)
+	case <-ctx.Done():
 	}
+	// A second signal kills the process without waiting.
+	stop()
+
+	logger.Info("shutting down", "drain_timeout", cfg.Server.DrainTimeout)
+	checker.Shutdown()
+	drainCtx, cancelDrain := context.WithTimeout(context.Background(), cfg.Server.DrainTimeout)
+	svc.Drain(drainCtx)
+	cancelDrain()
+
+	// Runs are done; other RPCs are short, so give them a moment before
+	// stopping hard.
+	stopped := make(chan struct{})
+	go func() {
+		s.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(10 * time.Second):
+		s.Stop()
+	}
+	httpCtx, cancelHTTP := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelHTTP()
+	if err := httpServer.Shutdown(httpCtx); err != nil {
+		logger.Warn("failed to stop metrics server", "error", err)
+	}
+	// The deferred Close calls shut the downstream connections.
+	logger.Info("closing downstream connections")
+}
+
+func fatal(logger *slog.Logger, msg string, err error) {
+	logger.Error(msg, "error", err)
+	os.Exit(1)
 }
@@ -0,0 +1,282 @@
+// Package admission bounds how many pipeline runs execute at once, globally
+// and per tenant. Runs over the limit wait in a bounded queue, served by
+// priority and, within a priority, fairly across accounts; when the queue is
+// full the lowest priority run is turned away with ResourceExhausted.
+package admission
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Optiq-CTO/orchestrator/internal/metrics"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// Run priorities, highest first. Interactive is the default.
+const (
+	Interactive = "interactive"
+	Scheduled   = "scheduled"
+	Backfill    = "backfill"
+)
+
+// Priorities lists the priorities, highest first.
+var Priorities = []string{Interactive, Scheduled, Backfill}
+
+// Rank returns the index of priority in Priorities, treating "" as
+// Interactive. ok is false for unknown priorities.
+func Rank(priority string) (rank int, ok bool) {
+	if priority == "" {
+		return 0, true
+	}
+	for i, p := range Priorities {
+		if p == priority {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// Config is the admission section of the server configuration.
+//
+//	admission:
+//	  max_concurrent: 10
+//	  tenant_max_concurrent: 3
+//	  tenants:
+//	    acme: 6
+//	  queue_size: 100
+type Config struct {
+	MaxConcurrent       int            `yaml:"max_concurrent" env:"MAX_CONCURRENT_RUNS"`               // zero means unlimited
+	TenantMaxConcurrent int            `yaml:"tenant_max_concurrent" env:"TENANT_MAX_CONCURRENT_RUNS"` // per tenant; zero means unlimited
+	Tenants             map[string]int `yaml:"tenants"`                                                // per-tenant overrides
+	QueueSize           int            `yaml:"queue_size" env:"RUN_QUEUE_SIZE"`                        // runs waiting at most
+	RetryAfter          time.Duration  `yaml:"retry_after"`                                            // hint sent with a full queue
+}
+
+// Validate rejects negative limits.
+func (c Config) Validate() error {
+	if c.MaxConcurrent < 0 || c.TenantMaxConcurrent < 0 || c.QueueSize < 0 {
+		return fmt.Errorf("admission: limits must not be negative")
+	}
+	for tenant, n := range c.Tenants {
+		if n < 0 {
+			return fmt.Errorf("admission.tenants.%s must not be negative", tenant)
+		}
+	}
+	if c.RetryAfter <= 0 {
+		return fmt.Errorf("admission.retry_after must be positive")
+	}
+	return nil
+}
+
+func (c Config) tenantLimit(tenant string) int {
+	if n, ok := c.Tenants[tenant]; ok {
+		return n
+	}
+	return c.TenantMaxConcurrent
+}
+
+// Request describes a run asking to execute. Runs without a tenant are
+// bound by the global limit only.
+type Request struct {
+	Priority string
+	Tenant   string
+	Account  string // the unit of fairness within a priority
+}
+
+type waiter struct {
+	req      Request
+	rank     int
+	seq      uint64
+	ready    chan struct{}
+	err      error
+	admitted bool
+}
+
+// Controller admits runs.
+type Controller struct {
+	cfg Config
+
+	mu       sync.Mutex
+	running  int
+	tenants  map[string]int
+	accounts map[string]int
+	queue    []*waiter
+	seq      uint64
+	closed   bool
+}
+
+// New returns a controller enforcing cfg.
+func New(cfg Config) *Controller {
+	return &Controller{cfg: cfg, tenants: make(map[string]int), accounts: make(map[string]int)}
+}
+
+// Acquire waits until the run may execute and returns the func that
+// releases its slot. It fails with ResourceExhausted, carrying a RetryInfo
+// hint, when the queue is full of runs of its priority or higher, with the context's error if ctx is done
+// first, and with Unavailable once the controller is closed.
+func (c *Controller) Acquire(ctx context.Context, req Request) (release func(), err error) {
+	rank, ok := Rank(req.Priority)
+	if !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "unknown priority %q", req.Priority)
+	}
+	priority := Priorities[rank]
+
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil, status.Error(codes.Unavailable, "server is shutting down; retry on another instance")
+	}
+	c.seq++
+	w := &waiter{req: req, rank: rank, seq: c.seq, ready: make(chan struct{})}
+	c.queue = append(c.queue, w)
+	c.dispatch()
+	if w.admitted {
+		c.mu.Unlock()
+		return c.releaser(w), nil
+	}
+	if len(c.queue) > c.cfg.QueueSize {
+		// A full queue turns away its lowest priority, newest run; that
+		// may be one already waiting, making room for this one.
+		worst := c.queue[0]
+		for _, q := range c.queue[1:] {
+			if q.rank > worst.rank || (q.rank == worst.rank && q.seq > worst.seq) {
+				worst = q
+			}
+		}
+		c.remove(worst)
+		metrics.AdmissionRejections.Inc(Priorities[worst.rank])
+		if worst == w {
+			c.mu.Unlock()
+			return nil, c.queueFull()
+		}
+		worst.err = c.queueFull()
+		close(worst.ready)
+	}
+	metrics.AdmissionQueued.Inc(priority)
+	c.mu.Unlock()
+
+	start := time.Now()
+	select {
+	case <-w.ready:
+	case <-ctx.Done():
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	metrics.AdmissionQueued.Dec(priority)
+	metrics.AdmissionWait.Observe(time.Since(start).Seconds(), priority)
+	switch {
+	case w.admitted && ctx.Err() == nil:
+		return c.releaser(w), nil
+	case w.admitted:
+		// Admitted as the caller gave up; hand the slot on.
+		c.done(w)
+	case w.err == nil:
+		c.remove(w)
+	}
+	if w.err != nil {
+		return nil, w.err
+	}
+	return nil, status.FromContextError(ctx.Err()).Err()
+}
+
+func (c *Controller) queueFull() error {
+	st := status.Newf(codes.ResourceExhausted, "too many pipeline runs queued; retry in %s", c.cfg.RetryAfter)
+	if d, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(c.cfg.RetryAfter)}); err == nil {
+		st = d
+	}
+	return st.Err()
+}
+
+func (c *Controller) releaser(w *waiter) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			c.done(w)
+		})
+	}
+}
+
+// done frees the slot of an admitted run and admits whoever can go next.
+func (c *Controller) done(w *waiter) {
+	c.running--
+	decr(c.accounts, w.req.Account)
+	if w.req.Tenant != "" {
+		decr(c.tenants, w.req.Tenant)
+	}
+	c.dispatch()
+}
+
+func decr(m map[string]int, key string) {
+	if m[key]--; m[key] <= 0 {
+		delete(m, key)
+	}
+}
+
+func (c *Controller) remove(w *waiter) {
+	for i, q := range c.queue {
+		if q == w {
+			c.queue = append(c.queue[:i], c.queue[i+1:]...)
+			return
+		}
+	}
+}
+
+// dispatch admits queued runs while there is capacity. Among the runs their
+// tenant's limit allows, the highest priority goes first, then the one whose
+// account has the fewest runs executing, then the oldest.
+func (c *Controller) dispatch() {
+	for c.cfg.MaxConcurrent == 0 || c.running < c.cfg.MaxConcurrent {
+		var next *waiter
+		for _, w := range c.queue {
+			if w.req.Tenant != "" {
+				if limit := c.cfg.tenantLimit(w.req.Tenant); limit > 0 && c.tenants[w.req.Tenant] >= limit {
+					continue
+				}
+			}
+			if next == nil || c.before(w, next) {
+				next = w
+			}
+		}
+		if next == nil {
+			return
+		}
+		c.remove(next)
+		c.running++
+		c.accounts[next.req.Account]++
+		if next.req.Tenant != "" {
+			c.tenants[next.req.Tenant]++
+		}
+		next.admitted = true
+		close(next.ready)
+	}
+}
+
+func (c *Controller) before(a, b *waiter) bool {
+	if a.rank != b.rank {
+		return a.rank < b.rank
+	}
+	if na, nb := c.accounts[a.req.Account], c.accounts[b.req.Account]; na != nb {
+		return na < nb
+	}
+	return a.seq < b.seq
+}
+
+// Close turns away queued and future runs with Unavailable. Runs already
+// admitted are unaffected.
+func (c *Controller) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	for _, w := range c.queue {
+		w.err = status.Error(codes.Unavailable, "server is shutting down; retry on another instance")
+		close(w.ready)
+	}
+	c.queue = nil
+}
@@ -0,0 +1,232 @@
+package admission
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Optiq-CTO/orchestrator/internal/retry"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestRank(t *testing.T) {
+	tests := []struct {
+		priority string
+		want     int
+		wantOK   bool
+	}{
+		{"", 0, true},
+		{Interactive, 0, true},
+		{Scheduled, 1, true},
+		{Backfill, 2, true},
+		{"urgent", 0, false},
+	}
+	for _, tt := range tests {
+		if got, ok := Rank(tt.priority); got != tt.want || ok != tt.wantOK {
+			t.Errorf("Rank(%q) = %d, %v; want %d, %v", tt.priority, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{"valid", Config{MaxConcurrent: 10, QueueSize: 100, RetryAfter: time.Second}, false},
+		{"unlimited", Config{RetryAfter: time.Second}, false},
+		{"negative", Config{MaxConcurrent: -1, RetryAfter: time.Second}, true},
+		{"negative tenant", Config{Tenants: map[string]int{"acme": -1}, RetryAfter: time.Second}, true},
+		{"no retry hint", Config{MaxConcurrent: 1}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.cfg.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// acquire starts Acquire in the background and returns a channel that
+// receives its outcome.
+func acquire(ctx context.Context, c *Controller, req Request) chan error {
+	ch := make(chan error, 1)
+	go func() {
+		release, err := c.Acquire(ctx, req)
+		if err == nil {
+			defer release()
+		}
+		ch <- err
+		if err == nil {
+			<-ctx.Done()
+		}
+	}()
+	return ch
+}
+
+// queued waits until n runs are queued.
+func queued(t *testing.T, c *Controller, n int) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		c.mu.Lock()
+		got := len(c.queue)
+		c.mu.Unlock()
+		if got == n {
+			return
+		}
+	}
+	t.Fatalf("queue never reached %d runs", n)
+}
+
+func result(t *testing.T, ch chan error) error {
+	t.Helper()
+	select {
+	case err := <-ch:
+		return err
+	case <-time.After(time.Second):
+		t.Fatal("run was neither admitted nor turned away")
+		return nil
+	}
+}
+
+func pending(ch chan error) bool {
+	select {
+	case err := <-ch:
+		ch <- err
+		return false
+	case <-time.After(10 * time.Millisecond):
+		return true
+	}
+}
+
+func TestAcquireLimits(t *testing.T) {
+	tests := []struct {
+		name      string
+		cfg       Config
+		running   []Request
+		next      Request
+		wantAdmit bool
+	}{
+		{"under global", Config{MaxConcurrent: 2}, []Request{{}}, Request{}, true},
+		{"at global", Config{MaxConcurrent: 1}, []Request{{}}, Request{}, false},
+		{"unlimited", Config{}, []Request{{}, {}, {}}, Request{}, true},
+		{"at tenant", Config{TenantMaxConcurrent: 1}, []Request{{Tenant: "acme"}}, Request{Tenant: "acme"}, false},
+		{"other tenant", Config{TenantMaxConcurrent: 1}, []Request{{Tenant: "acme"}}, Request{Tenant: "globex"}, true},
+		{"tenant override", Config{TenantMaxConcurrent: 1, Tenants: map[string]int{"acme": 2}}, []Request{{Tenant: "acme"}}, Request{Tenant: "acme"}, true},
+		{"no tenant", Config{TenantMaxConcurrent: 1}, []Request{{}}, Request{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.QueueSize, tt.cfg.RetryAfter = 10, time.Second
+			c := New(tt.cfg)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			for _, r := range tt.running {
+				if _, err := c.Acquire(ctx, r); err != nil {
+					t.Fatal(err)
+				}
+			}
+			ch := acquire(ctx, c, tt.next)
+			if admitted := !pending(ch); admitted != tt.wantAdmit {
+				t.Errorf("admitted = %v, want %v", admitted, tt.wantAdmit)
+			}
+		})
+	}
+}
+
+func TestAcquireOrder(t *testing.T) {
+	c := New(Config{MaxConcurrent: 2, QueueSize: 10, RetryAfter: time.Second})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	releaseA, err := c.Acquire(ctx, Request{Account: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	releaseX, err := c.Acquire(ctx, Request{Account: "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	backfill := acquire(ctx, c, Request{Priority: Backfill, Account: "b"})
+	queued(t, c, 1)
+	sameAccount := acquire(ctx, c, Request{Priority: Scheduled, Account: "a"})
+	queued(t, c, 2)
+	otherAccount := acquire(ctx, c, Request{Priority: Scheduled, Account: "c"})
+	queued(t, c, 3)
+
+	// Account a still has a run executing, so c goes first although it
+	// queued later.
+	releaseX()
+	if err := result(t, otherAccount); err != nil {
+		t.Fatal(err)
+	}
+	if !pending(sameAccount) || !pending(backfill) {
+		t.Fatal("more than one run admitted into a single slot")
+	}
+	releaseA()
+	if err := result(t, sameAccount); err != nil {
+		t.Fatal(err)
+	}
+	if !pending(backfill) {
+		t.Fatal("backfill run admitted before the scheduled ones")
+	}
+}
+
+func TestQueueFull(t *testing.T) {
+	c := New(Config{MaxConcurrent: 1, QueueSize: 1, RetryAfter: 3 * time.Second})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if _, err := c.Acquire(ctx, Request{}); err != nil {
+		t.Fatal(err)
+	}
+	backfill := acquire(ctx, c, Request{Priority: Backfill})
+	queued(t, c, 1)
+
+	// A higher priority run displaces the queued backfill run.
+	interactive := acquire(ctx, c, Request{Priority: Interactive})
+	err := result(t, backfill)
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("displaced run got %v, want ResourceExhausted", err)
+	}
+	if d, ok := retry.Delay(err); !ok || d != 3*time.Second {
+		t.Errorf("retry delay = %v, %v; want 3s", d, ok)
+	}
+	if !pending(interactive) {
+		t.Fatal("interactive run was not queued")
+	}
+
+	// A lower priority run is turned away itself.
+	if _, err := c.Acquire(ctx, Request{Priority: Scheduled}); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("scheduled run into a full queue got %v, want ResourceExhausted", err)
+	}
+}
+
+func TestAcquireCanceledAndClose(t *testing.T) {
+	c := New(Config{MaxConcurrent: 1, QueueSize: 10, RetryAfter: time.Second})
+	if _, err := c.Acquire(context.Background(), Request{}); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := acquire(ctx, c, Request{})
+	queued(t, c, 1)
+	cancel()
+	if err := result(t, ch); status.Code(err) != codes.Canceled {
+		t.Errorf("canceled run got %v, want Canceled", err)
+	}
+	queued(t, c, 0)
+
+	ch = acquire(context.Background(), c, Request{})
+	queued(t, c, 1)
+	c.Close()
+	if err := result(t, ch); status.Code(err) != codes.Unavailable {
+		t.Errorf("queued run got %v after Close, want Unavailable", err)
+	}
+	if _, err := c.Acquire(context.Background(), Request{}); status.Code(err) != codes.Unavailable {
+		t.Errorf("run after Close got %v, want Unavailable", err)
+	}
+	if _, err := c.Acquire(context.Background(), Request{Priority: "urgent"}); err == nil {
+		t.Error("unknown priority admitted")
+	}
+}
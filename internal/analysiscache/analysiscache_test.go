@@ -0,0 +1,78 @@
+package analysiscache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	analyzer "github.com/Optiq-CTO/analyzer/api/proto"
+)
+
+func TestMemoryBackend(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	b := NewMemoryBackend(2)
+	b.now = func() time.Time { return now }
+
+	b.Set(ctx, "a", []byte("1"), time.Minute)
+	b.Set(ctx, "b", []byte("2"), time.Hour)
+	b.Get(ctx, "a") // a is now the most recently used
+	b.Set(ctx, "c", []byte("3"), time.Hour)
+
+	tests := []struct {
+		advance time.Duration
+		key     string
+		want    string
+		ok      bool
+	}{
+		{0, "b", "", false}, // evicted as least recently used
+		{0, "a", "1", true},
+		{0, "c", "3", true},
+		{2 * time.Minute, "a", "", false}, // expired
+		{0, "c", "3", true},
+	}
+	for _, tt := range tests {
+		now = now.Add(tt.advance)
+		v, ok, err := b.Get(ctx, tt.key)
+		if err != nil || ok != tt.ok || string(v) != tt.want {
+			t.Errorf("Get(%s) = %q, %v, %v; want %q, %v", tt.key, v, ok, err, tt.want, tt.ok)
+		}
+	}
+}
+
+type failingBackend struct{}
+
+func (failingBackend) Get(context.Context, string) ([]byte, bool, error) {
+	return nil, false, errors.New("down")
+}
+func (failingBackend) Set(context.Context, string, []byte, time.Duration) error {
+	return errors.New("down")
+}
+
+func TestCache(t *testing.T) {
+	ctx := context.Background()
+	c := New(NewMemoryBackend(10), time.Hour)
+	key := Key("twitter", "123", "gemini")
+	if key != "twitter/123/gemini" {
+		t.Errorf("Key = %q", key)
+	}
+	if _, ok := c.Get(ctx, key); ok {
+		t.Fatal("hit on an empty cache")
+	}
+	if err := c.Put(ctx, key, &analyzer.AnalyzeContentResponse{Sentiment: "positive", RiskScore: 0.25}); err != nil {
+		t.Fatal(err)
+	}
+	got, ok := c.Get(ctx, key)
+	if !ok || got.Sentiment != "positive" || got.RiskScore != 0.25 {
+		t.Errorf("Get = %v, %v", got, ok)
+	}
+	if _, ok := c.Get(ctx, Key("twitter", "123", "openai")); ok {
+		t.Error("another provider's analysis was a hit")
+	}
+
+	broken := New(failingBackend{}, time.Hour)
+	if _, ok := broken.Get(ctx, key); ok {
+		t.Error("a backend error was a hit")
+	}
+}
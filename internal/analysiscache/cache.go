@@ -0,0 +1,129 @@
+// Package analysiscache caches analyzer results by source item, so posts
+// that stay the latest content across runs are analyzed once (design log
+// 06). Storage is pluggable through Backend.
+package analysiscache
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	analyzer "github.com/Optiq-CTO/analyzer/api/proto"
+	"github.com/Optiq-CTO/orchestrator/internal/metrics"
+	"google.golang.org/protobuf/proto"
+)
+
+// Backend stores encoded analyses. Implementations enforce their own size
+// bounds and must honor the TTL given to Set.
+type Backend interface {
+	// Get returns the value for key; ok is false on a miss or expiry.
+	Get(ctx context.Context, key string) (value []byte, ok bool, err error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+}
+
+// Cache stores analyzer responses keyed by platform, source item and model
+// provider.
+type Cache struct {
+	backend Backend
+	ttl     time.Duration
+}
+
+// New returns a cache over backend whose entries live for ttl.
+func New(backend Backend, ttl time.Duration) *Cache {
+	return &Cache{backend: backend, ttl: ttl}
+}
+
+// Key identifies an analysis. Different providers analyze differently, so
+// the provider is part of the key.
+func Key(platform, sourceID, modelProvider string) string {
+	return platform + "/" + sourceID + "/" + modelProvider
+}
+
+// Get returns the cached analysis for key. Backend errors count as misses.
+func (c *Cache) Get(ctx context.Context, key string) (*analyzer.AnalyzeContentResponse, bool) {
+	data, ok, err := c.backend.Get(ctx, key)
+	if err != nil || !ok {
+		metrics.AnalysisCacheRequests.Inc("miss")
+		return nil, false
+	}
+	var a analyzer.AnalyzeContentResponse
+	if err := proto.Unmarshal(data, &a); err != nil {
+		metrics.AnalysisCacheRequests.Inc("miss")
+		return nil, false
+	}
+	metrics.AnalysisCacheRequests.Inc("hit")
+	return &a, true
+}
+
+// Put stores an analysis under key.
+func (c *Cache) Put(ctx context.Context, key string, a *analyzer.AnalyzeContentResponse) error {
+	data, err := proto.Marshal(a)
+	if err != nil {
+		return fmt.Errorf("encoding analysis: %w", err)
+	}
+	return c.backend.Set(ctx, key, data, c.ttl)
+}
+
+// MemoryBackend is an in-process LRU with per-entry expiry.
+type MemoryBackend struct {
+	mu      sync.Mutex
+	max     int
+	ll      *list.List
+	entries map[string]*list.Element
+	now     func() time.Time
+}
+
+type memoryEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+// NewMemoryBackend returns a backend holding at most max entries; the least
+// recently used entry is evicted first.
+func NewMemoryBackend(max int) *MemoryBackend {
+	return &MemoryBackend{max: max, ll: list.New(), entries: make(map[string]*list.Element), now: time.Now}
+}
+
+// Get implements Backend.
+func (m *MemoryBackend) Get(_ context.Context, key string) ([]byte, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	el, ok := m.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	e := el.Value.(*memoryEntry)
+	if m.now().After(e.expires) {
+		m.remove(el)
+		return nil, false, nil
+	}
+	m.ll.MoveToFront(el)
+	return e.value, true, nil
+}
+
+// Set implements Backend.
+func (m *MemoryBackend) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	expires := m.now().Add(ttl)
+	if el, ok := m.entries[key]; ok {
+		e := el.Value.(*memoryEntry)
+		e.value, e.expires = value, expires
+		m.ll.MoveToFront(el)
+		return nil
+	}
+	m.entries[key] = m.ll.PushFront(&memoryEntry{key: key, value: value, expires: expires})
+	for m.max > 0 && m.ll.Len() > m.max {
+		m.remove(m.ll.Back())
+		metrics.AnalysisCacheEvictions.Inc("memory")
+	}
+	return nil
+}
+
+func (m *MemoryBackend) remove(el *list.Element) {
+	m.ll.Remove(el)
+	delete(m.entries, el.Value.(*memoryEntry).key)
+}
@@ -0,0 +1,254 @@
+// Package auth authenticates callers of the OrchestratorService by API key
+// and authorizes them by scope and by flow and account allowlists.
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Scope is a class of RPCs an API key may call.
+type Scope string
+
+// Scopes. Admin implies every other scope.
+const (
+	ScopeRun   Scope = "run"   // run and validate flows
+	ScopeRead  Scope = "read"  // read run history
+	ScopeAdmin Scope = "admin" // manage credentials, tokens and the server
+)
+
+func (s Scope) valid() bool {
+	return s == ScopeRun || s == ScopeRead || s == ScopeAdmin
+}
+
+const servicePrefix = "/orchestrator.OrchestratorService/"
+
+// methodScopes maps every OrchestratorService method to the scope it needs.
+// Methods missing from this map are denied, so new RPCs fail closed until
+// they are classified here.
+var methodScopes = map[string]Scope{
+	servicePrefix + "RunPipeline":          ScopeRun,
+	servicePrefix + "ValidateAccount":      ScopeRun,
+	servicePrefix + "GetRun":               ScopeRead,
+	servicePrefix + "ListRuns":             ScopeRead,
+	servicePrefix + "ResumeRun":            ScopeRun,
+	servicePrefix + "UnpublishRun":         ScopeAdmin,
+	servicePrefix + "PutSecret":            ScopeAdmin,
+	servicePrefix + "DeleteSecret":         ScopeAdmin,
+	servicePrefix + "ListSecrets":          ScopeAdmin,
+	servicePrefix + "ExchangeMetaToken":    ScopeAdmin,
+	servicePrefix + "RefreshAccountToken":  ScopeAdmin,
+	servicePrefix + "ListAccountTokens":    ScopeAdmin,
+	servicePrefix + "ListAccounts":         ScopeRead,
+	servicePrefix + "GetUsage":             ScopeRead,
+	servicePrefix + "ListCircuitBreakers":  ScopeRead,
+	servicePrefix + "ListRateLimits":       ScopeRead,
+	servicePrefix + "ListOutbox":           ScopeRead,
+	servicePrefix + "CancelOutboxEntry":    ScopeRun,
+	servicePrefix + "ApproveOutboxEntry":   ScopeRun,
+	servicePrefix + "PausePublishing":      ScopeAdmin,
+	servicePrefix + "ResumePublishing":     ScopeAdmin,
+	servicePrefix + "ListPublishingPauses": ScopeRead,
+	servicePrefix + "ListDeadLetters":      ScopeRead,
+	servicePrefix + "RetryDeadLetter":      ScopeRun,
+	servicePrefix + "DiscardDeadLetter":    ScopeRun,
+}
+
+// healthPrefix marks the grpc.health.v1 methods, which orchestrators such as
+// Kubernetes call without credentials. They reveal only serving status.
+const healthPrefix = "/grpc.health.v1.Health/"
+
+func isPublic(fullMethod string) bool {
+	return strings.HasPrefix(fullMethod, healthPrefix)
+}
+
+// requiredScope returns the scope needed for a full method name. Methods of
+// other services, such as reflection, need admin.
+func requiredScope(fullMethod string) (Scope, bool) {
+	if s, ok := methodScopes[fullMethod]; ok {
+		return s, true
+	}
+	if strings.HasPrefix(fullMethod, servicePrefix) {
+		return "", false
+	}
+	return ScopeAdmin, true
+}
+
+// Principal is an authenticated caller.
+type Principal struct {
+	Name string
+	// Tenant, when set, confines the principal to one tenant.
+	Tenant   string
+	scopes   map[Scope]bool
+	flows    map[string]bool
+	accounts map[string]bool
+}
+
+// HasScope reports whether the principal was granted s.
+func (p *Principal) HasScope(s Scope) bool {
+	return p.scopes[ScopeAdmin] || p.scopes[s]
+}
+
+// AllowsFlow reports whether the principal may run flow.
+func (p *Principal) AllowsFlow(flow string) bool {
+	return len(p.flows) == 0 || p.flows[flow]
+}
+
+// AllowsAccount reports whether the principal may act for a credential
+// store account.
+func (p *Principal) AllowsAccount(account string) bool {
+	return len(p.accounts) == 0 || p.accounts[account]
+}
+
+// AllowsTenant reports whether the principal may see or act for tenant.
+func (p *Principal) AllowsTenant(tenant string) bool {
+	return p.Tenant == "" || p.Tenant == tenant
+}
+
+// RestrictsAccounts reports whether the principal is limited to an account
+// allowlist.
+func (p *Principal) RestrictsAccounts() bool {
+	return len(p.accounts) > 0
+}
+
+type ctxKey struct{}
+
+// FromContext returns the authenticated caller. ok is false when auth is
+// disabled, in which case callers are unrestricted.
+func FromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(ctxKey{}).(*Principal)
+	return p, ok
+}
+
+// WithPrincipal returns a context carrying p.
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, ctxKey{}, p)
+}
+
+// Authenticator resolves API keys to principals.
+type Authenticator struct {
+	keys []keyEntry
+}
+
+type keyEntry struct {
+	digest    []byte
+	principal *Principal
+}
+
+// NewAuthenticator builds an authenticator from a validated config.
+func NewAuthenticator(cfg *Config) *Authenticator {
+	a := &Authenticator{}
+	for _, k := range cfg.APIKeys {
+		digest, _ := hex.DecodeString(strings.ToLower(k.KeySHA256))
+		p := &Principal{
+			Name:     k.Name,
+			Tenant:   k.Tenant,
+			scopes:   make(map[Scope]bool),
+			flows:    toSet(k.Flows),
+			accounts: toSet(k.Accounts),
+		}
+		for _, s := range k.Scopes {
+			p.scopes[s] = true
+		}
+		a.keys = append(a.keys, keyEntry{digest: digest, principal: p})
+	}
+	return a
+}
+
+func toSet(values []string) map[string]bool {
+	if len(values) == 0 {
+		return nil
+	}
+	m := make(map[string]bool, len(values))
+	for _, v := range values {
+		m[v] = true
+	}
+	return m
+}
+
+// authenticate checks the API key in ctx and the scope fullMethod needs.
+func (a *Authenticator) authenticate(ctx context.Context, fullMethod string) (*Principal, error) {
+	scope, known := requiredScope(fullMethod)
+	if !known {
+		return nil, status.Errorf(codes.PermissionDenied, "method %s is not authorized for any scope", fullMethod)
+	}
+	key := apiKeyFromMetadata(ctx)
+	if key == "" {
+		return nil, status.Error(codes.Unauthenticated, "missing API key")
+	}
+	sum := sha256.Sum256([]byte(key))
+	var principal *Principal
+	for _, k := range a.keys {
+		// Compare every entry so timing does not reveal which key matched.
+		if subtle.ConstantTimeCompare(sum[:], k.digest) == 1 {
+			principal = k.principal
+		}
+	}
+	if principal == nil {
+		return nil, status.Error(codes.Unauthenticated, "invalid API key")
+	}
+	if !principal.HasScope(scope) {
+		return nil, status.Errorf(codes.PermissionDenied, "api key %q lacks the %s scope", principal.Name, scope)
+	}
+	return principal, nil
+}
+
+// apiKeyFromMetadata reads "authorization: Bearer <key>" or "x-api-key".
+func apiKeyFromMetadata(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	for _, v := range md.Get("authorization") {
+		if len(v) > 7 && strings.EqualFold(v[:7], "bearer ") {
+			return strings.TrimSpace(v[7:])
+		}
+	}
+	if v := md.Get("x-api-key"); len(v) > 0 {
+		return strings.TrimSpace(v[0])
+	}
+	return ""
+}
+
+// UnaryServerInterceptor authenticates unary calls.
+func (a *Authenticator) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if isPublic(info.FullMethod) {
+			return handler(ctx, req)
+		}
+		p, err := a.authenticate(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(WithPrincipal(ctx, p), req)
+	}
+}
+
+// StreamServerInterceptor authenticates streaming calls.
+func (a *Authenticator) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if isPublic(info.FullMethod) {
+			return handler(srv, ss)
+		}
+		p, err := a.authenticate(ss.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &principalStream{ServerStream: ss, ctx: WithPrincipal(ss.Context(), p)})
+	}
+}
+
+type principalStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *principalStream) Context() context.Context { return s.ctx }
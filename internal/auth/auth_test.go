@@ -0,0 +1,144 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func digest(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	a := NewAuthenticator(&Config{APIKeys: []APIKey{
+		{Name: "runner", KeySHA256: digest("run-key"), Scopes: []Scope{ScopeRun}, Flows: []string{"twitter_echo"}},
+		{Name: "ops", KeySHA256: strings.ToUpper(digest("admin-key")), Scopes: []Scope{ScopeAdmin}},
+	}})
+	tests := []struct {
+		name   string
+		method string
+		md     metadata.MD
+		want   codes.Code
+		caller string
+	}{
+		{"health is public", "/grpc.health.v1.Health/Check", nil, codes.OK, ""},
+		{"missing key", servicePrefix + "RunPipeline", nil, codes.Unauthenticated, ""},
+		{"invalid key", servicePrefix + "RunPipeline", metadata.Pairs("x-api-key", "nope"), codes.Unauthenticated, ""},
+		{"bearer key", servicePrefix + "RunPipeline", metadata.Pairs("authorization", "Bearer run-key"), codes.OK, "runner"},
+		{"x-api-key", servicePrefix + "RunPipeline", metadata.Pairs("x-api-key", "run-key"), codes.OK, "runner"},
+		{"lacks scope", servicePrefix + "PutSecret", metadata.Pairs("x-api-key", "run-key"), codes.PermissionDenied, ""},
+		{"admin implies all", servicePrefix + "RunPipeline", metadata.Pairs("x-api-key", "admin-key"), codes.OK, "ops"},
+		{"unclassified method", servicePrefix + "NewRPC", metadata.Pairs("x-api-key", "admin-key"), codes.PermissionDenied, ""},
+		{"other service needs admin", "/grpc.reflection.v1.ServerReflection/ServerReflectionInfo", metadata.Pairs("x-api-key", "run-key"), codes.PermissionDenied, ""},
+	}
+	intercept := a.UnaryServerInterceptor()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.md != nil {
+				ctx = metadata.NewIncomingContext(ctx, tt.md)
+			}
+			var caller string
+			_, err := intercept(ctx, nil, &grpc.UnaryServerInfo{FullMethod: tt.method}, func(ctx context.Context, _ any) (any, error) {
+				if p, ok := FromContext(ctx); ok {
+					caller = p.Name
+				}
+				return nil, nil
+			})
+			if status.Code(err) != tt.want {
+				t.Fatalf("code = %v, want %v (%v)", status.Code(err), tt.want, err)
+			}
+			if caller != tt.caller {
+				t.Errorf("principal = %q, want %q", caller, tt.caller)
+			}
+		})
+	}
+}
+
+func TestPrincipalAllowlists(t *testing.T) {
+	a := NewAuthenticator(&Config{APIKeys: []APIKey{
+		{Name: "narrow", KeySHA256: digest("k1"), Scopes: []Scope{ScopeRead}, Flows: []string{"twitter_echo"}, Accounts: []string{"acme"}},
+		{Name: "wide", KeySHA256: digest("k2"), Scopes: []Scope{ScopeRead}},
+	}})
+	narrow, wide := a.keys[0].principal, a.keys[1].principal
+	tests := []struct {
+		name string
+		got  bool
+		want bool
+	}{
+		{"narrow allows its flow", narrow.AllowsFlow("twitter_echo"), true},
+		{"narrow denies other flows", narrow.AllowsFlow("facebook_echo"), false},
+		{"narrow allows its account", narrow.AllowsAccount("acme"), true},
+		{"narrow denies other accounts", narrow.AllowsAccount("beta"), false},
+		{"narrow restricts accounts", narrow.RestrictsAccounts(), true},
+		{"read lacks run", narrow.HasScope(ScopeRun), false},
+		{"wide allows any flow", wide.AllowsFlow("facebook_echo"), true},
+		{"wide allows any account", wide.AllowsAccount("beta"), true},
+		{"wide does not restrict", wide.RestrictsAccounts(), false},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	key := func(name, key string, scopes ...Scope) APIKey {
+		return APIKey{Name: name, KeySHA256: digest(key), Scopes: scopes}
+	}
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr string
+	}{
+		{"valid", Config{APIKeys: []APIKey{key("a", "1", ScopeRun)}}, ""},
+		{"no keys", Config{}, "at least one api key"},
+		{"cert without key", Config{TLS: TLSConfig{CertFile: "c"}, APIKeys: []APIKey{key("a", "1", ScopeRun)}}, "set together"},
+		{"client ca without tls", Config{TLS: TLSConfig{ClientCAFile: "ca"}, APIKeys: []APIKey{key("a", "1", ScopeRun)}}, "requires tls.cert_file"},
+		{"unnamed", Config{APIKeys: []APIKey{key("", "1", ScopeRun)}}, "name is required"},
+		{"duplicate name", Config{APIKeys: []APIKey{key("a", "1", ScopeRun), key("a", "2", ScopeRun)}}, "duplicate api key name"},
+		{"reused digest", Config{APIKeys: []APIKey{key("a", "1", ScopeRun), key("b", "1", ScopeRun)}}, "reuses another key's digest"},
+		{"bad digest", Config{APIKeys: []APIKey{{Name: "a", KeySHA256: "abc", Scopes: []Scope{ScopeRun}}}}, "hex SHA-256"},
+		{"no scopes", Config{APIKeys: []APIKey{key("a", "1")}}, "has no scopes"},
+		{"unknown scope", Config{APIKeys: []APIKey{key("a", "1", "write")}}, "unknown scope"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Validate = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Validate = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTenantKeys(t *testing.T) {
+	cfg := Config{APIKeys: []APIKey{{Name: "acme", KeySHA256: digest("k"), Scopes: []Scope{ScopeAdmin}, Tenant: "acme"}}}
+	if err := cfg.Validate(); err == nil {
+		t.Error("a tenant key was allowed the admin scope")
+	}
+	cfg.APIKeys[0].Scopes = []Scope{ScopeRun}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	p := NewAuthenticator(&cfg).keys[0].principal
+	if !p.AllowsTenant("acme") || p.AllowsTenant("beta") {
+		t.Errorf("tenant key allows acme=%v beta=%v", p.AllowsTenant("acme"), p.AllowsTenant("beta"))
+	}
+}
@@ -0,0 +1,46 @@
+package auth
+
+import (
+	"context"
+	"crypto/tls"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// ClientDialOptions returns the dial options for an orchestrator client.
+// apiKey is sent on every call when set; caFile switches the connection to
+// TLS verified against that CA.
+func ClientDialOptions(apiKey, caFile string) ([]grpc.DialOption, error) {
+	var opts []grpc.DialOption
+	if caFile != "" {
+		pool, err := loadCertPool(caFile)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{
+			RootCAs:    pool,
+			MinVersion: tls.VersionTLS12,
+		})))
+	} else {
+		opts = append(opts, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	}
+	if apiKey != "" {
+		opts = append(opts, grpc.WithPerRPCCredentials(apiKeyCredentials{key: apiKey, secure: caFile != ""}))
+	}
+	return opts, nil
+}
+
+type apiKeyCredentials struct {
+	key    string
+	secure bool
+}
+
+func (c apiKeyCredentials) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + c.key}, nil
+}
+
+func (c apiKeyCredentials) RequireTransportSecurity() bool {
+	return c.secure
+}
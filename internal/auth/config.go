@@ -0,0 +1,156 @@
+package auth
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"google.golang.org/grpc/credentials"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the auth section of the server configuration.
+//
+//	tls:
+//	  cert_file: /etc/orchestrator/tls.crt
+//	  key_file: /etc/orchestrator/tls.key
+//	  client_ca_file: /etc/orchestrator/clients-ca.crt  # enables mTLS
+//	api_keys:
+//	  - name: batch-runner
+//	    key_sha256: 9f86d0...  # printf %s "$KEY" | sha256sum
+//	    scopes: [run, read]
+//	    flows: [facebook_echo]
+//	    accounts: [health-page]
+//	  - name: acme-dashboard
+//	    key_sha256: 60303a...
+//	    scopes: [run, read]
+//	    tenant: acme  # only acme's accounts and runs
+type Config struct {
+	TLS TLSConfig `yaml:"tls"`
+	// Reflection registers the gRPC reflection service. It is off by default
+	// once auth is configured; reflection calls require the admin scope.
+	Reflection bool     `yaml:"reflection"`
+	APIKeys    []APIKey `yaml:"api_keys"`
+}
+
+// TLSConfig enables TLS, and mTLS when ClientCAFile is set.
+type TLSConfig struct {
+	CertFile     string `yaml:"cert_file"`
+	KeyFile      string `yaml:"key_file"`
+	ClientCAFile string `yaml:"client_ca_file"`
+}
+
+// APIKey grants a caller scopes, optionally restricted to some flows and
+// credential store accounts. Empty Flows or Accounts allow all. A key bound
+// to a Tenant may only run that tenant's registered accounts and only sees
+// that tenant's runs.
+type APIKey struct {
+	Name      string   `yaml:"name"`
+	KeySHA256 string   `yaml:"key_sha256"`
+	Scopes    []Scope  `yaml:"scopes"`
+	Flows     []string `yaml:"flows"`
+	Accounts  []string `yaml:"accounts"`
+	Tenant    string   `yaml:"tenant"`
+}
+
+// LoadConfig reads and validates an auth config file.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading auth config: %w", err)
+	}
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parsing auth config: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// Validate checks the config for mistakes that would silently weaken auth.
+func (c *Config) Validate() error {
+	if (c.TLS.CertFile == "") != (c.TLS.KeyFile == "") {
+		return errors.New("auth: tls.cert_file and tls.key_file must be set together")
+	}
+	if c.TLS.ClientCAFile != "" && c.TLS.CertFile == "" {
+		return errors.New("auth: tls.client_ca_file requires tls.cert_file and tls.key_file")
+	}
+	if len(c.APIKeys) == 0 {
+		return errors.New("auth: at least one api key is required")
+	}
+	names := make(map[string]bool)
+	hashes := make(map[string]bool)
+	for i, k := range c.APIKeys {
+		if k.Name == "" {
+			return fmt.Errorf("auth: api_keys[%d]: name is required", i)
+		}
+		if names[k.Name] {
+			return fmt.Errorf("auth: duplicate api key name %q", k.Name)
+		}
+		names[k.Name] = true
+		h, err := hex.DecodeString(strings.ToLower(k.KeySHA256))
+		if err != nil || len(h) != 32 {
+			return fmt.Errorf("auth: api key %q: key_sha256 must be a hex SHA-256 digest", k.Name)
+		}
+		if hashes[strings.ToLower(k.KeySHA256)] {
+			return fmt.Errorf("auth: api key %q reuses another key's digest", k.Name)
+		}
+		hashes[strings.ToLower(k.KeySHA256)] = true
+		if len(k.Scopes) == 0 {
+			return fmt.Errorf("auth: api key %q has no scopes", k.Name)
+		}
+		for _, s := range k.Scopes {
+			if !s.valid() {
+				return fmt.Errorf("auth: api key %q: unknown scope %q", k.Name, s)
+			}
+			// Credential and token administration is not tenant-aware.
+			if s == ScopeAdmin && k.Tenant != "" {
+				return fmt.Errorf("auth: api key %q: tenant keys cannot have the admin scope", k.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// ServerCredentials returns TLS transport credentials, or nil if TLS is not
+// configured.
+func (c *Config) ServerCredentials() (credentials.TransportCredentials, error) {
+	if c.TLS.CertFile == "" {
+		return nil, nil
+	}
+	cert, err := tls.LoadX509KeyPair(c.TLS.CertFile, c.TLS.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("loading tls key pair: %w", err)
+	}
+	tlsCfg := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if c.TLS.ClientCAFile != "" {
+		pool, err := loadCertPool(c.TLS.ClientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsCfg.ClientCAs = pool
+		tlsCfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return credentials.NewTLS(tlsCfg), nil
+}
+
+func loadCertPool(path string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading CA file: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in %s", path)
+	}
+	return pool, nil
+}
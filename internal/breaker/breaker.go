@@ -0,0 +1,349 @@
+// Package breaker implements circuit breakers for downstream calls, keyed by
+// service and platform (or model provider), so a broken adapter such as the
+// publisher's X integration fails fast instead of slowing every run down.
+package breaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Optiq-CTO/orchestrator/internal/metrics"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// State is a breaker state.
+type State int
+
+// Breaker states. Closed lets calls through; Open rejects them until the
+// open timeout passes; HalfOpen lets a few trial calls through to decide.
+const (
+	Closed State = iota
+	HalfOpen
+	Open
+)
+
+func (s State) String() string {
+	switch s {
+	case HalfOpen:
+		return "half_open"
+	case Open:
+		return "open"
+	}
+	return "closed"
+}
+
+// Settings are the thresholds of a breaker. Zero fields take the default.
+type Settings struct {
+	FailureThreshold int           `yaml:"failure_threshold"` // consecutive failures that open it
+	OpenTimeout      time.Duration `yaml:"open_timeout"`      // time open before trial calls
+	HalfOpenCalls    int           `yaml:"half_open_calls"`   // trial calls that must succeed to close it
+}
+
+// Config is the breakers section of the server configuration.
+//
+//	breakers:
+//	  default: {failure_threshold: 5, open_timeout: 30s, half_open_calls: 1}
+//	  overrides:
+//	    publisher/twitter: {failure_threshold: 2, open_timeout: 5m}
+type Config struct {
+	Default   Settings            `yaml:"default"`
+	Overrides map[string]Settings `yaml:"overrides"` // keyed by breaker name, service/key
+}
+
+// DefaultSettings are used for fields the configuration leaves unset.
+var DefaultSettings = Settings{FailureThreshold: 5, OpenTimeout: 30 * time.Second, HalfOpenCalls: 1}
+
+func (s Settings) or(def Settings) Settings {
+	if s.FailureThreshold == 0 {
+		s.FailureThreshold = def.FailureThreshold
+	}
+	if s.OpenTimeout == 0 {
+		s.OpenTimeout = def.OpenTimeout
+	}
+	if s.HalfOpenCalls == 0 {
+		s.HalfOpenCalls = def.HalfOpenCalls
+	}
+	return s
+}
+
+// Validate rejects negative thresholds.
+func (c Config) Validate() error {
+	check := func(name string, s Settings) error {
+		if s.FailureThreshold < 0 || s.OpenTimeout < 0 || s.HalfOpenCalls < 0 {
+			return fmt.Errorf("breakers.%s: thresholds must not be negative", name)
+		}
+		return nil
+	}
+	if err := check("default", c.Default); err != nil {
+		return err
+	}
+	for name, s := range c.Overrides {
+		if !strings.Contains(name, "/") {
+			return fmt.Errorf("breakers.overrides: %q is not a breaker name (want service/key)", name)
+		}
+		if err := check("overrides."+name, s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Status is a snapshot of one breaker.
+type Status struct {
+	Service  string
+	Key      string
+	State    State
+	Failures int       // consecutive failures while closed
+	OpenedAt time.Time // zero unless open or half-open
+	RetryAt  time.Time // when an open breaker lets a trial call through
+}
+
+// Name returns the breaker's name, service/key.
+func (s Status) Name() string { return name(s.Service, s.Key) }
+
+func name(service, key string) string { return service + "/" + key }
+
+// Breaker guards calls to one downstream service and platform.
+type Breaker struct {
+	service, key string
+	settings     Settings
+	now          func() time.Time
+
+	mu        sync.Mutex
+	state     State
+	failures  int
+	openedAt  time.Time
+	trials    int // trial calls in flight while half-open
+	successes int // trial calls succeeded while half-open
+}
+
+// OpenError is returned for calls rejected by an open breaker. It carries
+// Unavailable with a RetryInfo detail saying when the breaker will let a
+// trial call through, so retry policies wait or give up accordingly.
+type OpenError struct {
+	Name    string
+	RetryAt time.Time
+}
+
+func (e *OpenError) Error() string {
+	return fmt.Sprintf("circuit breaker %s is open; failing fast until %s", e.Name, e.RetryAt.UTC().Format(time.RFC3339))
+}
+
+// GRPCStatus implements the interface status.FromError looks for.
+func (e *OpenError) GRPCStatus() *status.Status {
+	st := status.New(codes.Unavailable, e.Error())
+	if d, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(max(time.Until(e.RetryAt), 0))}); err == nil {
+		return d
+	}
+	return st
+}
+
+// OpenUntil reports whether err, or an error it wraps, is an OpenError, and
+// when the breaker lets a trial call through.
+func OpenUntil(err error) (time.Time, bool) {
+	var oe *OpenError
+	if errors.As(err, &oe) {
+		return oe.RetryAt, true
+	}
+	return time.Time{}, false
+}
+
+// Allow reports whether a call may proceed. If it may, done must be called
+// with the call's outcome.
+func (b *Breaker) Allow() (done func(err error), err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.now()
+	if b.state == Open {
+		retryAt := b.openedAt.Add(b.settings.OpenTimeout)
+		if now.Before(retryAt) {
+			metrics.BreakerRejections.Inc(b.service, b.key)
+			return nil, &OpenError{Name: name(b.service, b.key), RetryAt: retryAt}
+		}
+		b.setState(HalfOpen)
+		b.trials, b.successes = 0, 0
+	}
+	if b.state == HalfOpen {
+		if b.trials >= b.settings.HalfOpenCalls {
+			metrics.BreakerRejections.Inc(b.service, b.key)
+			return nil, &OpenError{Name: name(b.service, b.key), RetryAt: now.Add(time.Second)}
+		}
+		b.trials++
+	}
+	return b.done, nil
+}
+
+func (b *Breaker) done(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if status.Code(err) == codes.Canceled {
+		// The caller gave up; that says nothing about the downstream.
+		if b.state == HalfOpen {
+			b.trials--
+		}
+		return
+	}
+	failed := IsFailure(err)
+	switch b.state {
+	case Closed:
+		if !failed {
+			b.failures = 0
+			return
+		}
+		b.failures++
+		if b.failures >= b.settings.FailureThreshold {
+			b.open()
+		}
+	case HalfOpen:
+		b.trials--
+		if failed {
+			b.open()
+			return
+		}
+		b.successes++
+		if b.successes >= b.settings.HalfOpenCalls {
+			b.failures = 0
+			b.setState(Closed)
+		}
+	}
+}
+
+func (b *Breaker) open() {
+	b.openedAt = b.now()
+	b.setState(Open)
+}
+
+func (b *Breaker) setState(s State) {
+	if b.state != s {
+		metrics.BreakerTransitions.Inc(b.service, b.key, s.String())
+	}
+	b.state = s
+	metrics.BreakerState.Set(float64(s), b.service, b.key)
+}
+
+// Status returns a snapshot of the breaker.
+func (b *Breaker) Status() Status {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	st := Status{Service: b.service, Key: b.key, State: b.state, Failures: b.failures}
+	if b.state != Closed {
+		st.OpenedAt = b.openedAt
+	}
+	if b.state == Open {
+		st.RetryAt = b.openedAt.Add(b.settings.OpenTimeout)
+	}
+	return st
+}
+
+// IsFailure reports whether err says the downstream is broken, as opposed
+// to rejecting a bad request. Only such failures count toward opening a
+// breaker.
+func IsFailure(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Internal, codes.Unknown, codes.Unimplemented, codes.DataLoss:
+		return true
+	}
+	return false
+}
+
+// Set holds the breakers of every service and key, created on first use.
+type Set struct {
+	cfg Config
+
+	mu       sync.Mutex
+	breakers map[string]*Breaker
+}
+
+// NewSet returns an empty set using cfg's thresholds.
+func NewSet(cfg Config) *Set {
+	return &Set{cfg: cfg, breakers: make(map[string]*Breaker)}
+}
+
+// Get returns the breaker for service and key.
+func (s *Set) Get(service, key string) *Breaker {
+	n := name(service, key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, ok := s.breakers[n]
+	if !ok {
+		settings := s.cfg.Overrides[n].or(s.cfg.Default.or(DefaultSettings))
+		b = &Breaker{service: service, key: key, settings: settings, now: time.Now}
+		s.breakers[n] = b
+		metrics.BreakerState.Set(float64(Closed), service, key)
+	}
+	return b
+}
+
+// Statuses returns a snapshot of every breaker, by name.
+func (s *Set) Statuses() []Status {
+	s.mu.Lock()
+	breakers := make([]*Breaker, 0, len(s.breakers))
+	for _, b := range s.breakers {
+		breakers = append(breakers, b)
+	}
+	s.mu.Unlock()
+	out := make([]Status, 0, len(breakers))
+	for _, b := range breakers {
+		out = append(out, b.Status())
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name() < out[j].Name() })
+	return out
+}
+
+// KeyFunc picks the breaker key of a request.
+type KeyFunc func(req interface{}) string
+
+// Requests name their platform and model provider, so breaker keys, and the
+// metric series labelled with them, are limited to known values; any other
+// value shares the "other" breaker.
+var (
+	knownPlatforms = map[string]bool{"reddit": true, "meta": true, "facebook": true, "twitter": true, "linkedin": true, "instagram": true}
+	knownProviders = map[string]bool{"gemini": true, "openai": true, "anthropic": true}
+)
+
+// PlatformKey keys breakers by the request's platform.
+func PlatformKey(req interface{}) string {
+	if r, ok := req.(interface{ GetPlatform() string }); ok && r.GetPlatform() != "" {
+		return boundedKey(r.GetPlatform(), knownPlatforms)
+	}
+	return "default"
+}
+
+// ModelProviderKey keys breakers by the request's model provider, for
+// AI-backed services where providers fail independently.
+func ModelProviderKey(req interface{}) string {
+	if r, ok := req.(interface{ GetModelProvider() string }); ok && r.GetModelProvider() != "" {
+		return boundedKey(r.GetModelProvider(), knownProviders)
+	}
+	return "default"
+}
+
+func boundedKey(v string, known map[string]bool) string {
+	if known[v] {
+		return v
+	}
+	return "other"
+}
+
+// UnaryClientInterceptor guards every call on a downstream connection with
+// the breaker of service and the request's key.
+func (s *Set) UnaryClientInterceptor(service string, key KeyFunc) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		done, err := s.Get(service, key(req)).Allow()
+		if err != nil {
+			return err
+		}
+		err = invoker(ctx, method, req, reply, cc, opts...)
+		done(err)
+		return err
+	}
+}
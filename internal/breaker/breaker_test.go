@@ -0,0 +1,263 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Optiq-CTO/orchestrator/internal/retry"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	errDown = status.Error(codes.Unavailable, "down")
+	errBad  = status.Error(codes.InvalidArgument, "bad request")
+)
+
+func newTestBreaker(s Settings) (*Breaker, *time.Time) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	b := NewSet(Config{Default: s}).Get("publisher", "twitter")
+	b.now = func() time.Time { return now }
+	return b, &now
+}
+
+func call(b *Breaker, err error) error {
+	done, rejected := b.Allow()
+	if rejected != nil {
+		return rejected
+	}
+	done(err)
+	return nil
+}
+
+func TestBreaker(t *testing.T) {
+	type step struct {
+		advance   time.Duration
+		err       error // outcome of the call, if allowed
+		wantAllow bool
+		wantState State
+	}
+	tests := []struct {
+		name     string
+		settings Settings
+		steps    []step
+	}{
+		{
+			name:     "opens after consecutive failures",
+			settings: Settings{FailureThreshold: 2, OpenTimeout: time.Minute, HalfOpenCalls: 1},
+			steps: []step{
+				{err: errDown, wantAllow: true, wantState: Closed},
+				{err: errDown, wantAllow: true, wantState: Open},
+				{wantAllow: false, wantState: Open},
+			},
+		},
+		{
+			name:     "success resets the count",
+			settings: Settings{FailureThreshold: 2, OpenTimeout: time.Minute, HalfOpenCalls: 1},
+			steps: []step{
+				{err: errDown, wantAllow: true, wantState: Closed},
+				{wantAllow: true, wantState: Closed},
+				{err: errDown, wantAllow: true, wantState: Closed},
+			},
+		},
+		{
+			name:     "client errors do not count",
+			settings: Settings{FailureThreshold: 1, OpenTimeout: time.Minute, HalfOpenCalls: 1},
+			steps: []step{
+				{err: errBad, wantAllow: true, wantState: Closed},
+				{err: status.Error(codes.Canceled, "gone"), wantAllow: true, wantState: Closed},
+			},
+		},
+		{
+			name:     "trial success closes",
+			settings: Settings{FailureThreshold: 1, OpenTimeout: time.Minute, HalfOpenCalls: 1},
+			steps: []step{
+				{err: errDown, wantAllow: true, wantState: Open},
+				{advance: 30 * time.Second, wantAllow: false, wantState: Open},
+				{advance: 30 * time.Second, wantAllow: true, wantState: Closed},
+			},
+		},
+		{
+			name:     "trial failure reopens",
+			settings: Settings{FailureThreshold: 1, OpenTimeout: time.Minute, HalfOpenCalls: 1},
+			steps: []step{
+				{err: errDown, wantAllow: true, wantState: Open},
+				{advance: time.Minute, err: errDown, wantAllow: true, wantState: Open},
+				{advance: 59 * time.Second, wantAllow: false, wantState: Open},
+			},
+		},
+		{
+			name:     "several trials needed",
+			settings: Settings{FailureThreshold: 1, OpenTimeout: time.Minute, HalfOpenCalls: 2},
+			steps: []step{
+				{err: errDown, wantAllow: true, wantState: Open},
+				{advance: time.Minute, wantAllow: true, wantState: HalfOpen},
+				{wantAllow: true, wantState: Closed},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, now := newTestBreaker(tt.settings)
+			for i, s := range tt.steps {
+				*now = now.Add(s.advance)
+				err := call(b, s.err)
+				if allowed := err == nil; allowed != s.wantAllow {
+					t.Fatalf("step %d: allowed = %v (%v), want %v", i, allowed, err, s.wantAllow)
+				}
+				if got := b.Status().State; got != s.wantState {
+					t.Fatalf("step %d: state = %v, want %v", i, got, s.wantState)
+				}
+			}
+		})
+	}
+}
+
+func TestHalfOpenLimitsTrials(t *testing.T) {
+	b, now := newTestBreaker(Settings{FailureThreshold: 1, OpenTimeout: time.Minute, HalfOpenCalls: 1})
+	call(b, errDown)
+	*now = now.Add(time.Minute)
+	done, err := b.Allow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.Allow(); err == nil {
+		t.Fatal("a second trial call was let through while the first is in flight")
+	}
+	done(nil)
+	if b.Status().State != Closed {
+		t.Errorf("state = %v, want closed", b.Status().State)
+	}
+}
+
+func TestOpenError(t *testing.T) {
+	b, _ := newTestBreaker(Settings{FailureThreshold: 1, OpenTimeout: time.Hour})
+	b.now = time.Now
+	call(b, errDown)
+	err := call(b, nil)
+	var oe *OpenError
+	if !errors.As(err, &oe) || oe.Name != "publisher/twitter" {
+		t.Fatalf("err = %v, want an OpenError for publisher/twitter", err)
+	}
+	if status.Code(err) != codes.Unavailable {
+		t.Errorf("code = %v, want Unavailable", status.Code(err))
+	}
+	if d, ok := retry.Delay(err); !ok || d < 59*time.Minute {
+		t.Errorf("retry delay = %v, %v; want about an hour", d, ok)
+	}
+	if st := b.Status(); st.RetryAt.IsZero() || st.OpenedAt.IsZero() {
+		t.Errorf("status of an open breaker lacks times: %+v", st)
+	}
+}
+
+func TestOpenUntil(t *testing.T) {
+	at := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	open := &OpenError{Name: "publisher/twitter", RetryAt: at}
+	tests := []struct {
+		name   string
+		err    error
+		wantOK bool
+	}{
+		{"nil", nil, false},
+		{"other failure", errDown, false},
+		{"open", open, true},
+		{"wrapped as not sent", retry.NotSent(open), true},
+	}
+	for _, tt := range tests {
+		got, ok := OpenUntil(tt.err)
+		if ok != tt.wantOK || (ok && !got.Equal(at)) {
+			t.Errorf("%s: OpenUntil = %v, %v", tt.name, got, ok)
+		}
+	}
+}
+
+func TestConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{"zero", Config{}, false},
+		{"override", Config{Overrides: map[string]Settings{"publisher/twitter": {FailureThreshold: 2}}}, false},
+		{"negative", Config{Default: Settings{OpenTimeout: -time.Second}}, true},
+		{"bad name", Config{Overrides: map[string]Settings{"twitter": {FailureThreshold: 2}}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.cfg.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	s := NewSet(Config{
+		Default:   Settings{FailureThreshold: 3},
+		Overrides: map[string]Settings{"publisher/twitter": {OpenTimeout: time.Minute}},
+	})
+	if got, want := s.Get("publisher", "twitter").settings, (Settings{3, time.Minute, 1}); got != want {
+		t.Errorf("publisher/twitter settings = %+v, want %+v", got, want)
+	}
+	if got, want := s.Get("publisher", "reddit").settings, (Settings{3, 30 * time.Second, 1}); got != want {
+		t.Errorf("publisher/reddit settings = %+v, want %+v", got, want)
+	}
+	if s.Get("publisher", "twitter") != s.Get("publisher", "twitter") {
+		t.Error("Get returned a new breaker for a known name")
+	}
+	if n := len(s.Statuses()); n != 2 {
+		t.Errorf("Statuses() has %d breakers, want 2", n)
+	}
+}
+
+type platformReq struct{ platform string }
+
+func (r platformReq) GetPlatform() string { return r.platform }
+
+type providerReq struct{ provider string }
+
+func (r providerReq) GetModelProvider() string { return r.provider }
+
+func TestKeys(t *testing.T) {
+	tests := []struct {
+		name string
+		key  KeyFunc
+		req  interface{}
+		want string
+	}{
+		{"platform", PlatformKey, platformReq{"twitter"}, "twitter"},
+		{"no platform", PlatformKey, platformReq{}, "default"},
+		{"unknown platform", PlatformKey, platformReq{"myspace-1234"}, "other"},
+		{"not a platform request", PlatformKey, providerReq{"gemini"}, "default"},
+		{"provider", ModelProviderKey, providerReq{"gemini"}, "gemini"},
+		{"unknown provider", ModelProviderKey, providerReq{"x-random"}, "other"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.key(tt.req); got != tt.want {
+				t.Errorf("key = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnaryClientInterceptor(t *testing.T) {
+	s := NewSet(Config{Default: Settings{FailureThreshold: 1, OpenTimeout: time.Hour}})
+	intercept := s.UnaryClientInterceptor("publisher", PlatformKey)
+	calls := 0
+	invoker := func(context.Context, string, interface{}, interface{}, *grpc.ClientConn, ...grpc.CallOption) error {
+		calls++
+		return errDown
+	}
+	for i := 0; i < 3; i++ {
+		intercept(context.Background(), "/publisher/Publish", platformReq{"twitter"}, nil, nil, invoker)
+	}
+	if calls != 1 {
+		t.Errorf("downstream called %d times, want 1 before the breaker opened", calls)
+	}
+	intercept(context.Background(), "/publisher/Publish", platformReq{"reddit"}, nil, nil, invoker)
+	if calls != 2 {
+		t.Error("an open twitter breaker blocked reddit calls")
+	}
+}
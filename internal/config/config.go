@@ -0,0 +1,579 @@
+// Package config loads the server configuration: a single YAML file, with
+// the server's environment variables overriding it. Every setting has a
+// default, so the file is optional. The flows, rate_limits and pause
+// sections are reloadable and can change while the server runs; everything
+// else needs a restart.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Optiq-CTO/orchestrator/internal/admission"
+	"github.com/Optiq-CTO/orchestrator/internal/breaker"
+	"github.com/Optiq-CTO/orchestrator/internal/filter"
+	"github.com/Optiq-CTO/orchestrator/internal/lease"
+	"github.com/Optiq-CTO/orchestrator/internal/pause"
+	"github.com/Optiq-CTO/orchestrator/internal/ratelimit"
+	"github.com/Optiq-CTO/orchestrator/internal/retry"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the server configuration.
+//
+//	server:
+//	  port: "50056"
+//	  drain_timeout: 1m
+//	downstream:
+//	  fetcher: fetcher:50053
+//	files:
+//	  auth_config: /etc/orchestrator/auth.yaml
+//	flows:
+//	  twitter_echo:
+//	    tone: playful
+//	    timeout: 2m
+type Config struct {
+	Server        Server        `yaml:"server"`
+	Logging       Logging       `yaml:"logging"`
+	Downstream    Downstream    `yaml:"downstream"`
+	Health        Health        `yaml:"health"`
+	Runs          Runs          `yaml:"runs"`
+	Files         Files         `yaml:"files"`
+	Tokens        Tokens        `yaml:"tokens"`
+	AnalysisCache AnalysisCache `yaml:"analysis_cache"`
+	Outbox        Outbox        `yaml:"outbox"`
+	Leases        Leases        `yaml:"leases"`
+	Leader        Leader        `yaml:"leader"`
+	DeadLetters   DeadLetters   `yaml:"dead_letters"`
+
+	// Retries maps pipeline steps (fetch, analyze, get_context, generate,
+	// publish, delete) to their retry policy. Fields a step leaves unset come from
+	// the "default" policy.
+	Retries map[string]retry.Policy `yaml:"retries"`
+
+	Breakers  breaker.Config   `yaml:"breakers"`
+	Admission admission.Config `yaml:"admission"`
+
+	// Reloadable sections.
+	Flows      map[string]Flow  `yaml:"flows"`
+	RateLimits ratelimit.Config `yaml:"rate_limits"`
+	Pause      Pause            `yaml:"pause"`
+}
+
+// Server holds the listeners and shutdown behavior.
+type Server struct {
+	Port         string        `yaml:"port" env:"PORT"`
+	HTTPPort     string        `yaml:"http_port" env:"HTTP_PORT"`
+	DrainTimeout time.Duration `yaml:"drain_timeout" env:"DRAIN_TIMEOUT"`
+}
+
+// Logging configures the server logger.
+type Logging struct {
+	Level  string `yaml:"level" env:"LOG_LEVEL"`   // debug, info, warn or error
+	Format string `yaml:"format" env:"LOG_FORMAT"` // text or json
+}
+
+// Downstream holds the addresses of the services the orchestrator calls.
+type Downstream struct {
+	Fetcher   string `yaml:"fetcher" env:"FETCHER_HOST"`
+	Creator   string `yaml:"creator" env:"CREATOR_HOST"`
+	Publisher string `yaml:"publisher" env:"PUBLISHER_HOST"`
+	AIContext string `yaml:"aicontext" env:"AICONTEXT_HOST"`
+	Analyzer  string `yaml:"analyzer" env:"ANALYZER_HOST"`
+}
+
+// Health configures dependency probes.
+type Health struct {
+	Interval     time.Duration `yaml:"interval" env:"HEALTH_CHECK_INTERVAL"`
+	ProbeTimeout time.Duration `yaml:"probe_timeout" env:"HEALTH_PROBE_TIMEOUT"`
+}
+
+// Runs configures the run store. Without a directory the most recent
+// MemoryLimit runs are kept in memory.
+type Runs struct {
+	Dir         string `yaml:"dir" env:"RUNS_DIR"`
+	MemoryLimit int    `yaml:"memory_limit" env:"RUNS_MEMORY_LIMIT"`
+}
+
+// Files points to the configuration and state files of optional features.
+// An empty path leaves the feature off.
+type Files struct {
+	Auth       string `yaml:"auth_config" env:"AUTH_CONFIG"`
+	Secrets    string `yaml:"secrets" env:"SECRETS_FILE"`
+	TokenState string `yaml:"token_state" env:"TOKEN_STATE_FILE"`
+	Tenants    string `yaml:"tenants" env:"TENANTS_FILE"`
+	Budgets    string `yaml:"budgets" env:"BUDGETS_FILE"`
+	Usage      string `yaml:"usage" env:"USAGE_FILE"`
+	Routing    string `yaml:"routing" env:"ROUTING_FILE"`
+	// Publishing policies, and the post history and outbox they keep.
+	PublishingPolicies string `yaml:"publishing_policies" env:"PUBLISHING_POLICIES_FILE"`
+	PublishingState    string `yaml:"publishing_state" env:"PUBLISHING_STATE_FILE"`
+	PauseState         string `yaml:"pause_state" env:"PAUSE_STATE_FILE"` // publishing pauses; in memory if unset
+	// Run leases and leader election, shared by the replicas on a host
+	// through file locks; in memory, with this replica always leading, if unset.
+	Leases string `yaml:"leases" env:"LEASE_FILE"`
+	// Items that failed within runs; in memory if unset.
+	DeadLetters string `yaml:"dead_letters" env:"DEAD_LETTERS_FILE"`
+}
+
+// Tokens configures OAuth token refresh.
+type Tokens struct {
+	MetaGraphURL    string        `yaml:"meta_graph_url" env:"META_GRAPH_URL"`
+	XAPIURL         string        `yaml:"x_api_url" env:"X_API_URL"`
+	RefreshInterval time.Duration `yaml:"refresh_interval" env:"TOKEN_REFRESH_INTERVAL"`
+}
+
+// AnalysisCache configures the analysis cache; a zero size disables it.
+type AnalysisCache struct {
+	Size int           `yaml:"size" env:"ANALYSIS_CACHE_SIZE"`
+	TTL  time.Duration `yaml:"ttl" env:"ANALYSIS_CACHE_TTL"`
+}
+
+// Leases configures the leases that keep two runs of a flow from working
+// on the same account at once.
+type Leases struct {
+	TTL        time.Duration `yaml:"ttl" env:"LEASE_TTL"`                 // renewed every third of it while a run lasts
+	OnConflict string        `yaml:"on_conflict" env:"LEASE_ON_CONFLICT"` // reject, wait or coalesce; runs may override it
+}
+
+// Leader configures the election of the replica that runs the singleton
+// duties: token refresh and outbox delivery.
+type Leader struct {
+	ID  string        `yaml:"id" env:"REPLICA_ID"` // host name and process ID if unset
+	TTL time.Duration `yaml:"ttl" env:"LEADER_TTL"`
+}
+
+// DeadLetters configures the store of items that failed within runs.
+type DeadLetters struct {
+	// QuarantineAfter is how many failures quarantine an item, so runs skip
+	// it; zero never does.
+	QuarantineAfter int `yaml:"quarantine_after" env:"DEAD_LETTER_QUARANTINE_AFTER"`
+}
+
+// Outbox configures delivery of posts deferred by publishing policies.
+type Outbox struct {
+	Interval time.Duration `yaml:"interval" env:"OUTBOX_INTERVAL"` // between checks for due posts
+}
+
+// Pause configures what paused publishing does with the drafts runs still
+// generate.
+type Pause struct {
+	Drafts string `yaml:"drafts"` // "hold" for approval in the outbox, or "discard"
+}
+
+// Flow holds the tunable settings of one flow.
+type Flow struct {
+	Tone       string        `yaml:"tone"`
+	FetchLimit int           `yaml:"fetch_limit"` // items fetched per run
+	ItemLimit  int           `yaml:"item_limit"`  // fetched items acted on per run; echo flows answer the latest only
+	Timeout    time.Duration `yaml:"timeout"`     // per run; zero means none
+	// AllOrNothing deletes a run's posts again if the run fails, so it
+	// leaves no orphaned posts.
+	AllOrNothing bool `yaml:"all_or_nothing"`
+	// Filters skip fetched items the flow should not act on.
+	Filters filter.Rules `yaml:"filters"`
+}
+
+// Default returns the configuration used for settings the file and
+// environment leave unset.
+func Default() *Config {
+	retries, _ := completeRetries(defaultRetries(), nil)
+	return &Config{
+		Server:  Server{Port: "50056", HTTPPort: "8056", DrainTimeout: time.Minute},
+		Logging: Logging{Level: "info", Format: "text"},
+		Downstream: Downstream{
+			Fetcher:   "localhost:50053",
+			Creator:   "localhost:50054",
+			Publisher: "localhost:50055",
+			AIContext: "localhost:50057",
+			Analyzer:  "localhost:50052",
+		},
+		Health:        Health{Interval: 10 * time.Second, ProbeTimeout: 5 * time.Second},
+		Runs:          Runs{MemoryLimit: 1000},
+		Tokens:        Tokens{RefreshInterval: time.Hour},
+		AnalysisCache: AnalysisCache{TTL: 24 * time.Hour},
+		Outbox:        Outbox{Interval: time.Minute},
+		Leases:        Leases{TTL: time.Minute, OnConflict: lease.OnConflictReject},
+		Leader:        Leader{TTL: 15 * time.Second},
+		DeadLetters:   DeadLetters{QuarantineAfter: 3},
+		Retries:       retries,
+		Breakers:      breaker.Config{Default: breaker.DefaultSettings},
+		Admission:     admission.Config{MaxConcurrent: 10, QueueSize: 100, RetryAfter: 5 * time.Second},
+		Flows:         DefaultFlows(),
+		Pause:         Pause{Drafts: pause.DraftsHold},
+		// Gemini's free tier allows 15 requests a minute.
+		RateLimits: ratelimit.Config{
+			Providers: map[string]ratelimit.Limit{"gemini": {PerMinute: 15, Burst: 3}},
+		},
+	}
+}
+
+// defaultRetries returns the built-in retry policies. Step policies set only
+// what differs from the default policy.
+func defaultRetries() map[string]retry.Policy {
+	return map[string]retry.Policy{
+		"default": {
+			MaxAttempts:    3,
+			InitialBackoff: 200 * time.Millisecond,
+			MaxBackoff:     5 * time.Second,
+			Multiplier:     2,
+			Jitter:         0.2,
+			AttemptTimeout: 30 * time.Second,
+		},
+		// Model calls are slow; a timed-out attempt has usually just not
+		// finished yet.
+		"generate": {AttemptTimeout: 2 * time.Minute},
+	}
+}
+
+// completeRetries completes the step policies: each starts from the default
+// policy, takes its built-in settings, then the keys the file's retries node
+// n sets for it. Zero values the file sets stay, so a step can turn off its
+// jitter or attempt timeout while the default keeps them.
+func completeRetries(policies map[string]retry.Policy, n *yaml.Node) (map[string]retry.Policy, error) {
+	def := policies["default"]
+	builtin := defaultRetries()
+	out := make(map[string]retry.Policy, len(policies))
+	for step := range policies {
+		p := def
+		if step != "default" {
+			p = builtin[step].Or(def)
+			if c := child(n, step); c != nil {
+				if err := overlay(reflect.ValueOf(&p).Elem(), c); err != nil {
+					return nil, err
+				}
+			}
+		}
+		out[step] = p
+	}
+	return out, nil
+}
+
+// DefaultFlows returns the settings of every configurable flow.
+func DefaultFlows() map[string]Flow {
+	return map[string]Flow{
+		"cross_pollinator": {Tone: "professional", FetchLimit: 3, ItemLimit: 1},
+		"facebook_echo":    {Tone: "friendly", FetchLimit: 1, ItemLimit: 1},
+		"twitter_echo":     {Tone: "witty", FetchLimit: 1, ItemLimit: 1},
+	}
+}
+
+// Load reads the configuration from path, if given, over the defaults, then
+// applies environment overrides and validates the result. Unknown keys in
+// the file are errors. Every key the file sets replaces the default, zero
+// values included; see overlay.
+func Load(path string) (*Config, error) {
+	cfg := Default()
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading config file: %w", err)
+		}
+		// Decoding into a Config first reports unknown keys and mistyped
+		// values, so overlay can take the file as well formed.
+		var file Config
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(&file); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("parsing config file: %w", err)
+		}
+		var doc yaml.Node
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("parsing config file: %w", err)
+		}
+		if err := overlay(reflect.ValueOf(cfg).Elem(), &doc); err != nil {
+			return nil, fmt.Errorf("parsing config file: %w", err)
+		}
+		if cfg.Retries, err = completeRetries(cfg.Retries, child(&doc, "retries")); err != nil {
+			return nil, fmt.Errorf("parsing config file: %w", err)
+		}
+	}
+	if err := applyEnv(reflect.ValueOf(cfg).Elem()); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// overlay decodes the YAML node n onto v. Structs and maps take only the
+// keys n sets, so the others keep their current value: a file can change one
+// setting of a flow and keep the other defaults. Everything else n sets
+// replaces v, zero values included; null leaves it alone.
+func overlay(v reflect.Value, n *yaml.Node) error {
+	switch n.Kind {
+	case yaml.DocumentNode:
+		if len(n.Content) == 0 {
+			return nil
+		}
+		return overlay(v, n.Content[0])
+	case yaml.AliasNode:
+		return overlay(v, n.Alias)
+	case yaml.ScalarNode:
+		if n.ShortTag() == "!!null" {
+			return nil
+		}
+	case yaml.MappingNode:
+		switch v.Kind() {
+		case reflect.Struct:
+			fields := yamlFields(v.Type())
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				if f, ok := fields[n.Content[i].Value]; ok {
+					if err := overlay(v.Field(f), n.Content[i+1]); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		case reflect.Map:
+			if v.IsNil() {
+				v.Set(reflect.MakeMap(v.Type()))
+			}
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				k := reflect.New(v.Type().Key())
+				if err := n.Content[i].Decode(k.Interface()); err != nil {
+					return err
+				}
+				e := reflect.New(v.Type().Elem()).Elem()
+				if cur := v.MapIndex(k.Elem()); cur.IsValid() {
+					e.Set(cur)
+				}
+				if err := overlay(e, n.Content[i+1]); err != nil {
+					return err
+				}
+				v.SetMapIndex(k.Elem(), e)
+			}
+			return nil
+		}
+	}
+	return n.Decode(v.Addr().Interface())
+}
+
+// yamlFields maps the YAML keys of struct type t to its field indexes.
+func yamlFields(t reflect.Type) map[string]int {
+	fields := make(map[string]int, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		switch name {
+		case "-":
+			continue
+		case "":
+			name = strings.ToLower(f.Name)
+		}
+		if f.IsExported() {
+			fields[name] = i
+		}
+	}
+	return fields
+}
+
+// child returns the value of key in the mapping node n, or nil.
+func child(n *yaml.Node, key string) *yaml.Node {
+	if n != nil && n.Kind == yaml.DocumentNode && len(n.Content) > 0 {
+		n = n.Content[0]
+	}
+	if n == nil || n.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// applyEnv overrides fields tagged with env from set environment variables.
+func applyEnv(v reflect.Value) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := v.Field(i)
+		if f.Kind() == reflect.Struct {
+			if err := applyEnv(f); err != nil {
+				return err
+			}
+			continue
+		}
+		name := t.Field(i).Tag.Get("env")
+		if name == "" {
+			continue
+		}
+		val, ok := os.LookupEnv(name)
+		if !ok || val == "" {
+			continue
+		}
+		switch {
+		case f.Type() == reflect.TypeOf(time.Duration(0)):
+			d, err := time.ParseDuration(val)
+			if err != nil {
+				return fmt.Errorf("invalid %s: %w", name, err)
+			}
+			f.SetInt(int64(d))
+		case f.Kind() == reflect.Int:
+			n, err := strconv.Atoi(val)
+			if err != nil {
+				return fmt.Errorf("invalid %s: want an integer, got %q", name, val)
+			}
+			f.SetInt(int64(n))
+		default:
+			f.SetString(val)
+		}
+	}
+	return nil
+}
+
+// Validate checks the configuration for values the server cannot run with.
+func (c *Config) Validate() error {
+	var errs []string
+	add := func(format string, args ...any) { errs = append(errs, fmt.Sprintf(format, args...)) }
+
+	for _, p := range []struct{ name, port string }{{"server.port", c.Server.Port}, {"server.http_port", c.Server.HTTPPort}} {
+		if n, err := strconv.Atoi(p.port); err != nil || n <= 0 || n > 65535 {
+			add("%s must be a port number, got %q", p.name, p.port)
+		}
+	}
+	if c.Server.DrainTimeout < 0 {
+		add("server.drain_timeout must not be negative")
+	}
+	switch strings.ToLower(c.Logging.Level) {
+	case "debug", "info", "warn", "error":
+	default:
+		add("logging.level must be debug, info, warn or error, got %q", c.Logging.Level)
+	}
+	switch strings.ToLower(c.Logging.Format) {
+	case "text", "json":
+	default:
+		add("logging.format must be text or json, got %q", c.Logging.Format)
+	}
+	d := c.Downstream
+	for _, ds := range []struct{ name, addr string }{
+		{"fetcher", d.Fetcher}, {"creator", d.Creator}, {"publisher", d.Publisher}, {"aicontext", d.AIContext}, {"analyzer", d.Analyzer},
+	} {
+		if ds.addr == "" {
+			add("downstream.%s must be set", ds.name)
+		}
+	}
+	if c.Health.Interval <= 0 || c.Health.ProbeTimeout <= 0 {
+		add("health.interval and health.probe_timeout must be positive")
+	}
+	if c.Runs.MemoryLimit <= 0 {
+		add("runs.memory_limit must be positive")
+	}
+	if c.Tokens.RefreshInterval <= 0 {
+		add("tokens.refresh_interval must be positive")
+	}
+	if c.AnalysisCache.Size < 0 {
+		add("analysis_cache.size must not be negative")
+	}
+	if c.AnalysisCache.Size > 0 && c.AnalysisCache.TTL <= 0 {
+		add("analysis_cache.ttl must be positive")
+	}
+	if c.Outbox.Interval <= 0 {
+		add("outbox.interval must be positive")
+	}
+	if c.Leases.TTL <= 0 {
+		add("leases.ttl must be positive")
+	}
+	if !lease.ValidOnConflict(c.Leases.OnConflict) {
+		add("leases.on_conflict must be reject, wait or coalesce, got %q", c.Leases.OnConflict)
+	}
+	if c.Leader.TTL <= 0 {
+		add("leader.ttl must be positive")
+	}
+	if c.DeadLetters.QuarantineAfter < 0 {
+		add("dead_letters.quarantine_after must not be negative")
+	}
+	errs = append(errs, validateRetries(c.Retries)...)
+	if err := c.Breakers.Validate(); err != nil {
+		errs = append(errs, err.Error())
+	}
+	if err := c.Admission.Validate(); err != nil {
+		errs = append(errs, err.Error())
+	}
+	errs = append(errs, validateFlows(c.Flows)...)
+	if err := c.RateLimits.Validate(); err != nil {
+		errs = append(errs, err.Error())
+	}
+	switch c.Pause.Drafts {
+	case pause.DraftsHold, pause.DraftsDiscard:
+	default:
+		add("pause.drafts must be hold or discard, got %q", c.Pause.Drafts)
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid config: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// RetrySteps are the pipeline steps with a retry policy.
+var RetrySteps = []string{"fetch", "analyze", "get_context", "generate", "publish", "delete"}
+
+func validateRetries(policies map[string]retry.Policy) []string {
+	var errs []string
+	if policies["default"].MaxAttempts <= 0 {
+		errs = append(errs, "retries.default.max_attempts must be positive")
+	}
+	names := sortedKeys(policies)
+	for _, name := range names {
+		if name != "default" && !slices.Contains(RetrySteps, name) {
+			errs = append(errs, fmt.Sprintf("retries: unknown step %q (want default or one of %s)", name, strings.Join(RetrySteps, ", ")))
+			continue
+		}
+		if err := policies[name].Validate(); err != nil {
+			errs = append(errs, fmt.Sprintf("retries.%s: %v", name, err))
+		}
+	}
+	return errs
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func validateFlows(flows map[string]Flow) []string {
+	known := DefaultFlows()
+	names := sortedKeys(flows)
+	var errs []string
+	for _, name := range names {
+		f := flows[name]
+		if _, ok := known[name]; !ok {
+			errs = append(errs, fmt.Sprintf("flows: unknown flow %q", name))
+			continue
+		}
+		if f.Tone == "" {
+			errs = append(errs, fmt.Sprintf("flows.%s.tone must be set", name))
+		}
+		if f.FetchLimit <= 0 || f.ItemLimit <= 0 {
+			errs = append(errs, fmt.Sprintf("flows.%s: fetch_limit and item_limit must be positive", name))
+		}
+		if f.Timeout < 0 {
+			errs = append(errs, fmt.Sprintf("flows.%s.timeout must not be negative", name))
+		}
+		if err := f.Filters.Validate(); err != nil {
+			errs = append(errs, fmt.Sprintf("flows.%s.filters: %v", name, err))
+		}
+	}
+	return errs
+}
+
+// Marshal encodes the configuration as YAML.
+func (c *Config) Marshal() ([]byte, error) {
+	return yaml.Marshal(c)
+}
@@ -0,0 +1,190 @@
+package config
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfig(t *testing.T, dir, data string) string {
+	t.Helper()
+	path := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		check   func(t *testing.T, c *Config)
+		wantErr string
+	}{
+		{
+			name: "empty file keeps defaults",
+			file: "",
+			check: func(t *testing.T, c *Config) {
+				if c.Server.Port != "50056" || c.Flows["twitter_echo"].Tone != "witty" {
+					t.Errorf("defaults not kept: port %q, twitter tone %q", c.Server.Port, c.Flows["twitter_echo"].Tone)
+				}
+			},
+		},
+		{
+			name: "partial flow keeps its other defaults",
+			file: "flows:\n  twitter_echo:\n    tone: playful\n    timeout: 2m\n",
+			check: func(t *testing.T, c *Config) {
+				f := c.Flows["twitter_echo"]
+				if f.Tone != "playful" || f.Timeout != 2*time.Minute || f.FetchLimit != 1 || f.ItemLimit != 1 {
+					t.Errorf("twitter_echo = %+v", f)
+				}
+				if c.Flows["facebook_echo"].Tone != "friendly" {
+					t.Errorf("facebook_echo lost its defaults: %+v", c.Flows["facebook_echo"])
+				}
+			},
+		},
+		{
+			name: "explicit zero replaces a default",
+			file: "server:\n  drain_timeout: 0s\nretries:\n  default:\n    jitter: 0\n  generate:\n    attempt_timeout: 0s\n",
+			check: func(t *testing.T, c *Config) {
+				if c.Server.DrainTimeout != 0 {
+					t.Errorf("drain_timeout = %v, want 0", c.Server.DrainTimeout)
+				}
+				if c.Retries["default"].Jitter != 0 || c.Retries["default"].MaxAttempts != 3 {
+					t.Errorf("default retry policy = %+v", c.Retries["default"])
+				}
+				if g := c.Retries["generate"]; g.AttemptTimeout != 0 || g.MaxAttempts != 3 || g.Jitter != 0 {
+					t.Errorf("generate retry policy = %+v", g)
+				}
+			},
+		},
+		{
+			name: "step policy inherits the file's default",
+			file: "retries:\n  default:\n    max_attempts: 5\n  fetch:\n    max_backoff: 1s\n",
+			check: func(t *testing.T, c *Config) {
+				if f := c.Retries["fetch"]; f.MaxAttempts != 5 || f.MaxBackoff != time.Second || f.InitialBackoff != 200*time.Millisecond {
+					t.Errorf("fetch retry policy = %+v", f)
+				}
+				if g := c.Retries["generate"]; g.MaxAttempts != 5 || g.AttemptTimeout != 2*time.Minute {
+					t.Errorf("generate retry policy = %+v", g)
+				}
+			},
+		},
+		{
+			name: "null leaves the default",
+			file: "server:\n  port: ~\n",
+			check: func(t *testing.T, c *Config) {
+				if c.Server.Port != "50056" {
+					t.Errorf("port = %q", c.Server.Port)
+				}
+			},
+		},
+		{
+			name: "filters",
+			file: "flows:\n  cross_pollinator:\n    filters:\n      min_chars: 20\n      blocked_keywords: [giveaway]\n",
+			check: func(t *testing.T, c *Config) {
+				f := c.Flows["cross_pollinator"]
+				if f.Filters.MinChars != 20 || len(f.Filters.BlockedKeywords) != 1 || f.Tone != "professional" {
+					t.Errorf("cross_pollinator = %+v", f)
+				}
+			},
+		},
+		{name: "unknown key", file: "server:\n  prot: \"1\"\n", wantErr: "field prot not found"},
+		{name: "mistyped value", file: "runs:\n  memory_limit: lots\n", wantErr: "parsing config file"},
+		{name: "unknown flow", file: "flows:\n  myspace_echo:\n    tone: x\n", wantErr: `unknown flow "myspace_echo"`},
+		{name: "zeroed required setting", file: "runs:\n  memory_limit: 0\n", wantErr: "runs.memory_limit must be positive"},
+		{name: "bad port", file: "server:\n  port: http\n", wantErr: "server.port must be a port number"},
+		{name: "unknown retry step", file: "retries:\n  publsh:\n    max_attempts: 2\n", wantErr: `unknown step "publsh"`},
+		{name: "bad filters", file: "flows:\n  twitter_echo:\n    filters:\n      min_chars: 10\n      max_chars: 5\n", wantErr: "flows.twitter_echo.filters"},
+		{name: "bad pause drafts", file: "pause:\n  drafts: keep\n", wantErr: "pause.drafts must be hold or discard"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := Load(writeConfig(t, t.TempDir(), tt.file))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Load() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			tt.check(t, cfg)
+		})
+	}
+}
+
+func TestLoadEnv(t *testing.T) {
+	t.Setenv("PORT", "6000")
+	t.Setenv("DRAIN_TIMEOUT", "5s")
+	t.Setenv("RUNS_MEMORY_LIMIT", "10")
+	cfg, err := Load(writeConfig(t, t.TempDir(), "server:\n  port: \"7000\"\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Server.Port != "6000" || cfg.Server.DrainTimeout != 5*time.Second || cfg.Runs.MemoryLimit != 10 {
+		t.Errorf("env overrides not applied: %+v %+v", cfg.Server, cfg.Runs)
+	}
+
+	t.Setenv("RUNS_MEMORY_LIMIT", "ten")
+	if _, err := Load(""); err == nil || !strings.Contains(err.Error(), "RUNS_MEMORY_LIMIT") {
+		t.Errorf("Load() error = %v, want invalid RUNS_MEMORY_LIMIT", err)
+	}
+}
+
+func TestDefaultValid(t *testing.T) {
+	if err := Default().Validate(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := Default().Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load(writeConfig(t, t.TempDir(), string(data)))
+	if err != nil {
+		t.Fatalf("printed default config does not load: %v", err)
+	}
+	if cfg.Retries["generate"] != Default().Retries["generate"] {
+		t.Errorf("generate retry policy = %+v after a round trip", cfg.Retries["generate"])
+	}
+}
+
+func TestWatcherReload(t *testing.T) {
+	dir := t.TempDir()
+	path := writeConfig(t, dir, "server:\n  port: \"7000\"\nflows:\n  twitter_echo:\n    tone: playful\n")
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := NewWatcher(path, cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	var got *Config
+	w.OnReload(func(c *Config) { got = c })
+
+	writeConfig(t, dir, "server:\n  port: \"7001\"\nflows:\n  twitter_echo:\n    tone: dry\npause:\n  drafts: discard\n")
+	if err := w.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if got != w.Current() {
+		t.Fatal("subscriber did not get the applied config")
+	}
+	if got.Flows["twitter_echo"].Tone != "dry" || got.Pause.Drafts != "discard" {
+		t.Errorf("reloadable sections not applied: %+v %+v", got.Flows["twitter_echo"], got.Pause)
+	}
+	if got.Server.Port != "7000" {
+		t.Errorf("port = %q, want the startup value kept until restart", got.Server.Port)
+	}
+
+	writeConfig(t, dir, "flows:\n  twitter_echo:\n    fetch_limit: -1\n")
+	if err := w.Reload(); err == nil {
+		t.Fatal("invalid config was applied")
+	}
+	if w.Current().Flows["twitter_echo"].Tone != "dry" {
+		t.Errorf("running config changed by a rejected reload")
+	}
+}
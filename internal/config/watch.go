@@ -0,0 +1,125 @@
+package config
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/Optiq-CTO/orchestrator/internal/ratelimit"
+)
+
+// Watcher reloads the configuration file on SIGHUP or when it changes, and
+// hands the reloadable sections to subscribers. Changes to other sections
+// are logged and ignored until the next restart.
+type Watcher struct {
+	path   string
+	logger *slog.Logger
+
+	mu      sync.Mutex
+	current *Config
+	modTime time.Time
+	subs    []func(*Config)
+}
+
+// NewWatcher returns a watcher for path, whose contents loaded as cfg.
+func NewWatcher(path string, cfg *Config, logger *slog.Logger) *Watcher {
+	w := &Watcher{path: path, logger: logger, current: cfg}
+	if fi, err := os.Stat(path); err == nil {
+		w.modTime = fi.ModTime()
+	}
+	return w
+}
+
+// OnReload registers fn to be called with the new configuration after each
+// successful reload.
+func (w *Watcher) OnReload(fn func(*Config)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.subs = append(w.subs, fn)
+}
+
+// Current returns the configuration in effect.
+func (w *Watcher) Current() *Config {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.current
+}
+
+// Run reloads on SIGHUP, and when the file's modification time changes,
+// checked every interval, until ctx is done.
+func (w *Watcher) Run(ctx context.Context, interval time.Duration) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			w.logger.Info("SIGHUP received, reloading config", "path", w.path)
+			w.Reload()
+		case <-ticker.C:
+			fi, err := os.Stat(w.path)
+			if err != nil {
+				continue
+			}
+			w.mu.Lock()
+			changed := !fi.ModTime().Equal(w.modTime)
+			w.mu.Unlock()
+			if changed {
+				w.logger.Info("config file changed, reloading", "path", w.path)
+				w.Reload()
+			}
+		}
+	}
+}
+
+// Reload loads the file again and applies its reloadable sections. An
+// invalid file is rejected as a whole and the running configuration kept.
+func (w *Watcher) Reload() error {
+	w.mu.Lock()
+	if fi, err := os.Stat(w.path); err == nil {
+		w.modTime = fi.ModTime()
+	}
+	w.mu.Unlock()
+
+	next, err := Load(w.path)
+	if err != nil {
+		w.logger.Error("config reload failed, keeping the running config", "error", err)
+		return err
+	}
+
+	w.mu.Lock()
+	if !reflect.DeepEqual(next.static(), w.current.static()) {
+		w.logger.Warn("config changes outside the reloadable sections need a restart; ignoring them")
+	}
+	applied := *w.current
+	applied.Flows = next.Flows
+	applied.RateLimits = next.RateLimits
+	applied.Pause = next.Pause
+	w.current = &applied
+	subs := append([]func(*Config){}, w.subs...)
+	w.mu.Unlock()
+
+	for _, fn := range subs {
+		fn(&applied)
+	}
+	w.logger.Info("config reloaded")
+	return nil
+}
+
+// static returns c without its reloadable sections.
+func (c *Config) static() Config {
+	s := *c
+	s.Flows = nil
+	s.RateLimits = ratelimit.Config{}
+	s.Pause = Pause{}
+	return s
+}
@@ -0,0 +1,137 @@
+// Package critique builds the prompts and parses the verdicts of the
+// critique-and-revise loop: a draft is critiqued against the account's
+// brand rules, then rewritten with that feedback until it is good enough.
+package critique
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Critics.
+const (
+	// CriticAnalyzer scores drafts with the analyzer and platform-fit checks.
+	// Brand rules then only guide the rewrite.
+	CriticAnalyzer = "analyzer"
+	// CriticJudge asks the creator to grade drafts with a judge prompt.
+	CriticJudge = "judge"
+)
+
+// DefaultJudgePrompt is used when no judge prompt is configured. Templates
+// may use {platform}, {rules} and {draft}.
+const DefaultJudgePrompt = `You are a strict social media editor. Grade this {platform} post draft against the brand rules.
+Brand rules:
+{rules}
+Draft:
+{draft}
+Reply with "Score: N/10" on the first line, then one short bullet per problem. Do not rewrite the post.`
+
+// Verdict is one critique of a draft.
+type Verdict struct {
+	// Score is normalized to 0..1; higher is better.
+	Score    float64
+	Feedback string
+}
+
+// JudgePrompt fills a judge prompt template.
+func JudgePrompt(template, platform string, rules []string, draft string) string {
+	if template == "" {
+		template = DefaultJudgePrompt
+	}
+	return strings.NewReplacer(
+		"{platform}", platform,
+		"{rules}", formatRules(rules),
+		"{draft}", draft,
+	).Replace(template)
+}
+
+var scoreRe = regexp.MustCompile(`(?i)score\W{0,3}(\d+(?:\.\d+)?)\s*(?:/\s*(100|10)\b)?`)
+
+// ErrNoScore is returned for judge replies without a recognizable score.
+var ErrNoScore = errors.New("judge reply has no score")
+
+// ParseJudge reads a judge reply of the form "Score: N/10" followed by
+// feedback. Scores out of 100 and bare 0..1 scores are also accepted.
+func ParseJudge(reply string) (Verdict, error) {
+	m := scoreRe.FindStringSubmatchIndex(reply)
+	if m == nil {
+		return Verdict{}, ErrNoScore
+	}
+	n, err := strconv.ParseFloat(reply[m[2]:m[3]], 64)
+	if err != nil {
+		return Verdict{}, fmt.Errorf("%w: %v", ErrNoScore, err)
+	}
+	switch {
+	case m[4] >= 0 && reply[m[4]:m[5]] == "100":
+		n /= 100
+	case m[4] >= 0 || n > 1:
+		n /= 10
+	}
+	if n > 1 {
+		n = 1
+	}
+	feedback := strings.TrimSpace(reply[:m[0]] + reply[m[1]:])
+	return Verdict{Score: n, Feedback: feedback}, nil
+}
+
+// IssueFeedback turns platform-fit issues and analyzer signals into
+// instructions for the reviser.
+func IssueFeedback(issues []string, riskScore float32) string {
+	var lines []string
+	for _, issue := range issues {
+		switch {
+		case issue == "preamble":
+			lines = append(lines, "- Remove the preamble; start directly with the post.")
+		case issue == "explanation":
+			lines = append(lines, "- Remove any explanation of why the post works.")
+		case issue == "post_tags":
+			lines = append(lines, "- Do not include tags or markup around the post.")
+		case issue == "self_reference":
+			lines = append(lines, "- Do not mention being an AI.")
+		case issue == "placeholder":
+			lines = append(lines, "- Replace placeholders with real content.")
+		case issue == "negative_sentiment":
+			lines = append(lines, "- Make the tone more positive.")
+		case strings.HasPrefix(issue, "too_long"):
+			lines = append(lines, fmt.Sprintf("- Shorten the post to fit the platform limit %s.", strings.TrimPrefix(issue, "too_long")))
+		default:
+			lines = append(lines, "- Fix: "+issue+".")
+		}
+	}
+	if riskScore >= 0.3 {
+		lines = append(lines, fmt.Sprintf("- Reduce risk (score %.2f): avoid controversial, medical or absolute claims.", riskScore))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// RevisePrompt asks the creator to rewrite draft with feedback.
+func RevisePrompt(platform string, rules []string, draft, feedback string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Revise this %s post. Keep its message, fix every point of feedback and follow the brand rules.\n", platform)
+	if len(rules) > 0 {
+		fmt.Fprintf(&b, "Brand rules:\n%s\n", formatRules(rules))
+	}
+	fmt.Fprintf(&b, "Feedback:\n%s\nDraft:\n%s", feedback, draft)
+	return b.String()
+}
+
+// ParseRules splits a newline-separated rules param.
+func ParseRules(v string) []string {
+	var rules []string
+	for _, line := range strings.Split(v, "\n") {
+		if line = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "-")); line != "" {
+			rules = append(rules, line)
+		}
+	}
+	return rules
+}
+
+func formatRules(rules []string) string {
+	if len(rules) == 0 {
+		return "- (none)"
+	}
+	return "- " + strings.Join(rules, "\n- ")
+}
@@ -0,0 +1,91 @@
+package critique
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseJudge(t *testing.T) {
+	tests := []struct {
+		reply        string
+		wantScore    float64
+		wantFeedback string
+		wantErr      bool
+	}{
+		{"Score: 7/10\n- Too long", 0.7, "- Too long", false},
+		{"score 85/100", 0.85, "", false},
+		{"Score: 8\n- ok", 0.8, "- ok", false},
+		{"Score: 0.9", 0.9, "", false},
+		{"Overall score: 12/10", 1, "Overall", false},
+		{"Looks good to me.", 0, "", true},
+	}
+	for _, tt := range tests {
+		v, err := ParseJudge(tt.reply)
+		if tt.wantErr {
+			if !errors.Is(err, ErrNoScore) {
+				t.Errorf("ParseJudge(%q) error = %v, want ErrNoScore", tt.reply, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseJudge(%q) = %v", tt.reply, err)
+			continue
+		}
+		if diff := v.Score - tt.wantScore; diff > 1e-9 || diff < -1e-9 || v.Feedback != tt.wantFeedback {
+			t.Errorf("ParseJudge(%q) = %+v, want score %v feedback %q", tt.reply, v, tt.wantScore, tt.wantFeedback)
+		}
+	}
+}
+
+func TestParseRules(t *testing.T) {
+	got := ParseRules("- No medical claims.\n\n  -  At most two emojis. \nBe kind")
+	want := []string{"No medical claims.", "At most two emojis.", "Be kind"}
+	if !slices.Equal(got, want) {
+		t.Errorf("ParseRules = %q, want %q", got, want)
+	}
+}
+
+func TestJudgePrompt(t *testing.T) {
+	got := JudgePrompt("", "twitter", []string{"Be kind", "No emojis"}, "hello")
+	for _, want := range []string{"twitter post draft", "- Be kind\n- No emojis", "Draft:\nhello"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("JudgePrompt lacks %q:\n%s", want, got)
+		}
+	}
+	if got := JudgePrompt("{platform}|{rules}|{draft}", "x", nil, "d"); got != "x|- (none)|d" {
+		t.Errorf("custom template = %q", got)
+	}
+}
+
+func TestIssueFeedback(t *testing.T) {
+	tests := []struct {
+		issues []string
+		risk   float32
+		want   []string
+	}{
+		{nil, 0, nil},
+		{[]string{"preamble"}, 0, []string{"Remove the preamble"}},
+		{[]string{"too_long(300>280)"}, 0, []string{"limit (300>280)"}},
+		{[]string{"odd"}, 0.5, []string{"Fix: odd.", "Reduce risk (score 0.50)"}},
+	}
+	for _, tt := range tests {
+		got := IssueFeedback(tt.issues, tt.risk)
+		if len(tt.want) == 0 && got != "" {
+			t.Errorf("IssueFeedback(%v, %v) = %q, want none", tt.issues, tt.risk, got)
+		}
+		for _, w := range tt.want {
+			if !strings.Contains(got, w) {
+				t.Errorf("IssueFeedback(%v, %v) = %q, lacks %q", tt.issues, tt.risk, got, w)
+			}
+		}
+	}
+}
+
+func TestRevisePrompt(t *testing.T) {
+	got := RevisePrompt("twitter", nil, "draft", "- shorter")
+	if strings.Contains(got, "Brand rules") || !strings.HasSuffix(got, "Feedback:\n- shorter\nDraft:\ndraft") {
+		t.Errorf("RevisePrompt = %q", got)
+	}
+}
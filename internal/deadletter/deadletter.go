@@ -0,0 +1,252 @@
+// Package deadletter keeps the items that failed within runs, so they are
+// not lost when a flow skips them. Items that keep failing are quarantined:
+// runs skip them until they are retried by hand or discarded.
+package deadletter
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Optiq-CTO/orchestrator/internal/statefile"
+)
+
+// Dead letter statuses.
+const (
+	StatusPending     = "pending"     // runs try the item again when they fetch it
+	StatusQuarantined = "quarantined" // runs skip the item
+)
+
+// ErrNotFound is returned for unknown dead letters.
+var ErrNotFound = errors.New("dead letter not found")
+
+// Entry is one failed item and what is needed to process it again.
+type Entry struct {
+	ID string `json:"id"`
+	// Key identifies the item within its flow and target, so repeated
+	// failures of the same item add up on one entry.
+	Key       string   `json:"key"`
+	Flow      string   `json:"flow"`
+	RunID     string   `json:"run_id"` // the run it last failed in
+	Tenant    string   `json:"tenant,omitempty"`
+	AccountID string   `json:"account_id,omitempty"` // the registered account the run was for
+	Accounts  []string `json:"accounts,omitempty"`   // credential store accounts the run used
+	// Params are the flow params to run the item again with.
+	Params         map[string]string `json:"params,omitempty"`
+	SourcePlatform string            `json:"source_platform"`
+	SourceID       string            `json:"source_id"`
+	Source         string            `json:"source"`          // the content the flow worked from
+	Step           string            `json:"step"`            // the step that failed, e.g. "remix" or "publish"
+	Draft          string            `json:"draft,omitempty"` // generated before the failure, if any
+	Error          string            `json:"error"`
+	Attempts       int               `json:"attempts"`
+	Status         string            `json:"status"`
+	FirstFailedAt  time.Time         `json:"first_failed_at"`
+	LastFailedAt   time.Time         `json:"last_failed_at"`
+}
+
+func (e *Entry) clone() Entry {
+	c := *e
+	c.Accounts = append([]string(nil), e.Accounts...)
+	if e.Params != nil {
+		c.Params = make(map[string]string, len(e.Params))
+		for k, v := range e.Params {
+			c.Params[k] = v
+		}
+	}
+	return c
+}
+
+// Store keeps the dead letters. It is safe for concurrent use, and a
+// file-backed store may be shared by the processes on one host: every call
+// re-reads the file under its lock.
+type Store struct {
+	mu      sync.Mutex
+	path    string
+	entries map[string]*Entry // by ID
+	now     func() time.Time
+
+	quarantineAfter int
+}
+
+// NewStore returns a store that keeps dead letters in memory and
+// quarantines items after quarantineAfter failures; zero never does.
+func NewStore(quarantineAfter int) *Store {
+	return &Store{entries: make(map[string]*Entry), now: time.Now, quarantineAfter: quarantineAfter}
+}
+
+// OpenStore returns a store persisted to a JSON file at path, loading it
+// first if it exists.
+func OpenStore(path string, quarantineAfter int) (*Store, error) {
+	s := NewStore(quarantineAfter)
+	s.path = path
+	if err := s.update(func() bool { return false }); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Fail records a failure of the item e.Key. A first failure adds e; later
+// ones update the entry and count the attempt, quarantining the item once
+// it has failed often enough. It returns the stored entry.
+func (s *Store) Fail(e Entry) (Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := s.update(func() bool {
+		s.fail(&e)
+		return true
+	})
+	return e, err
+}
+
+// fail merges e into the entry of its item. Callers hold s.mu.
+func (s *Store) fail(e *Entry) {
+	now := s.now().UTC()
+	e.Attempts, e.Status, e.FirstFailedAt = 1, StatusPending, now
+	if cur := s.byKey(e.Key); cur != nil {
+		e.ID = cur.ID
+		e.Attempts = cur.Attempts + 1
+		e.Status = cur.Status
+		e.FirstFailedAt = cur.FirstFailedAt
+		if e.Draft == "" {
+			e.Draft = cur.Draft
+		}
+	} else {
+		e.ID = newID()
+	}
+	if s.quarantineAfter > 0 && e.Attempts >= s.quarantineAfter {
+		e.Status = StatusQuarantined
+	}
+	e.LastFailedAt = now
+	stored := e.clone()
+	s.entries[e.ID] = &stored
+}
+
+// Quarantined reports whether runs should skip the item key. If the state
+// file cannot be read, the entries last read apply.
+func (s *Store) Quarantined(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.update(func() bool { return false })
+	e := s.byKey(key)
+	return e != nil && e.Status == StatusQuarantined
+}
+
+// Resolve removes the entry of item key, if any, once the item succeeded.
+func (s *Store) Resolve(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.update(func() bool {
+		e := s.byKey(key)
+		if e == nil {
+			return false
+		}
+		delete(s.entries, e.ID)
+		return true
+	})
+}
+
+// Discard removes an entry, giving up on the item, and returns it.
+func (s *Store) Discard(id string) (Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var e *Entry
+	if err := s.update(func() bool {
+		if e = s.entries[id]; e != nil {
+			delete(s.entries, id)
+		}
+		return e != nil
+	}); err != nil {
+		return Entry{}, err
+	}
+	if e == nil {
+		return Entry{}, ErrNotFound
+	}
+	return e.clone(), nil
+}
+
+// Get returns one entry.
+func (s *Store) Get(id string) (Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.update(func() bool { return false }); err != nil {
+		return Entry{}, err
+	}
+	e, ok := s.entries[id]
+	if !ok {
+		return Entry{}, ErrNotFound
+	}
+	return e.clone(), nil
+}
+
+// Entries lists the dead letters, oldest failure first. An empty status
+// lists all.
+func (s *Store) Entries(status string) []Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.update(func() bool { return false })
+	var out []Entry
+	for _, e := range s.entries {
+		if status == "" || e.Status == status {
+			out = append(out, e.clone())
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].FirstFailedAt.Equal(out[j].FirstFailedAt) {
+			return out[i].FirstFailedAt.Before(out[j].FirstFailedAt)
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out
+}
+
+// byKey returns the entry of item key. Callers hold s.mu.
+func (s *Store) byKey(key string) *Entry {
+	for _, e := range s.entries {
+		if e.Key == key {
+			return e
+		}
+	}
+	return nil
+}
+
+// update re-reads the entries from the state file under its lock, runs fn,
+// and writes them back if fn reports a change. Callers hold s.mu.
+func (s *Store) update(fn func() bool) error {
+	if s.path == "" {
+		fn()
+		return nil
+	}
+	var entries []*Entry
+	err := statefile.Update(s.path, &entries, func() bool {
+		s.entries = make(map[string]*Entry, len(entries))
+		for _, e := range entries {
+			s.entries[e.ID] = e
+		}
+		if !fn() {
+			return false
+		}
+		entries = make([]*Entry, 0, len(s.entries))
+		for _, e := range s.entries {
+			entries = append(entries, e)
+		}
+		sort.Slice(entries, func(i, j int) bool { return entries[i].ID < entries[j].ID })
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("dead letter file: %w", err)
+	}
+	return nil
+}
+
+func newID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "dl-" + time.Now().UTC().Format("20060102T150405.000000000")
+	}
+	return "dl-" + hex.EncodeToString(b)
+}
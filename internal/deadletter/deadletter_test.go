@@ -0,0 +1,101 @@
+package deadletter
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFail(t *testing.T) {
+	tests := []struct {
+		name            string
+		quarantineAfter int
+		failures        int
+		wantStatus      string
+	}{
+		{"first failure", 3, 1, StatusPending},
+		{"below threshold", 3, 2, StatusPending},
+		{"quarantined", 3, 3, StatusQuarantined},
+		{"stays quarantined", 3, 5, StatusQuarantined},
+		{"never quarantined", 0, 10, StatusPending},
+		{"quarantined at once", 1, 1, StatusQuarantined},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewStore(tt.quarantineAfter)
+			now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+			s.now = func() time.Time { return now }
+			var first, last Entry
+			for i := 0; i < tt.failures; i++ {
+				e, err := s.Fail(Entry{Key: "cross_pollinator/reddit/t3_abc", Step: "publish", Draft: map[bool]string{true: "draft"}[i == 0]})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if i == 0 {
+					first = e
+				}
+				last = e
+				now = now.Add(time.Minute)
+			}
+			if last.ID != first.ID || last.Attempts != tt.failures || last.Status != tt.wantStatus {
+				t.Errorf("after %d failures: id %s (first %s), attempts %d, status %s; want status %s",
+					tt.failures, last.ID, first.ID, last.Attempts, last.Status, tt.wantStatus)
+			}
+			if !last.FirstFailedAt.Equal(first.FirstFailedAt) || (tt.failures > 1 && !last.LastFailedAt.After(first.LastFailedAt)) {
+				t.Errorf("failure times: first %v, last %v", last.FirstFailedAt, last.LastFailedAt)
+			}
+			if last.Draft != "draft" {
+				t.Errorf("draft = %q, want the earlier draft kept", last.Draft)
+			}
+			if got := s.Quarantined(first.Key); got != (tt.wantStatus == StatusQuarantined) {
+				t.Errorf("Quarantined() = %v", got)
+			}
+			if n := len(s.Entries("")); n != 1 {
+				t.Errorf("%d entries, want 1", n)
+			}
+		})
+	}
+}
+
+func TestStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dead_letters.json")
+	s, err := OpenStore(path, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, _ := s.Fail(Entry{Key: "a", Params: map[string]string{"subreddit": "golang"}})
+	b, _ := s.Fail(Entry{Key: "b"})
+	s.Fail(Entry{Key: "b"})
+	c, _ := s.Fail(Entry{Key: "c"})
+
+	if err := s.Resolve("c"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Resolve("unknown"); err != nil {
+		t.Errorf("Resolve(unknown) = %v", err)
+	}
+	if _, err := s.Get(c.ID); err != ErrNotFound {
+		t.Errorf("resolved entry still there: %v", err)
+	}
+
+	reopened, err := OpenStore(path, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := reopened.Entries(StatusQuarantined); len(got) != 1 || got[0].ID != b.ID {
+		t.Errorf("quarantined after reopening = %+v, want %s", got, b.ID)
+	}
+	got, err := reopened.Get(a.ID)
+	if err != nil || got.Params["subreddit"] != "golang" || got.Status != StatusPending {
+		t.Errorf("Get(%s) = %+v, %v", a.ID, got, err)
+	}
+	if _, err := reopened.Discard(b.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reopened.Discard(b.ID); err != ErrNotFound {
+		t.Errorf("second Discard() = %v, want ErrNotFound", err)
+	}
+	if reopened.Quarantined("b") {
+		t.Error("discarded item still quarantined")
+	}
+}
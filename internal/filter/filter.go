@@ -0,0 +1,66 @@
+// Package filter decides which fetched items a flow acts on. Rules live in
+// each flow's config section, so they hot reload with the flows.
+package filter
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Rules filter items by length, keywords and analyzed sentiment. Zero
+// fields don't filter.
+type Rules struct {
+	MinChars          int      `yaml:"min_chars"`
+	MaxChars          int      `yaml:"max_chars"`
+	BlockedKeywords   []string `yaml:"blocked_keywords"`   // case-insensitive
+	RequiredKeywords  []string `yaml:"required_keywords"`  // at least one must appear, if set
+	BlockedSentiments []string `yaml:"blocked_sentiments"` // e.g. "negative"; items without analysis pass
+}
+
+// Validate rejects rules no item could pass.
+func (r Rules) Validate() error {
+	switch {
+	case r.MinChars < 0 || r.MaxChars < 0:
+		return errors.New("min_chars and max_chars must not be negative")
+	case r.MaxChars > 0 && r.MinChars > r.MaxChars:
+		return errors.New("min_chars must not exceed max_chars")
+	}
+	return nil
+}
+
+// Reject returns why an item with text and sentiment fails the rules, or ""
+// if it passes.
+func (r Rules) Reject(text, sentiment string) string {
+	n := len([]rune(text))
+	if r.MinChars > 0 && n < r.MinChars {
+		return fmt.Sprintf("shorter than %d characters", r.MinChars)
+	}
+	if r.MaxChars > 0 && n > r.MaxChars {
+		return fmt.Sprintf("longer than %d characters", r.MaxChars)
+	}
+	lower := strings.ToLower(text)
+	for _, k := range r.BlockedKeywords {
+		if k != "" && strings.Contains(lower, strings.ToLower(k)) {
+			return fmt.Sprintf("contains blocked keyword %q", k)
+		}
+	}
+	if len(r.RequiredKeywords) > 0 && !containsAny(lower, r.RequiredKeywords) {
+		return "contains none of the required keywords"
+	}
+	for _, s := range r.BlockedSentiments {
+		if sentiment != "" && strings.EqualFold(s, sentiment) {
+			return fmt.Sprintf("sentiment is %s", sentiment)
+		}
+	}
+	return ""
+}
+
+func containsAny(lower string, keywords []string) bool {
+	for _, k := range keywords {
+		if k != "" && strings.Contains(lower, strings.ToLower(k)) {
+			return true
+		}
+	}
+	return false
+}
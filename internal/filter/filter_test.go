@@ -0,0 +1,58 @@
+package filter
+
+import "testing"
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   Rules
+		wantErr bool
+	}{
+		{"zero", Rules{}, false},
+		{"range", Rules{MinChars: 10, MaxChars: 280}, false},
+		{"min only", Rules{MinChars: 10}, false},
+		{"negative", Rules{MinChars: -1}, true},
+		{"inverted", Rules{MinChars: 300, MaxChars: 280}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.rules.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestReject(t *testing.T) {
+	rules := Rules{
+		MinChars:          5,
+		MaxChars:          40,
+		BlockedKeywords:   []string{"Giveaway"},
+		RequiredKeywords:  []string{"launch", "release"},
+		BlockedSentiments: []string{"negative"},
+	}
+	tests := []struct {
+		name      string
+		rules     Rules
+		text      string
+		sentiment string
+		want      string
+	}{
+		{"no rules", Rules{}, "", "negative", ""},
+		{"passes", rules, "Our launch is today", "positive", ""},
+		{"too short", rules, "hi", "", "shorter than 5 characters"},
+		{"too long", rules, "a release note that runs on well past forty characters", "", "longer than 40 characters"},
+		{"runes not bytes", Rules{MaxChars: 5}, "héllo", "", ""},
+		{"blocked keyword", rules, "launch GIVEAWAY now", "", `contains blocked keyword "Giveaway"`},
+		{"no required keyword", rules, "nothing to see here", "", "contains none of the required keywords"},
+		{"blocked sentiment", rules, "the release slipped", "Negative", "sentiment is Negative"},
+		{"no analysis", rules, "the release slipped", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rules.Reject(tt.text, tt.sentiment); got != tt.want {
+				t.Errorf("Reject(%q, %q) = %q, want %q", tt.text, tt.sentiment, got, tt.want)
+			}
+		})
+	}
+}
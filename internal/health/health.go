@@ -0,0 +1,235 @@
+// Package health probes the orchestrator's downstream dependencies and
+// reports readiness over the standard grpc.health.v1 service and over HTTP.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Optiq-CTO/orchestrator/internal/metrics"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// OrchestratorService is the health service name of the orchestrator API.
+// The empty name reports the server as a whole; each dependency is also
+// reported under its own name, e.g. "fetcher".
+const OrchestratorService = "orchestrator.OrchestratorService"
+
+// Probe checks one dependency, returning nil if it is usable.
+type Probe func(ctx context.Context) error
+
+// ConnProbe checks that a client connection is, or can become, ready. It
+// only looks at connection state, so it needs nothing from the downstream
+// beyond a reachable gRPC endpoint and does not show up in call metrics.
+func ConnProbe(conn *grpc.ClientConn) Probe {
+	return func(ctx context.Context) error {
+		conn.Connect()
+		for {
+			state := conn.GetState()
+			switch state {
+			case connectivity.Ready:
+				return nil
+			case connectivity.TransientFailure, connectivity.Shutdown:
+				return fmt.Errorf("connection is %s", state)
+			}
+			if !conn.WaitForStateChange(ctx, state) {
+				return fmt.Errorf("connection still %s: %w", state, ctx.Err())
+			}
+		}
+	}
+}
+
+// Dependency is a probed downstream. The server is only ready while every
+// required dependency is up; optional ones are reported but do not gate
+// readiness.
+type Dependency struct {
+	Name     string
+	Probe    Probe
+	Required bool
+}
+
+// DependencyStatus is the outcome of a dependency's last probe.
+type DependencyStatus struct {
+	Name      string    `json:"name"`
+	Up        bool      `json:"up"`
+	Required  bool      `json:"required"`
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// Checker probes dependencies periodically and publishes the results to a
+// grpc.health.v1 server.
+type Checker struct {
+	deps    []Dependency
+	timeout time.Duration
+	server  *grpchealth.Server
+	logger  *slog.Logger
+
+	mu           sync.Mutex
+	statuses     map[string]DependencyStatus
+	probed       bool
+	shuttingDown bool
+}
+
+// NewChecker returns a checker for deps. Each probe gets timeout to finish.
+// Until the first probe round completes the server reports NOT_SERVING.
+func NewChecker(deps []Dependency, timeout time.Duration, logger *slog.Logger) *Checker {
+	c := &Checker{
+		deps:     deps,
+		timeout:  timeout,
+		server:   grpchealth.NewServer(),
+		logger:   logger,
+		statuses: make(map[string]DependencyStatus),
+	}
+	c.server.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+	c.server.SetServingStatus(OrchestratorService, healthpb.HealthCheckResponse_NOT_SERVING)
+	return c
+}
+
+// Register adds the grpc.health.v1 service to s.
+func (c *Checker) Register(s *grpc.Server) {
+	healthpb.RegisterHealthServer(s, c.server)
+}
+
+// Run probes every interval until ctx is done.
+func (c *Checker) Run(ctx context.Context, interval time.Duration) {
+	c.CheckNow(ctx)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.CheckNow(ctx)
+		}
+	}
+}
+
+// CheckNow probes every dependency concurrently and updates the reported
+// status.
+func (c *Checker) CheckNow(ctx context.Context) {
+	results := make([]DependencyStatus, len(c.deps))
+	var wg sync.WaitGroup
+	for i, d := range c.deps {
+		wg.Add(1)
+		go func(i int, d Dependency) {
+			defer wg.Done()
+			pctx, cancel := context.WithTimeout(ctx, c.timeout)
+			defer cancel()
+			err := d.Probe(pctx)
+			st := DependencyStatus{Name: d.Name, Up: err == nil, Required: d.Required, CheckedAt: time.Now().UTC()}
+			if err != nil {
+				st.Error = err.Error()
+			}
+			results[i] = st
+		}(i, d)
+	}
+	wg.Wait()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, st := range results {
+		if prev, ok := c.statuses[st.Name]; (!ok || prev.Up) && !st.Up {
+			c.logger.Warn("dependency down", "dependency", st.Name, "error", st.Error)
+		} else if ok && !prev.Up && st.Up {
+			c.logger.Info("dependency recovered", "dependency", st.Name)
+		}
+		c.statuses[st.Name] = st
+		up := 0.0
+		if st.Up {
+			up = 1
+		}
+		metrics.DependencyUp.Set(up, st.Name)
+		if !c.shuttingDown {
+			c.server.SetServingStatus(st.Name, servingStatus(st.Up))
+		}
+	}
+	c.probed = true
+	if !c.shuttingDown {
+		ready := c.readyLocked()
+		c.server.SetServingStatus("", servingStatus(ready))
+		c.server.SetServingStatus(OrchestratorService, servingStatus(ready))
+	}
+}
+
+// Shutdown reports NOT_SERVING from now on, so load balancers stop sending
+// new work while the server drains.
+func (c *Checker) Shutdown() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.shuttingDown = true
+	c.server.Shutdown()
+}
+
+// Ready reports whether the server should receive traffic.
+func (c *Checker) Ready() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return !c.shuttingDown && c.readyLocked()
+}
+
+func (c *Checker) readyLocked() bool {
+	if !c.probed {
+		return false
+	}
+	for _, d := range c.deps {
+		if d.Required && !c.statuses[d.Name].Up {
+			return false
+		}
+	}
+	return true
+}
+
+// Statuses returns the last probe result of every dependency, by name.
+func (c *Checker) Statuses() []DependencyStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make([]DependencyStatus, 0, len(c.statuses))
+	for _, st := range c.statuses {
+		out = append(out, st)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+func servingStatus(up bool) healthpb.HealthCheckResponse_ServingStatus {
+	if up {
+		return healthpb.HealthCheckResponse_SERVING
+	}
+	return healthpb.HealthCheckResponse_NOT_SERVING
+}
+
+// LiveHandler serves /healthz: the process is up and serving HTTP.
+func (c *Checker) LiveHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprintln(w, "ok")
+	})
+}
+
+// ReadyHandler serves /readyz: 200 while ready, 503 otherwise, with the
+// per-dependency status as JSON.
+func (c *Checker) ReadyHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		ready := c.Ready()
+		body := struct {
+			Ready        bool               `json:"ready"`
+			Dependencies []DependencyStatus `json:"dependencies"`
+		}{ready, c.Statuses()}
+		w.Header().Set("Content-Type", "application/json")
+		if !ready {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		_ = json.NewEncoder(w).Encode(body)
+	})
+}
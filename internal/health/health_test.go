@@ -0,0 +1,118 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func up(context.Context) error   { return nil }
+func down(context.Context) error { return errors.New("unreachable") }
+
+func TestCheckerReady(t *testing.T) {
+	tests := []struct {
+		name string
+		deps []Dependency
+		want bool
+	}{
+		{"no deps", nil, true},
+		{"all up", []Dependency{{"fetcher", up, true}, {"ai", up, true}}, true},
+		{"required down", []Dependency{{"fetcher", up, true}, {"ai", down, true}}, false},
+		{"optional down", []Dependency{{"fetcher", up, true}, {"analyzer", down, false}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewChecker(tt.deps, time.Second, slog.New(slog.NewTextHandler(io.Discard, nil)))
+			if c.Ready() {
+				t.Fatal("ready before the first probe round")
+			}
+			c.CheckNow(context.Background())
+			if got := c.Ready(); got != tt.want {
+				t.Errorf("Ready() = %v, want %v", got, tt.want)
+			}
+			resp, err := c.server.Check(context.Background(), &healthpb.HealthCheckRequest{Service: OrchestratorService})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := resp.Status == healthpb.HealthCheckResponse_SERVING; got != tt.want {
+				t.Errorf("serving status = %v, want serving %v", resp.Status, tt.want)
+			}
+			for _, d := range tt.deps {
+				resp, err := c.server.Check(context.Background(), &healthpb.HealthCheckRequest{Service: d.Name})
+				if err != nil {
+					t.Fatal(err)
+				}
+				wantUp := d.Probe(context.Background()) == nil
+				if got := resp.Status == healthpb.HealthCheckResponse_SERVING; got != wantUp {
+					t.Errorf("%s status = %v, want serving %v", d.Name, resp.Status, wantUp)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckerShutdown(t *testing.T) {
+	c := NewChecker([]Dependency{{"fetcher", up, true}}, time.Second, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	c.CheckNow(context.Background())
+	c.Shutdown()
+	if c.Ready() {
+		t.Error("ready after Shutdown")
+	}
+	c.CheckNow(context.Background())
+	if c.Ready() {
+		t.Error("a probe round after Shutdown made the checker ready again")
+	}
+}
+
+func TestHandlers(t *testing.T) {
+	tests := []struct {
+		name       string
+		probe      Probe
+		wantStatus int
+	}{
+		{"ready", up, http.StatusOK},
+		{"not ready", down, http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewChecker([]Dependency{{"fetcher", tt.probe, true}}, time.Second, slog.New(slog.NewTextHandler(io.Discard, nil)))
+			c.CheckNow(context.Background())
+
+			rec := httptest.NewRecorder()
+			c.LiveHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+			if rec.Code != http.StatusOK {
+				t.Errorf("/healthz = %d, want 200", rec.Code)
+			}
+
+			rec = httptest.NewRecorder()
+			c.ReadyHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			if rec.Code != tt.wantStatus {
+				t.Errorf("/readyz = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			var body struct {
+				Ready        bool               `json:"ready"`
+				Dependencies []DependencyStatus `json:"dependencies"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if len(body.Dependencies) != 1 || body.Dependencies[0].Name != "fetcher" {
+				t.Fatalf("dependencies = %+v", body.Dependencies)
+			}
+			if body.Dependencies[0].Up != (tt.wantStatus == http.StatusOK) {
+				t.Errorf("fetcher up = %v", body.Dependencies[0].Up)
+			}
+			if !body.Dependencies[0].Up && body.Dependencies[0].Error == "" {
+				t.Error("down dependency has no error")
+			}
+		})
+	}
+}
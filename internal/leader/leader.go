@@ -0,0 +1,133 @@
+// Package leader elects one replica to run the singleton duties, such as
+// token refresh and outbox delivery, over a lease store shared between the
+// replicas. The others follow: they keep serving requests and take over when
+// the leader's lease expires.
+package leader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Optiq-CTO/orchestrator/internal/lease"
+	"github.com/Optiq-CTO/orchestrator/internal/metrics"
+)
+
+// key is the lease the replicas compete for.
+const key = "leader"
+
+// DefaultID identifies this replica by host name and process ID.
+func DefaultID() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
+
+type duty struct {
+	name string
+	run  func(context.Context)
+}
+
+// Elector campaigns for leadership and runs the duties while it leads.
+type Elector struct {
+	store  lease.Store
+	id     string
+	ttl    time.Duration
+	logger *slog.Logger
+
+	mu     sync.Mutex
+	duties []duty
+	leader bool
+}
+
+// New returns an elector for replica id. The leader renews its lease every
+// third of ttl; if it stops, another replica takes over once ttl has passed.
+func New(store lease.Store, id string, ttl time.Duration, logger *slog.Logger) *Elector {
+	return &Elector{store: store, id: id, ttl: ttl, logger: logger.With("replica", id)}
+}
+
+// Duty registers fn to run while this replica leads. fn must return once its
+// context is done. Duties are registered before Run.
+func (e *Elector) Duty(name string, fn func(ctx context.Context)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.duties = append(e.duties, duty{name: name, run: fn})
+}
+
+// IsLeader reports whether this replica leads.
+func (e *Elector) IsLeader() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.leader
+}
+
+// Run campaigns until ctx is done, trying every third of the ttl. While
+// leading it runs the duties; if the lease is lost it stops them and
+// campaigns again. When ctx is done a leader releases its lease, so another
+// replica need not wait for it to expire.
+func (e *Elector) Run(ctx context.Context) {
+	metrics.Leader.Set(0)
+	var following string
+	for {
+		lost := make(chan struct{})
+		held, cur, err := lease.Hold(ctx, e.store, key, e.id, e.ttl, func(error) { close(lost) })
+		switch {
+		case err == nil:
+			following = ""
+			e.lead(ctx, held, lost)
+		case errors.Is(err, lease.ErrHeld):
+			if cur.Holder != following {
+				following = cur.Holder
+				e.logger.Info("following the leader", "leader", cur.Holder)
+			}
+		default:
+			e.logger.Warn("leader election failed; retrying", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(e.ttl / 3):
+		}
+	}
+}
+
+// lead runs the duties until ctx is done or the lease is lost.
+func (e *Elector) lead(ctx context.Context, held *lease.Held, lost <-chan struct{}) {
+	e.mu.Lock()
+	e.leader = true
+	duties := append([]duty{}, e.duties...)
+	e.mu.Unlock()
+	metrics.Leader.Set(1)
+	e.logger.Info("became leader; starting singleton duties", "duties", len(duties))
+
+	dctx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	for _, d := range duties {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.run(dctx)
+		}()
+	}
+	select {
+	case <-ctx.Done():
+	case <-lost:
+		e.logger.Warn("lost leadership; stopping singleton duties")
+	}
+	cancel()
+	wg.Wait()
+
+	e.mu.Lock()
+	e.leader = false
+	e.mu.Unlock()
+	metrics.Leader.Set(0)
+	if err := held.Release(context.WithoutCancel(ctx)); err != nil && !errors.Is(err, lease.ErrNotHeld) {
+		e.logger.Warn("failed to release leadership", "error", err)
+	}
+}
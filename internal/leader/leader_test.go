@@ -0,0 +1,101 @@
+package leader
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Optiq-CTO/orchestrator/internal/lease"
+)
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		if cond() {
+			return
+		}
+	}
+	t.Fatalf("timed out waiting until %s", what)
+}
+
+func TestElector(t *testing.T) {
+	store := lease.NewMemoryStore()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	const ttl = 60 * time.Millisecond
+
+	var running atomic.Int32
+	duty := func(ctx context.Context) {
+		running.Add(1)
+		defer running.Add(-1)
+		<-ctx.Done()
+	}
+	a := New(store, "replica-a", ttl, logger)
+	a.Duty("refresh", duty)
+	b := New(store, "replica-b", ttl, logger)
+	b.Duty("refresh", duty)
+
+	ctxA, stopA := context.WithCancel(context.Background())
+	doneA := make(chan struct{})
+	go func() { a.Run(ctxA); close(doneA) }()
+	waitFor(t, "replica-a leads", a.IsLeader)
+
+	ctxB, stopB := context.WithCancel(context.Background())
+	defer stopB()
+	doneB := make(chan struct{})
+	go func() { b.Run(ctxB); close(doneB) }()
+	time.Sleep(2 * ttl)
+	if b.IsLeader() {
+		t.Fatal("two replicas lead at once")
+	}
+	if n := running.Load(); n != 1 {
+		t.Fatalf("%d duties running, want 1", n)
+	}
+
+	// Stopping the leader releases the lease; the follower takes over.
+	stopA()
+	<-doneA
+	if a.IsLeader() {
+		t.Error("stopped replica still leads")
+	}
+	waitFor(t, "replica-b leads", b.IsLeader)
+	waitFor(t, "one duty runs", func() bool { return running.Load() == 1 })
+
+	stopB()
+	<-doneB
+	if n := running.Load(); n != 0 {
+		t.Errorf("%d duties still running after shutdown", n)
+	}
+}
+
+func TestElectorLosesLease(t *testing.T) {
+	store := lease.NewMemoryStore()
+	const ttl = 60 * time.Millisecond
+	e := New(store, "replica-a", ttl, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	stopped := make(chan struct{}, 1)
+	e.Duty("outbox", func(ctx context.Context) {
+		<-ctx.Done()
+		stopped <- struct{}{}
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go e.Run(ctx)
+	waitFor(t, "the replica leads", e.IsLeader)
+
+	// Another replica takes the lease, as if this one had stalled past its
+	// expiry.
+	if err := store.Release(ctx, key, "replica-a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Acquire(ctx, key, "replica-b", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("duties kept running after the lease was lost")
+	}
+	waitFor(t, "the replica follows", func() bool { return !e.IsLeader() })
+}
@@ -0,0 +1,120 @@
+package lease
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Optiq-CTO/orchestrator/internal/statefile"
+)
+
+// FileStore keeps leases in a JSON file, locked with an OS file lock around
+// every change, so the replicas on one host share them.
+type FileStore struct {
+	path string
+	now  func() time.Time
+}
+
+// OpenFileStore returns a store over the file at path, creating it on the
+// first lease. The lock is taken on path + ".lock".
+func OpenFileStore(path string) (*FileStore, error) {
+	f := &FileStore{path: path, now: time.Now}
+	// Fail at startup, not on the first run, if the file can't be used.
+	if err := f.update(func(map[string]Lease) bool { return false }); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Acquire implements Store.
+func (f *FileStore) Acquire(_ context.Context, key, holder string, ttl time.Duration) (Lease, error) {
+	var l Lease
+	var held bool
+	err := f.update(func(leases map[string]Lease) bool {
+		now := f.now()
+		if cur, ok := leases[key]; ok && cur.Holder != holder && now.Before(cur.ExpiresAt) {
+			l, held = cur, true
+			return false
+		}
+		l = Lease{Key: key, Holder: holder, AcquiredAt: now, ExpiresAt: now.Add(ttl)}
+		leases[key] = l
+		return true
+	})
+	if err == nil && held {
+		err = ErrHeld
+	}
+	return l, err
+}
+
+// Renew implements Store.
+func (f *FileStore) Renew(_ context.Context, key, holder string, ttl time.Duration) (Lease, error) {
+	var l Lease
+	err := ErrNotHeld
+	if uerr := f.update(func(leases map[string]Lease) bool {
+		now := f.now()
+		cur, ok := leases[key]
+		if !ok || cur.Holder != holder || !now.Before(cur.ExpiresAt) {
+			return false
+		}
+		cur.ExpiresAt = now.Add(ttl)
+		leases[key] = cur
+		l, err = cur, nil
+		return true
+	}); uerr != nil {
+		return Lease{}, uerr
+	}
+	return l, err
+}
+
+// Release implements Store.
+func (f *FileStore) Release(_ context.Context, key, holder string) error {
+	err := ErrNotHeld
+	if uerr := f.update(func(leases map[string]Lease) bool {
+		if cur, ok := leases[key]; !ok || cur.Holder != holder {
+			return false
+		}
+		delete(leases, key)
+		err = nil
+		return true
+	}); uerr != nil {
+		return uerr
+	}
+	return err
+}
+
+// Get implements Store.
+func (f *FileStore) Get(_ context.Context, key string) (Lease, bool, error) {
+	var l Lease
+	var ok bool
+	err := f.update(func(leases map[string]Lease) bool {
+		l, ok = leases[key]
+		ok = ok && f.now().Before(l.ExpiresAt)
+		return false
+	})
+	if err != nil || !ok {
+		return Lease{}, false, err
+	}
+	return l, true, nil
+}
+
+// update runs fn on the leases under the file lock and writes them back if
+// fn reports a change. Expired leases are dropped on write.
+func (f *FileStore) update(fn func(map[string]Lease) bool) error {
+	leases := make(map[string]Lease)
+	err := statefile.Update(f.path, &leases, func() bool {
+		if !fn(leases) {
+			return false
+		}
+		now := f.now()
+		for k, l := range leases {
+			if !now.Before(l.ExpiresAt) {
+				delete(leases, k)
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("lease file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+// Package lease provides expiring leases, kept alive by heartbeats, so only
+// one run at a time works on an account and flow, and one replica leads.
+// Leases live in a Store: the in-memory one serves a single server, and the
+// file one is shared by the replicas on a host.
+package lease
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrHeld is returned when another holder has the lease.
+	ErrHeld = errors.New("lease is held by another holder")
+	// ErrNotHeld is returned when a holder renews or releases a lease it
+	// no longer has, e.g. because it expired and was taken.
+	ErrNotHeld = errors.New("lease is not held by this holder")
+)
+
+// What a run does when another run holds its lease.
+const (
+	OnConflictReject   = "reject"   // fail at once
+	OnConflictWait     = "wait"     // wait for the lease, then run
+	OnConflictCoalesce = "coalesce" // wait, then return the other run's result
+)
+
+// ValidOnConflict reports whether policy is a conflict policy.
+func ValidOnConflict(policy string) bool {
+	switch policy {
+	case OnConflictReject, OnConflictWait, OnConflictCoalesce:
+		return true
+	}
+	return false
+}
+
+// Lease is a claim on a key by a holder until it expires.
+type Lease struct {
+	Key        string    `json:"key"`
+	Holder     string    `json:"holder"`
+	AcquiredAt time.Time `json:"acquired_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// Store keeps leases. Acquire, Renew and Release must be atomic, so that a
+// store shared between replicas serializes them.
+type Store interface {
+	// Acquire takes the lease on key for holder if it is free or expired.
+	// If another holder has it, it returns that lease and ErrHeld.
+	Acquire(ctx context.Context, key, holder string, ttl time.Duration) (Lease, error)
+	// Renew extends holder's lease on key to ttl from now.
+	Renew(ctx context.Context, key, holder string, ttl time.Duration) (Lease, error)
+	// Release frees holder's lease on key.
+	Release(ctx context.Context, key, holder string) error
+	// Get returns the unexpired lease on key, if any.
+	Get(ctx context.Context, key string) (Lease, bool, error)
+}
+
+// MemoryStore keeps leases in memory.
+type MemoryStore struct {
+	mu     sync.Mutex
+	leases map[string]Lease
+	now    func() time.Time
+}
+
+// NewMemoryStore returns an empty in-memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{leases: make(map[string]Lease), now: time.Now}
+}
+
+// Acquire implements Store.
+func (m *MemoryStore) Acquire(_ context.Context, key, holder string, ttl time.Duration) (Lease, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	if l, ok := m.leases[key]; ok && l.Holder != holder && now.Before(l.ExpiresAt) {
+		return l, ErrHeld
+	}
+	l := Lease{Key: key, Holder: holder, AcquiredAt: now, ExpiresAt: now.Add(ttl)}
+	m.leases[key] = l
+	return l, nil
+}
+
+// Renew implements Store.
+func (m *MemoryStore) Renew(_ context.Context, key, holder string, ttl time.Duration) (Lease, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	l, ok := m.leases[key]
+	if !ok || l.Holder != holder || !now.Before(l.ExpiresAt) {
+		return Lease{}, ErrNotHeld
+	}
+	l.ExpiresAt = now.Add(ttl)
+	m.leases[key] = l
+	return l, nil
+}
+
+// Release implements Store.
+func (m *MemoryStore) Release(_ context.Context, key, holder string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	l, ok := m.leases[key]
+	if !ok || l.Holder != holder {
+		return ErrNotHeld
+	}
+	delete(m.leases, key)
+	return nil
+}
+
+// Get implements Store.
+func (m *MemoryStore) Get(_ context.Context, key string) (Lease, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	l, ok := m.leases[key]
+	if !ok || !m.now().Before(l.ExpiresAt) {
+		return Lease{}, false, nil
+	}
+	return l, true, nil
+}
+
+// Held is a lease kept alive by heartbeats until released.
+type Held struct {
+	store Store
+	lease Lease
+
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+// Hold acquires the lease on key for holder and renews it every third of
+// ttl until Release. If a renewal finds the lease lost, or renewals keep
+// failing until the lease would expire before the next beat, onLost is
+// called and the heartbeats stop. Hold returns ErrHeld, with the current
+// lease, if another holder has it.
+func Hold(ctx context.Context, store Store, key, holder string, ttl time.Duration, onLost func(error)) (*Held, Lease, error) {
+	l, err := store.Acquire(ctx, key, holder, ttl)
+	if err != nil {
+		return nil, l, err
+	}
+	h := &Held{store: store, lease: l, stop: make(chan struct{}), done: make(chan struct{})}
+	go h.heartbeat(context.WithoutCancel(ctx), ttl, onLost)
+	return h, l, nil
+}
+
+func (h *Held) heartbeat(ctx context.Context, ttl time.Duration, onLost func(error)) {
+	defer close(h.done)
+	expires := h.lease.ExpiresAt
+	ticker := time.NewTicker(ttl / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-h.stop:
+			return
+		case <-ticker.C:
+		}
+		l, err := h.store.Renew(ctx, h.lease.Key, h.lease.Holder, ttl)
+		switch {
+		case err == nil:
+			expires = l.ExpiresAt
+		case errors.Is(err, ErrNotHeld):
+			onLost(err)
+			return
+		case !time.Now().Add(ttl / 3).Before(expires):
+			// The store stayed unreachable and the lease runs out before
+			// the next beat, when another holder may take it; give it up
+			// while it is still ours.
+			onLost(fmt.Errorf("lease expiring without a renewal: %w", err))
+			return
+		}
+		// Other errors, such as a shared store being briefly unreachable,
+		// are retried on the next beat while the lease lasts.
+	}
+}
+
+// Release stops the heartbeats and frees the lease. It is safe to call more
+// than once.
+func (h *Held) Release(ctx context.Context) error {
+	var err error
+	h.once.Do(func() {
+		close(h.stop)
+		<-h.done
+		err = h.store.Release(ctx, h.lease.Key, h.lease.Holder)
+	})
+	return err
+}
@@ -0,0 +1,202 @@
+package lease
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+type clockStore interface {
+	Store
+	setNow(func() time.Time)
+}
+
+func (m *MemoryStore) setNow(now func() time.Time) { m.now = now }
+func (f *FileStore) setNow(now func() time.Time)   { f.now = now }
+
+func stores(t *testing.T) map[string]clockStore {
+	f, err := OpenFileStore(filepath.Join(t.TempDir(), "leases.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return map[string]clockStore{"memory": NewMemoryStore(), "file": f}
+}
+
+func TestStore(t *testing.T) {
+	type op struct {
+		advance time.Duration
+		do      string // acquire, renew, release or get
+		holder  string
+		wantErr error
+		wantOK  bool // for get: a lease is held
+	}
+	tests := []struct {
+		name string
+		ops  []op
+	}{
+		{"acquire free", []op{{do: "acquire", holder: "a"}, {do: "get", wantOK: true}}},
+		{"held by another", []op{{do: "acquire", holder: "a"}, {do: "acquire", holder: "b", wantErr: ErrHeld}}},
+		{"reacquire own", []op{{do: "acquire", holder: "a"}, {do: "acquire", holder: "a"}}},
+		{"take expired", []op{{do: "acquire", holder: "a"}, {advance: time.Minute, do: "get"}, {do: "acquire", holder: "b"}}},
+		{"renew extends", []op{{do: "acquire", holder: "a"}, {advance: 40 * time.Second, do: "renew", holder: "a"}, {advance: 40 * time.Second, do: "acquire", holder: "b", wantErr: ErrHeld}}},
+		{"renew another's", []op{{do: "acquire", holder: "a"}, {do: "renew", holder: "b", wantErr: ErrNotHeld}}},
+		{"renew expired", []op{{do: "acquire", holder: "a"}, {advance: time.Minute, do: "renew", holder: "a", wantErr: ErrNotHeld}}},
+		{"release", []op{{do: "acquire", holder: "a"}, {do: "release", holder: "a"}, {do: "get"}, {do: "acquire", holder: "b"}}},
+		{"release another's", []op{{do: "acquire", holder: "a"}, {do: "release", holder: "b", wantErr: ErrNotHeld}, {do: "get", wantOK: true}}},
+	}
+	for _, tt := range tests {
+		for name, s := range stores(t) {
+			t.Run(tt.name+"/"+name, func(t *testing.T) {
+				ctx := context.Background()
+				now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+				s.setNow(func() time.Time { return now })
+				for i, o := range tt.ops {
+					now = now.Add(o.advance)
+					var err error
+					switch o.do {
+					case "acquire":
+						var l Lease
+						l, err = s.Acquire(ctx, "flow/acct", o.holder, time.Minute)
+						if errors.Is(err, ErrHeld) && l.Holder == o.holder {
+							t.Errorf("op %d: ErrHeld names the caller as holder", i)
+						}
+					case "renew":
+						_, err = s.Renew(ctx, "flow/acct", o.holder, time.Minute)
+					case "release":
+						err = s.Release(ctx, "flow/acct", o.holder)
+					case "get":
+						var ok bool
+						_, ok, err = s.Get(ctx, "flow/acct")
+						if ok != o.wantOK {
+							t.Errorf("op %d: Get() held = %v, want %v", i, ok, o.wantOK)
+						}
+					}
+					if !errors.Is(err, o.wantErr) {
+						t.Fatalf("op %d (%s %s): err = %v, want %v", i, o.do, o.holder, err, o.wantErr)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestFileStoreShared(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "leases.json")
+	a, err := OpenFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := OpenFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if _, err := a.Acquire(ctx, "leader", "replica-a", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if l, err := b.Acquire(ctx, "leader", "replica-b", time.Minute); !errors.Is(err, ErrHeld) || l.Holder != "replica-a" {
+		t.Fatalf("second replica got %+v, %v; want ErrHeld by replica-a", l, err)
+	}
+
+	var wg sync.WaitGroup
+	wins := make(chan string, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(s *FileStore, holder string) {
+			defer wg.Done()
+			if _, err := s.Acquire(ctx, "race", holder, time.Minute); err == nil {
+				wins <- holder
+			}
+		}([]*FileStore{a, b}[i%2], string(rune('a'+i)))
+	}
+	wg.Wait()
+	close(wins)
+	if n := len(wins); n != 1 {
+		t.Errorf("%d holders acquired the same lease", n)
+	}
+}
+
+func TestHold(t *testing.T) {
+	s := NewMemoryStore()
+	ctx := context.Background()
+	h, _, err := Hold(ctx, s, "k", "a", 30*time.Millisecond, func(err error) { t.Errorf("lease lost: %v", err) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if _, err := s.Acquire(ctx, "k", "b", time.Minute); !errors.Is(err, ErrHeld) {
+		t.Fatalf("lease not kept alive by heartbeats: %v", err)
+	}
+	if _, _, err := Hold(ctx, s, "k", "b", time.Minute, nil); !errors.Is(err, ErrHeld) {
+		t.Errorf("Hold() of a held lease = %v, want ErrHeld", err)
+	}
+	if err := h.Release(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Release(ctx); err != nil {
+		t.Errorf("second Release() = %v", err)
+	}
+	if _, ok, _ := s.Get(ctx, "k"); ok {
+		t.Error("lease still held after Release")
+	}
+}
+
+func TestHoldLost(t *testing.T) {
+	s := NewMemoryStore()
+	ctx := context.Background()
+	lost := make(chan error, 1)
+	h, _, err := Hold(ctx, s, "k", "a", 30*time.Millisecond, func(err error) { lost <- err })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Release(ctx)
+	// Another holder takes the lease over, as if it had expired.
+	s.mu.Lock()
+	s.leases["k"] = Lease{Key: "k", Holder: "b", ExpiresAt: time.Now().Add(time.Minute)}
+	s.mu.Unlock()
+	select {
+	case err := <-lost:
+		if !errors.Is(err, ErrNotHeld) {
+			t.Errorf("onLost(%v), want ErrNotHeld", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("losing the lease went unnoticed")
+	}
+}
+
+// failingStore fails every renewal, like a shared store that is down.
+type failingStore struct{ *MemoryStore }
+
+func (failingStore) Renew(context.Context, string, string, time.Duration) (Lease, error) {
+	return Lease{}, errors.New("store unreachable")
+}
+
+func TestHoldStepsDown(t *testing.T) {
+	s := failingStore{NewMemoryStore()}
+	ctx := context.Background()
+	lost := make(chan error, 1)
+	h, _, err := Hold(ctx, s, "k", "a", 60*time.Millisecond, func(err error) { lost <- err })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Release(ctx)
+	select {
+	case err := <-lost:
+		if errors.Is(err, ErrNotHeld) {
+			t.Errorf("onLost(%v), want the renewal error", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("kept a lease whose renewals all failed")
+	}
+}
+
+func TestValidOnConflict(t *testing.T) {
+	for policy, want := range map[string]bool{OnConflictReject: true, OnConflictWait: true, OnConflictCoalesce: true, "": false, "queue": false} {
+		if got := ValidOnConflict(policy); got != want {
+			t.Errorf("ValidOnConflict(%q) = %v, want %v", policy, got, want)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+// Package logging builds the orchestrator's structured logger and carries
+// per-run loggers through request contexts.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/Optiq-CTO/orchestrator/internal/redact"
+)
+
+// New returns a logger writing to w at the given level ("debug", "info",
+// "warn", "error") in the given format ("text" or "json"). All output is
+// passed through the redactor.
+func New(w io.Writer, level, format string) (*slog.Logger, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(strings.ToLower(orDefault(level, "info")))); err != nil {
+		return nil, fmt.Errorf("invalid log level %q", level)
+	}
+
+	opts := &slog.HandlerOptions{Level: lvl}
+	var h slog.Handler
+	switch strings.ToLower(orDefault(format, "text")) {
+	case "text":
+		h = slog.NewTextHandler(w, opts)
+	case "json":
+		h = slog.NewJSONHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("invalid log format %q (want text or json)", format)
+	}
+	return slog.New(redact.NewHandler(h)), nil
+}
+
+// FromEnv builds a stderr logger configured by LOG_LEVEL and LOG_FORMAT.
+func FromEnv() (*slog.Logger, error) {
+	return New(os.Stderr, os.Getenv("LOG_LEVEL"), os.Getenv("LOG_FORMAT"))
+}
+
+type ctxKey struct{}
+
+// WithLogger returns a context carrying logger.
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, logger)
+}
+
+// FromContext returns the logger carried by ctx, or slog.Default().
+func FromContext(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(ctxKey{}).(*slog.Logger); ok {
+		return l
+	}
+	return slog.Default()
+}
+
+func orDefault(v, def string) string {
+	if v == "" {
+		return def
+	}
+	return v
+}
@@ -0,0 +1,60 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		level, format string
+		wantErr       bool
+	}{
+		{"", "", false},
+		{"debug", "json", false},
+		{"WARN", "Text", false},
+		{"verbose", "text", true},
+		{"info", "xml", true},
+	}
+	for _, tt := range tests {
+		_, err := New(&bytes.Buffer{}, tt.level, tt.format)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("New(%q, %q) error = %v, want error %v", tt.level, tt.format, err, tt.wantErr)
+		}
+	}
+}
+
+func TestNewRedactsAndFilters(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, "info", "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger.Debug("hidden")
+	logger.Info("shown", "access_token", "abc123")
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("got %d lines, want 1: %s", len(lines), buf.String())
+	}
+	var rec map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &rec); err != nil {
+		t.Fatal(err)
+	}
+	if rec["msg"] != "shown" || rec["access_token"] != "[REDACTED]" {
+		t.Errorf("record = %v", rec)
+	}
+}
+
+func TestContext(t *testing.T) {
+	if FromContext(context.Background()) != slog.Default() {
+		t.Error("FromContext without a logger is not slog.Default()")
+	}
+	l := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+	if FromContext(WithLogger(context.Background(), l)) != l {
+		t.Error("FromContext did not return the carried logger")
+	}
+}
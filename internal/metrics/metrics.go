@@ -0,0 +1,303 @@
+// Package metrics implements a small Prometheus-compatible metrics registry
+// and the text exposition format served on /metrics.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefBuckets are the default histogram buckets, in seconds. They are tuned
+// for pipeline steps, which range from a fast fetch to a slow AI generation.
+var DefBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 20, 30, 60, 120}
+
+type collector interface {
+	write(w io.Writer)
+	metricName() string
+}
+
+// Registry holds a set of metric families.
+type Registry struct {
+	mu         sync.Mutex
+	collectors []collector
+}
+
+// NewRegistry returns an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Default is the registry the orchestrator metrics are registered in.
+var Default = NewRegistry()
+
+func (r *Registry) register(c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, existing := range r.collectors {
+		if existing.metricName() == c.metricName() {
+			panic("metrics: duplicate metric " + c.metricName())
+		}
+	}
+	r.collectors = append(r.collectors, c)
+}
+
+// WriteText writes every registered metric in the Prometheus text format.
+func (r *Registry) WriteText(w io.Writer) {
+	r.mu.Lock()
+	cs := make([]collector, len(r.collectors))
+	copy(cs, r.collectors)
+	r.mu.Unlock()
+
+	sort.Slice(cs, func(i, j int) bool { return cs[i].metricName() < cs[j].metricName() })
+	for _, c := range cs {
+		c.write(w)
+	}
+}
+
+// Handler serves the registry in the Prometheus text format.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		r.WriteText(w)
+	})
+}
+
+// family holds the label-keyed series shared by all metric kinds.
+type family struct {
+	name   string
+	help   string
+	kind   string
+	labels []string
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+type series struct {
+	labelValues []string
+	value       float64   // counters and gauges
+	buckets     []float64 // histogram bucket counts, not cumulative
+	sum         float64
+	count       uint64
+}
+
+func newFamily(name, help, kind string, labels []string) *family {
+	return &family{
+		name:   name,
+		help:   help,
+		kind:   kind,
+		labels: labels,
+		series: make(map[string]*series),
+	}
+}
+
+func (f *family) metricName() string { return f.name }
+
+// get returns the series for the label values, creating it if needed.
+// Callers must hold f.mu.
+func (f *family) get(values []string, nBuckets int) *series {
+	if len(values) != len(f.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", f.name, len(f.labels), len(values)))
+	}
+	key := strings.Join(values, "\xff")
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{labelValues: append([]string(nil), values...)}
+		if nBuckets > 0 {
+			s.buckets = make([]float64, nBuckets)
+		}
+		f.series[key] = s
+	}
+	return s
+}
+
+func (f *family) sortedSeries() []*series {
+	out := make([]*series, 0, len(f.series))
+	for _, s := range f.series {
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return strings.Join(out[i].labelValues, "\xff") < strings.Join(out[j].labelValues, "\xff")
+	})
+	return out
+}
+
+func (f *family) writeHeader(w io.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n", f.name, escapeHelp(f.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", f.name, f.kind)
+}
+
+// CounterVec is a monotonically increasing value partitioned by labels.
+type CounterVec struct{ f *family }
+
+// NewCounterVec creates and registers a counter in the default registry.
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{f: newFamily(name, help, "counter", labels)}
+	Default.register(c)
+	return c
+}
+
+// Inc adds one to the series identified by the label values.
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds v, which must not be negative, to the series.
+func (c *CounterVec) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		panic("metrics: counter cannot decrease")
+	}
+	c.f.mu.Lock()
+	c.f.get(labelValues, 0).value += v
+	c.f.mu.Unlock()
+}
+
+func (c *CounterVec) metricName() string { return c.f.name }
+
+func (c *CounterVec) write(w io.Writer) {
+	writeScalar(w, c.f)
+}
+
+// GaugeVec is a value that can go up and down, partitioned by labels.
+type GaugeVec struct{ f *family }
+
+// NewGaugeVec creates and registers a gauge in the default registry.
+func NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	g := &GaugeVec{f: newFamily(name, help, "gauge", labels)}
+	Default.register(g)
+	return g
+}
+
+// Inc adds one to the series.
+func (g *GaugeVec) Inc(labelValues ...string) { g.Add(1, labelValues...) }
+
+// Dec subtracts one from the series.
+func (g *GaugeVec) Dec(labelValues ...string) { g.Add(-1, labelValues...) }
+
+// Add adds v to the series.
+func (g *GaugeVec) Add(v float64, labelValues ...string) {
+	g.f.mu.Lock()
+	g.f.get(labelValues, 0).value += v
+	g.f.mu.Unlock()
+}
+
+// Set sets the series to v.
+func (g *GaugeVec) Set(v float64, labelValues ...string) {
+	g.f.mu.Lock()
+	g.f.get(labelValues, 0).value = v
+	g.f.mu.Unlock()
+}
+
+func (g *GaugeVec) metricName() string { return g.f.name }
+
+func (g *GaugeVec) write(w io.Writer) {
+	writeScalar(w, g.f)
+}
+
+func writeScalar(w io.Writer, f *family) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.writeHeader(w)
+	for _, s := range f.sortedSeries() {
+		fmt.Fprintf(w, "%s%s %s\n", f.name, formatLabels(f.labels, s.labelValues, "", ""), formatFloat(s.value))
+	}
+}
+
+// HistogramVec samples observations into buckets, partitioned by labels.
+type HistogramVec struct {
+	f       *family
+	buckets []float64
+}
+
+// NewHistogramVec creates and registers a histogram in the default registry.
+// buckets must be sorted in increasing order; nil selects DefBuckets.
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if buckets == nil {
+		buckets = DefBuckets
+	}
+	h := &HistogramVec{f: newFamily(name, help, "histogram", labels), buckets: buckets}
+	Default.register(h)
+	return h
+}
+
+// Observe records v in the series identified by the label values.
+func (h *HistogramVec) Observe(v float64, labelValues ...string) {
+	h.f.mu.Lock()
+	defer h.f.mu.Unlock()
+	s := h.f.get(labelValues, len(h.buckets))
+	for i, upper := range h.buckets {
+		if v <= upper {
+			s.buckets[i]++
+			break
+		}
+	}
+	s.sum += v
+	s.count++
+}
+
+func (h *HistogramVec) metricName() string { return h.f.name }
+
+func (h *HistogramVec) write(w io.Writer) {
+	h.f.mu.Lock()
+	defer h.f.mu.Unlock()
+	h.f.writeHeader(w)
+	for _, s := range h.f.sortedSeries() {
+		var cumulative float64
+		for i, upper := range h.buckets {
+			cumulative += s.buckets[i]
+			fmt.Fprintf(w, "%s_bucket%s %s\n", h.f.name, formatLabels(h.f.labels, s.labelValues, "le", formatFloat(upper)), formatFloat(cumulative))
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.f.name, formatLabels(h.f.labels, s.labelValues, "le", "+Inf"), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.f.name, formatLabels(h.f.labels, s.labelValues, "", ""), formatFloat(s.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.f.name, formatLabels(h.f.labels, s.labelValues, "", ""), s.count)
+	}
+}
+
+func formatLabels(names, values []string, extraName, extraValue string) string {
+	if len(names) == 0 && extraName == "" {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, "%s=\"%s\"", name, escapeLabel(values[i]))
+	}
+	if extraName != "" {
+		if len(names) > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, "%s=\"%s\"", extraName, extraValue)
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escapeLabel(s string) string { return labelEscaper.Replace(s) }
+
+func escapeHelp(s string) string { return helpEscaper.Replace(s) }
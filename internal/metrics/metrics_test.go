@@ -0,0 +1,74 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWriteText(t *testing.T) {
+	r := NewRegistry()
+	c := &CounterVec{f: newFamily("test_runs_total", "Runs.\nBy flow.", "counter", []string{"flow"})}
+	g := &GaugeVec{f: newFamily("test_in_flight", "In flight.", "gauge", nil)}
+	h := &HistogramVec{f: newFamily("test_seconds", "Durations.", "histogram", []string{"step"}), buckets: []float64{1, 5}}
+	r.register(c)
+	r.register(g)
+	r.register(h)
+
+	c.Inc("b")
+	c.Add(2, "a")
+	c.Inc(`q"x`)
+	g.Inc()
+	g.Inc()
+	g.Dec()
+	h.Observe(0.5, "fetch")
+	h.Observe(3, "fetch")
+	h.Observe(10, "fetch")
+
+	var b strings.Builder
+	r.WriteText(&b)
+	got := b.String()
+	for _, want := range []string{
+		"# HELP test_runs_total Runs.\\nBy flow.\n# TYPE test_runs_total counter\n",
+		"test_runs_total{flow=\"a\"} 2\ntest_runs_total{flow=\"b\"} 1\ntest_runs_total{flow=\"q\\\"x\"} 1\n",
+		"# TYPE test_in_flight gauge\ntest_in_flight 1\n",
+		"test_seconds_bucket{step=\"fetch\",le=\"1\"} 1\n",
+		"test_seconds_bucket{step=\"fetch\",le=\"5\"} 2\n",
+		"test_seconds_bucket{step=\"fetch\",le=\"+Inf\"} 3\n",
+		"test_seconds_sum{step=\"fetch\"} 13.5\n",
+		"test_seconds_count{step=\"fetch\"} 3\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output lacks %q:\n%s", want, got)
+		}
+	}
+	if strings.Index(got, "test_in_flight") > strings.Index(got, "test_runs_total") {
+		t.Errorf("families are not sorted by name:\n%s", got)
+	}
+}
+
+func TestRegisterDuplicatePanics(t *testing.T) {
+	r := NewRegistry()
+	r.register(&GaugeVec{f: newFamily("dup", "", "gauge", nil)})
+	defer func() {
+		if recover() == nil {
+			t.Fatal("registering a duplicate name did not panic")
+		}
+	}()
+	r.register(&GaugeVec{f: newFamily("dup", "", "gauge", nil)})
+}
+
+func TestFormatFloat(t *testing.T) {
+	tests := []struct {
+		in   float64
+		want string
+	}{
+		{0, "0"},
+		{1.5, "1.5"},
+		{1e21, "1e+21"},
+	}
+	for _, tt := range tests {
+		if got := formatFloat(tt.in); got != tt.want {
+			t.Errorf("formatFloat(%v) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
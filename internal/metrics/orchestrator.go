@@ -0,0 +1,116 @@
+package metrics
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// Orchestrator metrics, exposed on the HTTP /metrics endpoint.
+var (
+	RunsTotal = NewCounterVec("orchestrator_runs_total",
+		"Pipeline runs by flow and final status.", "flow", "status")
+
+	RunsInFlight = NewGaugeVec("orchestrator_runs_in_flight",
+		"Pipeline runs currently executing.", "flow")
+
+	StepDuration = NewHistogramVec("orchestrator_step_duration_seconds",
+		"Latency of individual pipeline steps.", nil, "flow", "step")
+
+	DownstreamErrors = NewCounterVec("orchestrator_downstream_errors_total",
+		"Failed calls to downstream services by service, method and gRPC code.", "service", "method", "code")
+
+	PostsPublished = NewCounterVec("orchestrator_posts_published_total",
+		"Posts successfully published by platform.", "platform")
+
+	ItemsSkipped = NewCounterVec("orchestrator_items_skipped_total",
+		"Fetched items that were filtered out or skipped, by reason.", "flow", "reason")
+
+	AICalls = NewCounterVec("orchestrator_ai_calls_total",
+		"Calls to AI-backed downstreams by flow and model provider.", "flow", "model_provider")
+
+	AITokens = NewCounterVec("orchestrator_ai_tokens_total",
+		"Tokens reported by AI-backed downstreams by flow and model provider.", "flow", "model_provider")
+
+	ProviderFailovers = NewCounterVec("orchestrator_provider_failovers_total",
+		"Generation calls retried on the next model provider.", "flow", "from", "to")
+
+	AnalysisCacheRequests = NewCounterVec("orchestrator_analysis_cache_requests_total",
+		"Analysis cache lookups by result (hit or miss).", "result")
+
+	AnalysisCacheEvictions = NewCounterVec("orchestrator_analysis_cache_evictions_total",
+		"Analyses evicted from the cache to stay within its size bound, by backend.", "backend")
+
+	DependencyUp = NewGaugeVec("orchestrator_dependency_up",
+		"Whether the last probe of a downstream dependency succeeded (1) or not (0).", "dependency")
+
+	StepRetries = NewCounterVec("orchestrator_step_retries_total",
+		"Pipeline step attempts retried, by step and the gRPC code of the failed attempt.", "step", "code")
+
+	BreakerState = NewGaugeVec("orchestrator_circuit_breaker_state",
+		"Circuit breaker state by service and key: 0 closed, 1 half-open, 2 open.", "service", "key")
+
+	BreakerTransitions = NewCounterVec("orchestrator_circuit_breaker_transitions_total",
+		"Circuit breaker state changes by service, key and new state.", "service", "key", "state")
+
+	BreakerRejections = NewCounterVec("orchestrator_circuit_breaker_rejections_total",
+		"Calls failed fast by an open circuit breaker, by service and key.", "service", "key")
+
+	RateLimitWait = NewHistogramVec("orchestrator_rate_limit_wait_seconds",
+		"Time calls waited for rate limiter capacity, by limiter kind.", nil, "kind")
+
+	RateLimitRejections = NewCounterVec("orchestrator_rate_limit_rejections_total",
+		"Calls failed because rate limiter capacity would come after their deadline, by limiter kind.", "kind")
+
+	AdmissionQueued = NewGaugeVec("orchestrator_admission_queued_runs",
+		"Pipeline runs waiting for admission, by priority.", "priority")
+
+	AdmissionWait = NewHistogramVec("orchestrator_admission_wait_seconds",
+		"Time queued pipeline runs waited for admission, by priority.", nil, "priority")
+
+	AdmissionRejections = NewCounterVec("orchestrator_admission_rejections_total",
+		"Pipeline runs turned away because the admission queue was full, by priority.", "priority")
+
+	PostsWithheld = NewCounterVec("orchestrator_posts_withheld_total",
+		"Posts kept back by a publishing policy, by platform and action (defer or drop).", "platform", "action")
+
+	Compensations = NewCounterVec("orchestrator_compensations_total",
+		"Steps undone for failed all-or-nothing runs, and posts deleted by UnpublishRun, by step and result.", "step", "result")
+
+	LeaseConflicts = NewCounterVec("orchestrator_lease_conflicts_total",
+		"Runs that found their account and flow leased by another run, by flow and conflict policy.", "flow", "policy")
+
+	LeasesLost = NewCounterVec("orchestrator_leases_lost_total",
+		"Runs cancelled because their lease expired and was taken by another run, by flow.", "flow")
+
+	DeadLetters = NewCounterVec("orchestrator_dead_letters_total",
+		"Item failures recorded in the dead letter store, by flow and failed step.", "flow", "step")
+
+	ItemsQuarantined = NewCounterVec("orchestrator_items_quarantined_total",
+		"Items quarantined after failing repeatedly, by flow.", "flow")
+
+	Leader = NewGaugeVec("orchestrator_leader",
+		"1 while this replica is the leader running the singleton duties, else 0.")
+
+	PublishingPauses = NewGaugeVec("orchestrator_publishing_pauses",
+		"Active publishing pauses by scope (global, platform, account, flow).", "scope")
+
+	OutboxDeliveries = NewCounterVec("orchestrator_outbox_deliveries_total",
+		"Deferred post delivery attempts by platform and result.", "platform", "result")
+
+	BudgetDecisions = NewCounterVec("orchestrator_budget_decisions_total",
+		"Runs blocked or downgraded by an exhausted AI budget.", "action")
+)
+
+// UnaryClientInterceptor counts failed calls made on a downstream connection.
+// service is the label recorded for every call, e.g. "fetcher".
+func UnaryClientInterceptor(service string) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		if err != nil {
+			DownstreamErrors.Inc(service, method, status.Code(err).String())
+		}
+		return err
+	}
+}
@@ -0,0 +1,217 @@
+// Package pause is the publishing kill switch: publishing can be paused
+// globally, per platform, per account or per flow, and the pauses persist
+// across restarts until resumed.
+package pause
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Optiq-CTO/orchestrator/internal/statefile"
+)
+
+// Pause scopes.
+const (
+	ScopeGlobal   = "global"
+	ScopePlatform = "platform"
+	ScopeAccount  = "account"
+	ScopeFlow     = "flow"
+)
+
+// Scopes lists the scopes, broadest first.
+var Scopes = []string{ScopeGlobal, ScopePlatform, ScopeAccount, ScopeFlow}
+
+// What happens to drafts whose publishing is paused.
+const (
+	DraftsHold    = "hold"    // kept in the outbox for approval
+	DraftsDiscard = "discard" // thrown away
+)
+
+// ErrNotPaused is returned when resuming something that is not paused.
+var ErrNotPaused = errors.New("publishing is not paused there")
+
+// Pause stops publishing within a scope. Key names the platform, account or
+// flow, and is empty for the global scope.
+type Pause struct {
+	Scope    string    `json:"scope"`
+	Key      string    `json:"key,omitempty"`
+	Reason   string    `json:"reason,omitempty"`
+	PausedBy string    `json:"paused_by,omitempty"`
+	PausedAt time.Time `json:"paused_at"`
+}
+
+// Describe names the pause for errors and logs, e.g. "platform twitter".
+func (p Pause) Describe() string {
+	if p.Scope == ScopeGlobal {
+		return "global"
+	}
+	return p.Scope + " " + p.Key
+}
+
+// Validate checks a scope and key pair.
+func Validate(scope, key string) error {
+	switch scope {
+	case ScopeGlobal:
+		if key != "" {
+			return fmt.Errorf("the global scope takes no key")
+		}
+	case ScopePlatform, ScopeAccount, ScopeFlow:
+		if key == "" {
+			return fmt.Errorf("the %s scope needs a key", scope)
+		}
+	default:
+		return fmt.Errorf("unknown scope %q; want global, platform, account or flow", scope)
+	}
+	return nil
+}
+
+// Target is a post about to be published.
+type Target struct {
+	Platform string
+	Account  string // the account the post goes out as, as publishing policies key it
+	Flow     string
+}
+
+func (t Target) key(scope string) string {
+	switch scope {
+	case ScopePlatform:
+		return t.Platform
+	case ScopeAccount:
+		return t.Account
+	case ScopeFlow:
+		return t.Flow
+	}
+	return ""
+}
+
+// Store holds the active pauses. It is safe for concurrent use, and a
+// file-backed store may be shared by the processes on one host: every call
+// re-reads the file under its lock.
+type Store struct {
+	mu     sync.Mutex
+	path   string
+	pauses map[string]Pause
+}
+
+// NewStore returns a store that keeps pauses in memory.
+func NewStore() *Store {
+	return &Store{pauses: make(map[string]Pause)}
+}
+
+// OpenStore returns a store persisted to a JSON file at path, loading it
+// first if it exists.
+func OpenStore(path string) (*Store, error) {
+	s := NewStore()
+	s.path = path
+	if err := s.update(func() bool { return false }); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func name(scope, key string) string { return scope + "/" + key }
+
+// Pause adds or replaces a pause.
+func (s *Store) Pause(p Pause) error {
+	if err := Validate(p.Scope, p.Key); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.update(func() bool {
+		s.pauses[name(p.Scope, p.Key)] = p
+		return true
+	})
+}
+
+// Resume lifts a pause and returns it.
+func (s *Store) Resume(scope, key string) (Pause, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := name(scope, key)
+	var p Pause
+	found := false
+	if err := s.update(func() bool {
+		p, found = s.pauses[n]
+		delete(s.pauses, n)
+		return found
+	}); err != nil {
+		return Pause{}, err
+	}
+	if !found {
+		return Pause{}, ErrNotPaused
+	}
+	return p, nil
+}
+
+// Match returns the broadest pause covering t, if any. If the state file
+// cannot be read, the pauses last read apply.
+func (s *Store) Match(t Target) (Pause, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.update(func() bool { return false })
+	for _, scope := range Scopes {
+		key := t.key(scope)
+		if scope != ScopeGlobal && key == "" {
+			continue
+		}
+		if p, ok := s.pauses[name(scope, key)]; ok {
+			return p, true
+		}
+	}
+	return Pause{}, false
+}
+
+// List returns the active pauses, broadest scope first.
+func (s *Store) List() []Pause {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.update(func() bool { return false })
+	return s.list()
+}
+
+func (s *Store) list() []Pause {
+	rank := make(map[string]int, len(Scopes))
+	for i, scope := range Scopes {
+		rank[scope] = i
+	}
+	out := make([]Pause, 0, len(s.pauses))
+	for _, p := range s.pauses {
+		out = append(out, p)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Scope != out[j].Scope {
+			return rank[out[i].Scope] < rank[out[j].Scope]
+		}
+		return out[i].Key < out[j].Key
+	})
+	return out
+}
+
+// update re-reads the pauses from the state file under its lock, runs fn,
+// and writes them back if fn reports a change. Callers hold s.mu.
+func (s *Store) update(fn func() bool) error {
+	if s.path == "" {
+		fn()
+		return nil
+	}
+	var pauses []Pause
+	err := statefile.Update(s.path, &pauses, func() bool {
+		s.pauses = make(map[string]Pause, len(pauses))
+		for _, p := range pauses {
+			s.pauses[name(p.Scope, p.Key)] = p
+		}
+		if !fn() {
+			return false
+		}
+		pauses = s.list()
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("pause state file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package pause
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		scope, key string
+		wantErr    bool
+	}{
+		{ScopeGlobal, "", false},
+		{ScopeGlobal, "twitter", true},
+		{ScopePlatform, "twitter", false},
+		{ScopeAccount, "", true},
+		{ScopeFlow, "twitter_echo", false},
+		{"tenant", "acme", true},
+	}
+	for _, tt := range tests {
+		if err := Validate(tt.scope, tt.key); (err != nil) != tt.wantErr {
+			t.Errorf("Validate(%q, %q) = %v, wantErr %v", tt.scope, tt.key, err, tt.wantErr)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	target := Target{Platform: "twitter", Account: "acme", Flow: "twitter_echo"}
+	tests := []struct {
+		name   string
+		pauses []Pause
+		target Target
+		want   string // Describe of the matching pause; "" for none
+	}{
+		{"none", nil, target, ""},
+		{"global", []Pause{{Scope: ScopeGlobal}}, target, "global"},
+		{"platform", []Pause{{Scope: ScopePlatform, Key: "twitter"}}, target, "platform twitter"},
+		{"other platform", []Pause{{Scope: ScopePlatform, Key: "reddit"}}, target, ""},
+		{"account", []Pause{{Scope: ScopeAccount, Key: "acme"}}, target, "account acme"},
+		{"flow", []Pause{{Scope: ScopeFlow, Key: "twitter_echo"}}, target, "flow twitter_echo"},
+		{"broadest wins", []Pause{{Scope: ScopeFlow, Key: "twitter_echo"}, {Scope: ScopePlatform, Key: "twitter"}}, target, "platform twitter"},
+		{"no account", []Pause{{Scope: ScopeAccount, Key: "acme"}}, Target{Platform: "twitter"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewStore()
+			for _, p := range tt.pauses {
+				if err := s.Pause(p); err != nil {
+					t.Fatal(err)
+				}
+			}
+			p, ok := s.Match(tt.target)
+			if got := ""; ok {
+				got = p.Describe()
+				if got != tt.want {
+					t.Errorf("Match() = %q, want %q", got, tt.want)
+				}
+			} else if tt.want != "" {
+				t.Errorf("Match() found nothing, want %q", tt.want)
+			}
+		})
+	}
+}
+
+func TestStorePersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pauses.json")
+	s, err := OpenStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []Pause{
+		{Scope: ScopeFlow, Key: "twitter_echo", Reason: "campaign review"},
+		{Scope: ScopeGlobal, Reason: "incident"},
+		{Scope: ScopePlatform, Key: "twitter"},
+	} {
+		if err := s.Pause(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Pause(Pause{Scope: ScopeAccount}); err == nil {
+		t.Error("pause without a key accepted")
+	}
+	if _, err := s.Resume(ScopePlatform, "twitter"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Resume(ScopePlatform, "twitter"); err != ErrNotPaused {
+		t.Errorf("second Resume() = %v, want ErrNotPaused", err)
+	}
+
+	reopened, err := OpenStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := reopened.List()
+	if len(got) != 2 || got[0].Scope != ScopeGlobal || got[1].Key != "twitter_echo" || got[1].Reason != "campaign review" {
+		t.Errorf("List() after reopening = %+v", got)
+	}
+}
+
+func TestStoreShared(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pauses.json")
+	a, err := OpenStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := OpenStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Pause(Pause{Scope: ScopePlatform, Key: "twitter"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Pause(Pause{Scope: ScopeAccount, Key: "acme"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := b.Match(Target{Platform: "twitter"}); !ok {
+		t.Error("pause set through one store not seen by the other")
+	}
+	if got := a.List(); len(got) != 2 {
+		t.Errorf("List() = %+v; a write through one store dropped the other's pause", got)
+	}
+	if _, err := b.Resume(ScopePlatform, "twitter"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := a.Match(Target{Platform: "twitter"}); ok {
+		t.Error("pause resumed through one store still matches in the other")
+	}
+}
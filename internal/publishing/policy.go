@@ -0,0 +1,273 @@
+// Package publishing enforces per-account and per-platform publishing
+// policies (daily and weekly caps, spacing between posts, quiet hours and
+// blackout dates) and keeps the outbox of posts deferred by them.
+package publishing
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// What happens to a post that violates its policy.
+const (
+	ActionDefer = "defer"
+	ActionDrop  = "drop"
+)
+
+// Config is the publishing policies file.
+//
+//	platforms:
+//	  twitter:
+//	    max_per_day: 10
+//	    min_spacing: 20m
+//	accounts:
+//	  health-page:
+//	    timezone: America/New_York
+//	    max_per_day: 3
+//	    max_per_week: 12
+//	    min_spacing: 2h
+//	    quiet_hours: {start: "22:00", end: "07:00"}
+//	    blackout_dates: ["2026-12-25"]
+//	    on_violation: drop
+//
+// Platform policies apply to every account's posts on the platform; an
+// account's policy overrides them field by field. Accounts are keyed by the
+// account a post goes out as: the registered account ID, else the page ID,
+// Twitter user ID or credential store account, or "publisher/<platform>"
+// for posts made with the publisher's own credentials.
+type Config struct {
+	Platforms map[string]Policy `yaml:"platforms"`
+	Accounts  map[string]Policy `yaml:"accounts"`
+}
+
+// Policy limits how often and when an account posts on a platform. Zero
+// fields are not enforced.
+type Policy struct {
+	Timezone      string        `yaml:"timezone"` // IANA name for days, weeks and quiet hours; UTC if unset
+	MaxPerDay     int           `yaml:"max_per_day"`
+	MaxPerWeek    int           `yaml:"max_per_week"` // weeks start on Monday
+	MinSpacing    time.Duration `yaml:"min_spacing"`
+	QuietHours    *QuietHours   `yaml:"quiet_hours"`
+	BlackoutDates []string      `yaml:"blackout_dates"` // YYYY-MM-DD
+	OnViolation   string        `yaml:"on_violation"`   // "defer" (default) or "drop"
+
+	loc *time.Location
+}
+
+// QuietHours is a daily window, HH:MM to HH:MM, during which nothing is
+// posted. A window whose end is before its start spans midnight.
+type QuietHours struct {
+	Start string `yaml:"start"`
+	End   string `yaml:"end"`
+}
+
+// LoadConfig reads and validates a publishing policies file.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading publishing policies file: %w", err)
+	}
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parsing publishing policies file: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// Validate checks every policy and loads its timezone.
+func (c *Config) Validate() error {
+	for _, section := range []struct {
+		name     string
+		policies map[string]Policy
+	}{{"platforms", c.Platforms}, {"accounts", c.Accounts}} {
+		for key, p := range section.policies {
+			if err := p.init(); err != nil {
+				return fmt.Errorf("publishing policy %s.%s: %w", section.name, key, err)
+			}
+			section.policies[key] = p
+		}
+	}
+	return nil
+}
+
+func (p *Policy) init() error {
+	if p.MaxPerDay < 0 || p.MaxPerWeek < 0 || p.MinSpacing < 0 {
+		return fmt.Errorf("limits must not be negative")
+	}
+	switch p.OnViolation {
+	case "", ActionDefer, ActionDrop:
+	default:
+		return fmt.Errorf("on_violation must be %s or %s, got %q", ActionDefer, ActionDrop, p.OnViolation)
+	}
+	if p.QuietHours != nil {
+		if _, err := clock(p.QuietHours.Start); err != nil {
+			return fmt.Errorf("quiet_hours.start: %w", err)
+		}
+		if _, err := clock(p.QuietHours.End); err != nil {
+			return fmt.Errorf("quiet_hours.end: %w", err)
+		}
+	}
+	for _, d := range p.BlackoutDates {
+		if _, err := time.Parse(time.DateOnly, d); err != nil {
+			return fmt.Errorf("blackout date %q is not YYYY-MM-DD", d)
+		}
+	}
+	if p.Timezone != "" {
+		loc, err := time.LoadLocation(p.Timezone)
+		if err != nil {
+			return fmt.Errorf("timezone: %w", err)
+		}
+		p.loc = loc
+	}
+	return nil
+}
+
+// clock parses HH:MM into minutes after midnight.
+func clock(s string) (int, error) {
+	h, m, ok := strings.Cut(s, ":")
+	hour, err1 := strconv.Atoi(h)
+	minute, err2 := strconv.Atoi(m)
+	if !ok || err1 != nil || err2 != nil || hour < 0 || hour > 23 || minute < 0 || minute > 59 {
+		return 0, fmt.Errorf("%q is not HH:MM", s)
+	}
+	return hour*60 + minute, nil
+}
+
+// Policy returns the policy of an account's posts on a platform.
+func (c *Config) Policy(account, platform string) Policy {
+	p := c.Platforms[platform]
+	a, ok := c.Accounts[account]
+	if !ok {
+		return p
+	}
+	if a.Timezone != "" {
+		p.Timezone, p.loc = a.Timezone, a.loc
+	}
+	if a.MaxPerDay != 0 {
+		p.MaxPerDay = a.MaxPerDay
+	}
+	if a.MaxPerWeek != 0 {
+		p.MaxPerWeek = a.MaxPerWeek
+	}
+	if a.MinSpacing != 0 {
+		p.MinSpacing = a.MinSpacing
+	}
+	if a.QuietHours != nil {
+		p.QuietHours = a.QuietHours
+	}
+	if a.BlackoutDates != nil {
+		p.BlackoutDates = a.BlackoutDates
+	}
+	if a.OnViolation != "" {
+		p.OnViolation = a.OnViolation
+	}
+	return p
+}
+
+func (p Policy) location() *time.Location {
+	if p.loc != nil {
+		return p.loc
+	}
+	return time.UTC
+}
+
+// Decision is the outcome of checking a post against its policy.
+type Decision struct {
+	Allowed bool
+	Action  string    // for posts not allowed: ActionDefer or ActionDrop
+	Reason  string    // the first rule the post violates
+	Retry   time.Time // when the post would next be allowed; zero if not in sight
+}
+
+// lookahead bounds the search for the next allowed time.
+const lookahead = 64
+
+// Check decides whether a post may go out at now, given the times of the
+// account's earlier posts on the platform.
+func (p Policy) Check(posts []time.Time, now time.Time) Decision {
+	reason, until, violated := p.violation(posts, now)
+	if !violated {
+		return Decision{Allowed: true}
+	}
+	d := Decision{Action: p.OnViolation, Reason: reason}
+	if d.Action == "" {
+		d.Action = ActionDefer
+	}
+	for i := 0; i < lookahead; i++ {
+		if _, next, v := p.violation(posts, until); !v {
+			d.Retry = until
+			break
+		} else {
+			until = next
+		}
+	}
+	return d
+}
+
+// violation returns the first rule a post at t violates and the earliest
+// time that rule allows it.
+func (p Policy) violation(posts []time.Time, t time.Time) (reason string, until time.Time, violated bool) {
+	lt := t.In(p.location())
+	midnight := time.Date(lt.Year(), lt.Month(), lt.Day(), 0, 0, 0, 0, lt.Location())
+	date := lt.Format(time.DateOnly)
+	for _, d := range p.BlackoutDates {
+		if d == date {
+			return "blackout date " + date, midnight.AddDate(0, 0, 1), true
+		}
+	}
+	if q := p.QuietHours; q != nil {
+		start, _ := clock(q.Start)
+		end, _ := clock(q.End)
+		now := lt.Hour()*60 + lt.Minute()
+		quiet := now >= start && now < end
+		if end < start {
+			quiet = now >= start || now < end
+		}
+		if quiet {
+			until := midnight.Add(time.Duration(end) * time.Minute)
+			if !until.After(lt) {
+				until = midnight.AddDate(0, 0, 1).Add(time.Duration(end) * time.Minute)
+			}
+			return fmt.Sprintf("quiet hours %s-%s %s", q.Start, q.End, p.location()), until, true
+		}
+	}
+	if p.MaxPerDay > 0 && countSince(posts, midnight, t) >= p.MaxPerDay {
+		return fmt.Sprintf("daily cap of %d posts reached", p.MaxPerDay), midnight.AddDate(0, 0, 1), true
+	}
+	if p.MaxPerWeek > 0 {
+		week := midnight.AddDate(0, 0, -((int(lt.Weekday()) + 6) % 7))
+		if countSince(posts, week, t) >= p.MaxPerWeek {
+			return fmt.Sprintf("weekly cap of %d posts reached", p.MaxPerWeek), week.AddDate(0, 0, 7), true
+		}
+	}
+	if p.MinSpacing > 0 {
+		var last time.Time
+		for _, at := range posts {
+			if !at.After(t) && at.After(last) {
+				last = at
+			}
+		}
+		if !last.IsZero() && t.Sub(last) < p.MinSpacing {
+			return fmt.Sprintf("last post %s ago; minimum spacing is %s", t.Sub(last).Round(time.Second), p.MinSpacing), last.Add(p.MinSpacing), true
+		}
+	}
+	return "", time.Time{}, false
+}
+
+func countSince(posts []time.Time, since, t time.Time) int {
+	n := 0
+	for _, at := range posts {
+		if !at.Before(since) && !at.After(t) {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,260 @@
+package publishing
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  Policy
+		wantErr string
+	}{
+		{"zero", Policy{}, ""},
+		{"full", Policy{Timezone: "America/New_York", MaxPerDay: 3, QuietHours: &QuietHours{"22:00", "07:00"}, BlackoutDates: []string{"2026-12-25"}, OnViolation: ActionDrop}, ""},
+		{"negative", Policy{MaxPerWeek: -1}, "must not be negative"},
+		{"bad action", Policy{OnViolation: "skip"}, "on_violation"},
+		{"bad clock", Policy{QuietHours: &QuietHours{"24:00", "07:00"}}, "quiet_hours.start"},
+		{"bad date", Policy{BlackoutDates: []string{"12/25"}}, "blackout date"},
+		{"bad timezone", Policy{Timezone: "Mars/Olympus"}, "timezone"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{Accounts: map[string]Policy{"acme": tt.policy}}
+			err := cfg.Validate()
+			if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("Validate() = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConfigPolicy(t *testing.T) {
+	cfg := Config{
+		Platforms: map[string]Policy{"twitter": {MaxPerDay: 10, MinSpacing: 20 * time.Minute}},
+		Accounts:  map[string]Policy{"acme": {Timezone: "Europe/Berlin", MaxPerDay: 3, OnViolation: ActionDrop}},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	p := cfg.Policy("acme", "twitter")
+	if p.MaxPerDay != 3 || p.MinSpacing != 20*time.Minute || p.OnViolation != ActionDrop || p.location().String() != "Europe/Berlin" {
+		t.Errorf("acme on twitter = %+v", p)
+	}
+	if p := cfg.Policy("globex", "twitter"); p.MaxPerDay != 10 || p.location() != time.UTC {
+		t.Errorf("globex on twitter = %+v", p)
+	}
+	if p := cfg.Policy("acme", "reddit"); p.MaxPerDay != 3 || p.MinSpacing != 0 {
+		t.Errorf("acme on reddit = %+v", p)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	// Wednesday 2026-01-07, 12:00 UTC.
+	now := time.Date(2026, 1, 7, 12, 0, 0, 0, time.UTC)
+	ago := func(d time.Duration) time.Time { return now.Add(-d) }
+	tests := []struct {
+		name       string
+		policy     Policy
+		posts      []time.Time
+		wantAction string // "" if allowed
+		wantReason string
+		wantRetry  time.Time
+	}{
+		{"no policy", Policy{}, []time.Time{ago(time.Minute)}, "", "", time.Time{}},
+		{"under daily cap", Policy{MaxPerDay: 2}, []time.Time{ago(time.Hour)}, "", "", time.Time{}},
+		{"daily cap", Policy{MaxPerDay: 2}, []time.Time{ago(time.Hour), ago(2 * time.Hour), ago(13 * time.Hour)}, ActionDefer, "daily cap of 2", time.Date(2026, 1, 8, 0, 0, 0, 0, time.UTC)},
+		{"weekly cap", Policy{MaxPerWeek: 2}, []time.Time{ago(24 * time.Hour), ago(48 * time.Hour)}, ActionDefer, "weekly cap of 2", time.Date(2026, 1, 12, 0, 0, 0, 0, time.UTC)},
+		{"last week does not count", Policy{MaxPerWeek: 1}, []time.Time{ago(3 * 24 * time.Hour)}, "", "", time.Time{}},
+		{"spacing", Policy{MinSpacing: time.Hour}, []time.Time{ago(20 * time.Minute)}, ActionDefer, "minimum spacing", now.Add(40 * time.Minute)},
+		{"quiet hours", Policy{QuietHours: &QuietHours{"11:00", "13:30"}}, nil, ActionDefer, "quiet hours", time.Date(2026, 1, 7, 13, 30, 0, 0, time.UTC)},
+		{"quiet hours over midnight", Policy{QuietHours: &QuietHours{"22:00", "07:00"}}, nil, "", "", time.Time{}},
+		{"quiet hours in timezone", Policy{Timezone: "America/New_York", QuietHours: &QuietHours{"06:00", "08:00"}}, nil, ActionDefer, "quiet hours", time.Date(2026, 1, 7, 13, 0, 0, 0, time.UTC)},
+		{"blackout", Policy{BlackoutDates: []string{"2026-01-07"}}, nil, ActionDefer, "blackout date", time.Date(2026, 1, 8, 0, 0, 0, 0, time.UTC)},
+		{"drop", Policy{MaxPerDay: 1, OnViolation: ActionDrop}, []time.Time{ago(time.Hour)}, ActionDrop, "daily cap", time.Date(2026, 1, 8, 0, 0, 0, 0, time.UTC)},
+		{"retry skips later rules", Policy{MaxPerDay: 1, QuietHours: &QuietHours{"00:00", "09:00"}}, []time.Time{ago(time.Hour)}, ActionDefer, "daily cap", time.Date(2026, 1, 8, 9, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{Accounts: map[string]Policy{"a": tt.policy}}
+			if err := cfg.Validate(); err != nil {
+				t.Fatal(err)
+			}
+			d := cfg.Accounts["a"].Check(tt.posts, now)
+			if d.Allowed != (tt.wantAction == "") || d.Action != tt.wantAction {
+				t.Fatalf("Check() = %+v, want action %q", d, tt.wantAction)
+			}
+			if !strings.Contains(d.Reason, tt.wantReason) {
+				t.Errorf("reason = %q, want it to contain %q", d.Reason, tt.wantReason)
+			}
+			if !d.Retry.Equal(tt.wantRetry) {
+				t.Errorf("retry = %v, want %v", d.Retry, tt.wantRetry)
+			}
+		})
+	}
+}
+
+func TestStoreReserve(t *testing.T) {
+	s := NewStore()
+	now := time.Date(2026, 1, 7, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	p := Policy{MaxPerDay: 1}
+
+	d, undo, err := s.Reserve(p, "acme", "twitter")
+	if err != nil || !d.Allowed {
+		t.Fatalf("first post: %+v, %v", d, err)
+	}
+	if d, _, _ := s.Reserve(p, "acme", "twitter"); d.Allowed {
+		t.Fatal("second post allowed past the daily cap")
+	}
+	if d, _, _ := s.Reserve(p, "acme", "reddit"); !d.Allowed {
+		t.Error("post on another platform counted against twitter")
+	}
+	if d, _, _ := s.Reserve(p, "globex", "twitter"); !d.Allowed {
+		t.Error("another account's post counted against acme")
+	}
+	undo()
+	if d, _, _ := s.Reserve(p, "acme", "twitter"); !d.Allowed {
+		t.Error("undone post still counted")
+	}
+}
+
+func TestStoreOutbox(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "publishing.json")
+	s, err := OpenStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 1, 7, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+
+	due, err := s.Defer(Entry{Account: "acme", Platform: "twitter", Content: "hi", NotBefore: now.Add(-time.Minute),
+		Credentials: map[string]string{"api_key": "secret://acme/api_key"}, Plain: map[string]string{"token": "raw"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	later, err := s.Defer(Entry{Account: "acme", Platform: "twitter", NotBefore: now.Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if due.ID == "" || due.ID == later.ID || due.Status != StatusPending {
+		t.Fatalf("deferred entries: %+v, %+v", due, later)
+	}
+	if got := s.Due(); len(got) != 1 || got[0].ID != due.ID {
+		t.Fatalf("Due() = %+v, want only %s", got, due.ID)
+	}
+
+	claimed, err := s.Claim(due.ID)
+	if err != nil || claimed.Status != StatusSending || claimed.Plain["token"] != "raw" {
+		t.Fatalf("Claim = %+v, %v", claimed, err)
+	}
+	if _, err := s.Claim(due.ID); !errors.Is(err, ErrConflict) {
+		t.Errorf("second Claim = %v, want ErrConflict", err)
+	}
+	claimed.Status, claimed.Attempts = StatusPublished, 1
+	if err := s.Update(claimed, StatusPending); !errors.Is(err, ErrConflict) {
+		t.Errorf("Update from a stale status = %v, want ErrConflict", err)
+	}
+	if err := s.Update(claimed, StatusSending); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Update(Entry{ID: "post-missing"}, StatusPending); err != ErrNotFound {
+		t.Errorf("Update(unknown) = %v, want ErrNotFound", err)
+	}
+	if len(s.Due()) != 0 {
+		t.Error("published entry still due")
+	}
+
+	reopened, err := OpenStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := reopened.Get(due.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != StatusPublished || got.Attempts != 1 || got.Credentials["api_key"] != "secret://acme/api_key" {
+		t.Errorf("reopened entry = %+v", got)
+	}
+	if got.Plain != nil {
+		t.Errorf("plain credentials were persisted: %v", got.Plain)
+	}
+	if n := len(reopened.Entries(StatusPending)); n != 1 {
+		t.Errorf("%d pending entries after reopening, want 1", n)
+	}
+	if n := len(reopened.Entries("")); n != 2 {
+		t.Errorf("%d entries after reopening, want 2", n)
+	}
+}
+
+func TestStoreFailsStaleClaims(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "publishing.json")
+	s, err := OpenStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		claim bool
+		want  string
+	}{
+		{false, StatusPending},
+		{true, StatusFailed},
+	}
+	ids := make([]string, len(tests))
+	for i, tt := range tests {
+		e, err := s.Defer(Entry{Account: "acme", Platform: "twitter"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tt.claim {
+			if _, err := s.Claim(e.ID); err != nil {
+				t.Fatal(err)
+			}
+		}
+		ids[i] = e.ID
+	}
+	other, err := OpenStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := other.Get(ids[1]); got.Status != StatusSending {
+		t.Errorf("fresh claim: status = %q, want %q", got.Status, StatusSending)
+	}
+	later := time.Now().Add(claimTimeout + time.Minute)
+	other.now = func() time.Time { return later }
+	for i, tt := range tests {
+		if got, _ := other.Get(ids[i]); got.Status != tt.want {
+			t.Errorf("claimed=%v: status after the claim timeout = %q, want %q", tt.claim, got.Status, tt.want)
+		}
+	}
+	if got, _ := s.Get(ids[1]); got.Status != StatusFailed {
+		t.Errorf("claiming store: status = %q, want %q", got.Status, StatusFailed)
+	}
+}
+
+func TestStorePrunes(t *testing.T) {
+	s := NewStore()
+	now := time.Date(2026, 1, 7, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	e, _ := s.Defer(Entry{Account: "acme"})
+	e.Status = StatusCancelled
+	s.Update(e, StatusPending)
+	pending, _ := s.Defer(Entry{Account: "acme"})
+	s.Reserve(Policy{}, "acme", "twitter")
+
+	now = now.Add(entryRetention + time.Hour)
+	s.Defer(Entry{Account: "globex"})
+	if _, err := s.Get(e.ID); err != ErrNotFound {
+		t.Error("old finished entry was kept")
+	}
+	if _, err := s.Get(pending.ID); err != nil {
+		t.Error("old pending entry was pruned")
+	}
+	if len(s.posts) != 0 {
+		t.Errorf("old post history kept: %+v", s.posts)
+	}
+}
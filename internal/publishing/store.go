@@ -0,0 +1,390 @@
+package publishing
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Optiq-CTO/orchestrator/internal/statefile"
+)
+
+// Outbox entry statuses.
+const (
+	StatusPending   = "pending"
+	StatusHeld      = "held"    // awaiting approval; see pause
+	StatusSending   = "sending" // claimed for delivery; see Claim
+	StatusPublished = "published"
+	StatusDropped   = "dropped"
+	StatusFailed    = "failed"
+	StatusCancelled = "cancelled"
+	StatusDeleted   = "deleted" // published, then deleted again
+)
+
+// Errors returned by the outbox.
+var (
+	ErrNotFound = errors.New("outbox entry not found")
+	// ErrConflict is returned when an entry's status changed since it was
+	// read.
+	ErrConflict = errors.New("outbox entry changed concurrently")
+)
+
+// Retention bounds how long post history and finished outbox entries are
+// kept. Post history only needs to cover the weekly cap.
+const (
+	postRetention  = 8 * 24 * time.Hour
+	entryRetention = 30 * 24 * time.Hour
+)
+
+// Post is one published post, counted against its account's caps.
+type Post struct {
+	Account  string    `json:"account"`
+	Platform string    `json:"platform"`
+	At       time.Time `json:"at"`
+}
+
+// Entry is a post deferred to the outbox, or held there for approval.
+type Entry struct {
+	ID       string   `json:"id"`
+	RunID    string   `json:"run_id"`
+	Flow     string   `json:"flow"`
+	Tenant   string   `json:"tenant,omitempty"`
+	Account  string   `json:"account"`            // the account the post goes out as
+	Accounts []string `json:"accounts,omitempty"` // credential store accounts the run used
+	Platform string   `json:"platform"`
+	Content  string   `json:"content"`
+	// Credentials holds the publish credentials as secret references. Those
+	// the run passed as plain values are kept in the deferring process's
+	// memory only; other processes and restarts lose them.
+	Credentials map[string]string `json:"credentials,omitempty"`
+	Plain       map[string]string `json:"-"`
+	Reason      string            `json:"reason"`
+	NotBefore   time.Time         `json:"not_before"`
+	CreatedAt   time.Time         `json:"created_at"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+	Status      string            `json:"status"`
+	Attempts    int               `json:"attempts,omitempty"`
+	Error       string            `json:"error,omitempty"`
+	PostID      string            `json:"post_id,omitempty"`
+	PostURL     string            `json:"post_url,omitempty"`
+}
+
+func (e *Entry) clone() Entry {
+	c := *e
+	c.Accounts = append([]string(nil), e.Accounts...)
+	c.Credentials = cloneMap(e.Credentials)
+	c.Plain = cloneMap(e.Plain)
+	return c
+}
+
+func cloneMap(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	c := make(map[string]string, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
+// Store keeps the post history policies are checked against and the
+// outbox. It is safe for concurrent use, and a file-backed store may be
+// shared by the processes on one host: every call re-reads the file under
+// its lock.
+type Store struct {
+	mu     sync.Mutex
+	path   string
+	posts  []Post
+	outbox map[string]*Entry
+	now    func() time.Time
+}
+
+type state struct {
+	Posts  []Post   `json:"posts"`
+	Outbox []*Entry `json:"outbox"`
+}
+
+// NewStore returns a store that keeps everything in memory.
+func NewStore() *Store {
+	return &Store{outbox: make(map[string]*Entry), now: time.Now}
+}
+
+// OpenStore returns a store persisted to a JSON file at path, loading it
+// first if it exists, so caps and deferred posts survive restarts.
+func OpenStore(path string) (*Store, error) {
+	s := NewStore()
+	s.path = path
+	if err := s.update(func() bool { return false }); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Reserve checks a post by account on platform against p and, if it is
+// allowed, counts it at once so concurrent posts see it. undo takes the
+// post back out, for publishes that fail. An error means the post was
+// counted but could not be persisted.
+func (s *Store) Reserve(p Policy, account, platform string) (d Decision, undo func(), err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	post := Post{Account: account, Platform: platform, At: now.UTC()}
+	err = s.update(func() bool {
+		var times []time.Time
+		for _, post := range s.posts {
+			if post.Account == account && post.Platform == platform {
+				times = append(times, post.At)
+			}
+		}
+		if d = p.Check(times, now); !d.Allowed {
+			return false
+		}
+		s.posts = append(s.posts, post)
+		return true
+	})
+	if !d.Allowed {
+		return d, func() {}, err
+	}
+	return d, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.update(func() bool {
+			for i, q := range s.posts {
+				if q.Account == post.Account && q.Platform == post.Platform && q.At.Equal(post.At) {
+					s.posts = append(s.posts[:i], s.posts[i+1:]...)
+					return true
+				}
+			}
+			return false
+		})
+	}, err
+}
+
+// Defer adds a pending entry to the outbox and returns it with its ID.
+func (s *Store) Defer(e Entry) (Entry, error) {
+	return s.add(e, StatusPending)
+}
+
+// Hold adds an entry that waits for approval instead of its time.
+func (s *Store) Hold(e Entry) (Entry, error) {
+	return s.add(e, StatusHeld)
+}
+
+func (s *Store) add(e Entry, status string) (Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now().UTC()
+	e.ID = newID()
+	e.Status = status
+	e.CreatedAt, e.UpdatedAt = now, now
+	stored := e.clone()
+	return e, s.update(func() bool {
+		s.outbox[e.ID] = &stored
+		return true
+	})
+}
+
+// Due returns the pending entries whose time has come, oldest first. If
+// the state file cannot be read, the entries last read apply.
+func (s *Store) Due() []Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.update(func() bool { return false })
+	now := s.now()
+	var out []Entry
+	for _, e := range s.outbox {
+		if e.Status == StatusPending && !e.NotBefore.After(now) {
+			out = append(out, e.clone())
+		}
+	}
+	sortEntries(out)
+	return out
+}
+
+// Claim marks a pending entry as sending and returns it, so no one else
+// delivers, cancels or approves it meanwhile. It returns ErrConflict if the
+// entry is no longer pending. A claim not settled by Update within
+// claimTimeout is taken to have died with its process; see failStale.
+func (s *Store) Claim(id string) (Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var (
+		claimed  Entry
+		claimErr error
+	)
+	err := s.update(func() bool {
+		e, ok := s.outbox[id]
+		if !ok {
+			claimErr = ErrNotFound
+			return false
+		}
+		if e.Status != StatusPending {
+			claimErr = fmt.Errorf("%w: %s is %s", ErrConflict, id, e.Status)
+			return false
+		}
+		e.Status, e.UpdatedAt = StatusSending, s.now().UTC()
+		claimed = e.clone()
+		return true
+	})
+	if err == nil {
+		err = claimErr
+	}
+	if err != nil {
+		return Entry{}, err
+	}
+	return claimed, nil
+}
+
+// Update replaces an entry, e.g. once it is delivered, if its stored status
+// is still from, the status it was read with. Otherwise it returns
+// ErrConflict and leaves the entry alone.
+func (s *Store) Update(e Entry, from string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e.UpdatedAt = s.now().UTC()
+	stored := e.clone()
+	var updateErr error
+	err := s.update(func() bool {
+		cur, ok := s.outbox[e.ID]
+		if !ok {
+			updateErr = ErrNotFound
+			return false
+		}
+		if cur.Status != from {
+			updateErr = fmt.Errorf("%w: %s is %s, not %s", ErrConflict, e.ID, cur.Status, from)
+			return false
+		}
+		s.outbox[e.ID] = &stored
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	return updateErr
+}
+
+// Get returns one entry.
+func (s *Store) Get(id string) (Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.update(func() bool { return false }); err != nil {
+		return Entry{}, err
+	}
+	e, ok := s.outbox[id]
+	if !ok {
+		return Entry{}, ErrNotFound
+	}
+	return e.clone(), nil
+}
+
+// Entries lists the outbox, oldest first. An empty status lists all.
+func (s *Store) Entries(status string) []Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.update(func() bool { return false })
+	var out []Entry
+	for _, e := range s.outbox {
+		if status == "" || e.Status == status {
+			out = append(out, e.clone())
+		}
+	}
+	sortEntries(out)
+	return out
+}
+
+func sortEntries(entries []Entry) {
+	sort.Slice(entries, func(i, j int) bool {
+		if !entries[i].CreatedAt.Equal(entries[j].CreatedAt) {
+			return entries[i].CreatedAt.Before(entries[j].CreatedAt)
+		}
+		return entries[i].ID < entries[j].ID
+	})
+}
+
+// update re-reads the state file under its lock, runs fn, and writes the
+// state back if fn reports a change. Plain credentials stay with the
+// entries already in memory. Callers hold s.mu.
+func (s *Store) update(fn func() bool) error {
+	if s.path == "" {
+		if fn() {
+			s.prune()
+		}
+		return nil
+	}
+	var st state
+	err := statefile.Update(s.path, &st, func() bool {
+		prev := s.outbox
+		s.posts = st.Posts
+		s.outbox = make(map[string]*Entry, len(st.Outbox))
+		for _, e := range st.Outbox {
+			if p, ok := prev[e.ID]; ok {
+				e.Plain = p.Plain
+			}
+			s.outbox[e.ID] = e
+		}
+		stale := s.failStale()
+		if !fn() && !stale {
+			return false
+		}
+		s.prune()
+		st = state{Posts: s.posts, Outbox: make([]*Entry, 0, len(s.outbox))}
+		for _, e := range s.outbox {
+			st.Outbox = append(st.Outbox, e)
+		}
+		sort.Slice(st.Outbox, func(i, j int) bool { return st.Outbox[i].ID < st.Outbox[j].ID })
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("publishing state file: %w", err)
+	}
+	return nil
+}
+
+// claimTimeout bounds how long a claimed entry may stay sending. Sends take
+// seconds, so an older claim belongs to a process that died mid-delivery.
+const claimTimeout = time.Hour
+
+// failStale fails the entries whose claim timed out and reports whether
+// there were any. A delivery cut short may or may not have posted, so the
+// entry is never sent again. Callers hold s.mu.
+func (s *Store) failStale() bool {
+	now := s.now()
+	stale := false
+	for _, e := range s.outbox {
+		if e.Status == StatusSending && now.Sub(e.UpdatedAt) > claimTimeout {
+			e.Status, e.Error = StatusFailed, "interrupted while sending; the post may have been published"
+			e.UpdatedAt = now.UTC()
+			stale = true
+		}
+	}
+	return stale
+}
+
+// prune drops old history and finished entries. Callers hold s.mu.
+func (s *Store) prune() {
+	now := s.now()
+	kept := s.posts[:0]
+	for _, p := range s.posts {
+		if now.Sub(p.At) < postRetention {
+			kept = append(kept, p)
+		}
+	}
+	s.posts = kept
+	for id, e := range s.outbox {
+		if e.Status != StatusPending && now.Sub(e.UpdatedAt) > entryRetention {
+			delete(s.outbox, id)
+		}
+	}
+}
+
+func newID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "post-" + time.Now().UTC().Format("20060102T150405.000000000")
+	}
+	return "post-" + hex.EncodeToString(b)
+}
@@ -0,0 +1,322 @@
+// Package ratelimit shares token-bucket limits across all runs: per model
+// provider for AI calls, per social platform and per account for platform
+// API calls. Callers wait for capacity rather than fail, as long as their
+// deadline allows it.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Optiq-CTO/orchestrator/internal/metrics"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// Limiter kinds.
+const (
+	KindProvider = "provider"
+	KindPlatform = "platform"
+	KindAccount  = "account"
+)
+
+// DefaultKey is the entry applied to keys without their own limit.
+const DefaultKey = "default"
+
+// Limit is a token bucket: PerMinute tokens are added per minute, up to
+// Burst.
+type Limit struct {
+	PerMinute float64 `yaml:"per_minute"`
+	Burst     int     `yaml:"burst"`
+}
+
+// Config is the rate_limits section of the server configuration. Each map
+// is keyed by model provider, platform or account; a "default" entry applies
+// to keys without one. Keys with no limit are not limited.
+//
+//	rate_limits:
+//	  providers:
+//	    gemini: {per_minute: 15, burst: 3}
+//	  platforms:
+//	    twitter: {per_minute: 10, burst: 2}
+//	  accounts:
+//	    default: {per_minute: 6, burst: 2}
+type Config struct {
+	Providers map[string]Limit `yaml:"providers"`
+	Platforms map[string]Limit `yaml:"platforms"`
+	Accounts  map[string]Limit `yaml:"accounts"`
+}
+
+// Validate rejects limits that would never grant a token.
+func (c Config) Validate() error {
+	for _, section := range []struct {
+		name   string
+		limits map[string]Limit
+	}{{"providers", c.Providers}, {"platforms", c.Platforms}, {"accounts", c.Accounts}} {
+		for key, l := range section.limits {
+			if l.PerMinute <= 0 || l.Burst < 1 {
+				return fmt.Errorf("rate_limits.%s.%s: per_minute must be positive and burst at least 1", section.name, key)
+			}
+		}
+	}
+	return nil
+}
+
+func (c Config) limits(kind string) map[string]Limit {
+	switch kind {
+	case KindProvider:
+		return c.Providers
+	case KindPlatform:
+		return c.Platforms
+	}
+	return c.Accounts
+}
+
+func (c Config) limit(kind, key string) (Limit, bool) {
+	limits := c.limits(kind)
+	if l, ok := limits[key]; ok {
+		return l, true
+	}
+	l, ok := limits[DefaultKey]
+	return l, ok
+}
+
+// bucket is one token bucket. Tokens may go negative: each waiter reserves
+// its token up front and waits until the bucket has refilled past it.
+type bucket struct {
+	limit   Limit
+	tokens  float64
+	last    time.Time
+	waiting int
+}
+
+func (b *bucket) advance(now time.Time) {
+	if now.After(b.last) {
+		b.tokens = math.Min(b.tokens+now.Sub(b.last).Minutes()*b.limit.PerMinute, float64(b.limit.Burst))
+		b.last = now
+	}
+}
+
+// reserve takes a token and returns how long until it is covered.
+func (b *bucket) reserve(now time.Time) time.Duration {
+	b.advance(now)
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.limit.PerMinute * float64(time.Minute))
+}
+
+// Limiters holds the buckets of every kind and key, created on first use.
+type Limiters struct {
+	mu      sync.Mutex
+	cfg     Config
+	buckets map[string]*bucket
+	now     func() time.Time
+}
+
+// New returns limiters enforcing cfg.
+func New(cfg Config) *Limiters {
+	return &Limiters{cfg: cfg, buckets: make(map[string]*bucket), now: time.Now}
+}
+
+// Update replaces the limits. Existing buckets keep their tokens, capped at
+// the new burst; buckets whose key lost its limit are dropped.
+func (l *Limiters) Update(cfg Config) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.cfg = cfg
+	now := l.now()
+	for name, b := range l.buckets {
+		kind, key := splitName(name)
+		limit, ok := cfg.limit(kind, key)
+		if !ok {
+			delete(l.buckets, name)
+			continue
+		}
+		b.advance(now)
+		b.limit = limit
+		b.tokens = math.Min(b.tokens, float64(limit.Burst))
+	}
+}
+
+// Key names a bucket.
+type Key struct {
+	Kind string
+	Key  string
+}
+
+func bucketName(k Key) string { return k.Kind + "/" + k.Key }
+
+func splitName(name string) (kind, key string) {
+	kind, key, _ = strings.Cut(name, "/")
+	return kind, key
+}
+
+// Wait takes a token from every limited bucket among keys, waiting until
+// all of them have capacity. If ctx's deadline comes first, it returns
+// ResourceExhausted at once, without waiting, and gives the tokens back.
+func (l *Limiters) Wait(ctx context.Context, keys ...Key) error {
+	l.mu.Lock()
+	now := l.now()
+	var (
+		wait     time.Duration
+		reserved []*bucket
+		names    []string
+		kinds    []string
+	)
+	for _, k := range keys {
+		if k.Key == "" {
+			continue
+		}
+		limit, ok := l.cfg.limit(k.Kind, k.Key)
+		if !ok {
+			continue
+		}
+		name := bucketName(k)
+		b, ok := l.buckets[name]
+		if !ok {
+			b = &bucket{limit: limit, tokens: float64(limit.Burst), last: now}
+			l.buckets[name] = b
+		}
+		if d := b.reserve(now); d > wait {
+			wait = d
+		}
+		reserved = append(reserved, b)
+		names = append(names, name)
+		kinds = append(kinds, k.Kind)
+	}
+	if wait == 0 {
+		l.mu.Unlock()
+		return nil
+	}
+	if deadline, ok := ctx.Deadline(); ok && now.Add(wait).After(deadline) {
+		l.release(reserved)
+		l.mu.Unlock()
+		for _, kind := range kinds {
+			metrics.RateLimitRejections.Inc(kind)
+		}
+		return exhausted(names, wait)
+	}
+	for _, b := range reserved {
+		b.waiting++
+	}
+	l.mu.Unlock()
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	var err error
+	select {
+	case <-ctx.Done():
+		err = status.FromContextError(ctx.Err()).Err()
+	case <-timer.C:
+	}
+
+	l.mu.Lock()
+	for _, b := range reserved {
+		b.waiting--
+	}
+	if err != nil {
+		l.release(reserved)
+	}
+	l.mu.Unlock()
+	for _, kind := range kinds {
+		metrics.RateLimitWait.Observe(wait.Seconds(), kind)
+	}
+	return err
+}
+
+func (l *Limiters) release(reserved []*bucket) {
+	for _, b := range reserved {
+		b.tokens = math.Min(b.tokens+1, float64(b.limit.Burst))
+	}
+}
+
+func exhausted(names []string, wait time.Duration) error {
+	st := status.Newf(codes.ResourceExhausted, "rate limited by %s: capacity in %s comes after the deadline", strings.Join(names, ", "), wait.Round(time.Millisecond))
+	if d, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(wait)}); err == nil {
+		st = d
+	}
+	return st.Err()
+}
+
+// State is a snapshot of one bucket.
+type State struct {
+	Kind    string
+	Key     string
+	Limit   Limit
+	Tokens  float64 // negative while callers wait
+	Waiting int
+}
+
+// States returns a snapshot of every bucket in use.
+func (l *Limiters) States() []State {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	out := make([]State, 0, len(l.buckets))
+	for name, b := range l.buckets {
+		b.advance(now)
+		kind, key := splitName(name)
+		out = append(out, State{Kind: kind, Key: key, Limit: b.limit, Tokens: b.tokens, Waiting: b.waiting})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Kind != out[j].Kind {
+			return out[i].Kind < out[j].Kind
+		}
+		return out[i].Key < out[j].Key
+	})
+	return out
+}
+
+type accountKey struct{}
+
+// WithAccount attributes the platform calls made with ctx to an account.
+func WithAccount(ctx context.Context, account string) context.Context {
+	return context.WithValue(ctx, accountKey{}, account)
+}
+
+func accountFromContext(ctx context.Context) string {
+	a, _ := ctx.Value(accountKey{}).(string)
+	return a
+}
+
+// ProviderInterceptor limits AI calls by the request's model provider.
+func (l *Limiters) ProviderInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if err := l.Wait(ctx, Key{KindProvider, modelProvider(req)}); err != nil {
+			return err
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// PlatformInterceptor limits platform API calls by the request's platform
+// and the account the call is made for.
+func (l *Limiters) PlatformInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		var platform string
+		if r, ok := req.(interface{ GetPlatform() string }); ok {
+			platform = r.GetPlatform()
+		}
+		if err := l.Wait(ctx, Key{KindPlatform, platform}, Key{KindAccount, accountFromContext(ctx)}); err != nil {
+			return err
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+func modelProvider(req interface{}) string {
+	if r, ok := req.(interface{ GetModelProvider() string }); ok {
+		return r.GetModelProvider()
+	}
+	return ""
+}
@@ -0,0 +1,170 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Optiq-CTO/orchestrator/internal/retry"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{"zero", Config{}, false},
+		{"valid", Config{Providers: map[string]Limit{"gemini": {PerMinute: 15, Burst: 3}}}, false},
+		{"no rate", Config{Platforms: map[string]Limit{"twitter": {Burst: 1}}}, true},
+		{"no burst", Config{Accounts: map[string]Limit{"default": {PerMinute: 6}}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.cfg.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func newTestLimiters(cfg Config) (*Limiters, *time.Time) {
+	// Wait compares the clock against real context deadlines.
+	now := time.Now()
+	l := New(cfg)
+	l.now = func() time.Time { return now }
+	return l, &now
+}
+
+// deadline returns a context whose deadline is already near, so Wait fails
+// instead of sleeping whenever a bucket is empty.
+func deadline(t *testing.T) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+func TestWait(t *testing.T) {
+	cfg := Config{
+		Providers: map[string]Limit{"gemini": {PerMinute: 60, Burst: 2}},
+		Accounts:  map[string]Limit{DefaultKey: {PerMinute: 1, Burst: 1}},
+	}
+	tests := []struct {
+		name    string
+		advance time.Duration
+		keys    []Key
+		wantErr bool
+	}{
+		{"burst 1", 0, []Key{{KindProvider, "gemini"}}, false},
+		{"burst 2", 0, []Key{{KindProvider, "gemini"}}, false},
+		{"empty", 0, []Key{{KindProvider, "gemini"}}, true},
+		{"refilled", time.Second, []Key{{KindProvider, "gemini"}}, false},
+		{"unlimited provider", 0, []Key{{KindProvider, "openai"}}, false},
+		{"unlimited platform", 0, []Key{{KindPlatform, "twitter"}}, false},
+		{"empty key", 0, []Key{{KindAccount, ""}}, false},
+		{"default account", 0, []Key{{KindAccount, "acme"}}, false},
+		{"account has its own bucket", 0, []Key{{KindAccount, "globex"}}, false},
+		{"account empty", 0, []Key{{KindAccount, "acme"}}, true},
+		{"any empty bucket blocks", time.Second, []Key{{KindProvider, "gemini"}, {KindAccount, "acme"}}, true},
+		{"tokens given back", 0, []Key{{KindProvider, "gemini"}}, false},
+	}
+	l, now := newTestLimiters(cfg)
+	for _, tt := range tests {
+		*now = now.Add(tt.advance)
+		err := l.Wait(deadline(t), tt.keys...)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("%s: Wait() = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+		if err != nil {
+			if status.Code(err) != codes.ResourceExhausted {
+				t.Errorf("%s: code = %v, want ResourceExhausted", tt.name, status.Code(err))
+			}
+			if _, ok := retry.Delay(err); !ok {
+				t.Errorf("%s: error has no retry delay", tt.name)
+			}
+		}
+	}
+}
+
+func TestWaitSleeps(t *testing.T) {
+	l := New(Config{Platforms: map[string]Limit{"twitter": {PerMinute: 6000, Burst: 1}}})
+	ctx := context.Background()
+	if err := l.Wait(ctx, Key{KindPlatform, "twitter"}); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	if err := l.Wait(ctx, Key{KindPlatform, "twitter"}); err != nil {
+		t.Fatal(err)
+	}
+	if waited := time.Since(start); waited < 5*time.Millisecond {
+		t.Errorf("waited %v, want about 10ms for the next token", waited)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	l, _ := newTestLimiters(Config{
+		Providers: map[string]Limit{"gemini": {PerMinute: 60, Burst: 5}},
+		Platforms: map[string]Limit{"twitter": {PerMinute: 60, Burst: 5}},
+	})
+	ctx := deadline(t)
+	l.Wait(ctx, Key{KindProvider, "gemini"}, Key{KindPlatform, "twitter"})
+	l.Update(Config{Providers: map[string]Limit{"gemini": {PerMinute: 30, Burst: 2}}})
+
+	states := l.States()
+	if len(states) != 1 {
+		t.Fatalf("States() = %+v, want only the gemini bucket", states)
+	}
+	if s := states[0]; s.Key != "gemini" || s.Limit.Burst != 2 || s.Tokens != 2 {
+		t.Errorf("gemini bucket = %+v, want the new limit and tokens capped at 2", s)
+	}
+}
+
+type providerReq struct{ provider string }
+
+func (r providerReq) GetModelProvider() string { return r.provider }
+
+type platformReq struct{ platform string }
+
+func (r platformReq) GetPlatform() string { return r.platform }
+
+func TestInterceptors(t *testing.T) {
+	l, _ := newTestLimiters(Config{
+		Providers: map[string]Limit{"gemini": {PerMinute: 1, Burst: 1}},
+		Accounts:  map[string]Limit{"acme": {PerMinute: 1, Burst: 1}},
+	})
+	calls := 0
+	invoker := func(context.Context, string, interface{}, interface{}, *grpc.ClientConn, ...grpc.CallOption) error {
+		calls++
+		return nil
+	}
+	provider := l.ProviderInterceptor()
+	platform := l.PlatformInterceptor()
+	acme := WithAccount(deadline(t), "acme")
+	tests := []struct {
+		name    string
+		call    func() error
+		wantErr bool
+	}{
+		{"gemini", func() error { return provider(deadline(t), "/m", providerReq{"gemini"}, nil, nil, invoker) }, false},
+		{"gemini again", func() error { return provider(deadline(t), "/m", providerReq{"gemini"}, nil, nil, invoker) }, true},
+		{"acme", func() error { return platform(acme, "/m", platformReq{"twitter"}, nil, nil, invoker) }, false},
+		{"acme again", func() error { return platform(acme, "/m", platformReq{"reddit"}, nil, nil, invoker) }, true},
+		{"no account", func() error { return platform(deadline(t), "/m", platformReq{"twitter"}, nil, nil, invoker) }, false},
+	}
+	want := 0
+	for _, tt := range tests {
+		err := tt.call()
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("%s: err = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+		if err == nil {
+			want++
+		}
+		if calls != want {
+			t.Fatalf("%s: downstream called %d times, want %d", tt.name, calls, want)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package redact
+
+import (
+	"context"
+	"log/slog"
+)
+
+// Handler is a slog.Handler that scrubs credentials from the message and
+// attributes of every record before passing it on.
+type Handler struct {
+	next slog.Handler
+}
+
+// NewHandler wraps next with redaction.
+func NewHandler(next slog.Handler) *Handler {
+	return &Handler{next: next}
+}
+
+// Enabled implements slog.Handler.
+func (h *Handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+// Handle implements slog.Handler.
+func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	out := slog.NewRecord(r.Time, r.Level, String(r.Message), r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		out.AddAttrs(Attr(a))
+		return true
+	})
+	return h.next.Handle(ctx, out)
+}
+
+// WithAttrs implements slog.Handler.
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	scrubbed := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		scrubbed[i] = Attr(a)
+	}
+	return &Handler{next: h.next.WithAttrs(scrubbed)}
+}
+
+// WithGroup implements slog.Handler.
+func (h *Handler) WithGroup(name string) slog.Handler {
+	return &Handler{next: h.next.WithGroup(name)}
+}
+
+// Attr scrubs a single log attribute, descending into groups.
+func Attr(a slog.Attr) slog.Attr {
+	v := a.Value.Resolve()
+	switch v.Kind() {
+	case slog.KindGroup:
+		group := v.Group()
+		scrubbed := make([]slog.Attr, len(group))
+		for i, ga := range group {
+			scrubbed[i] = Attr(ga)
+		}
+		return slog.Attr{Key: a.Key, Value: slog.GroupValue(scrubbed...)}
+	case slog.KindString:
+		return slog.String(a.Key, Value(a.Key, v.String()))
+	case slog.KindAny:
+		switch x := v.Any().(type) {
+		case error:
+			return slog.String(a.Key, String(x.Error()))
+		case map[string]string:
+			return slog.Any(a.Key, Params(x))
+		}
+	}
+	if IsSensitiveKey(a.Key) {
+		return slog.String(a.Key, Placeholder)
+	}
+	return slog.Attr{Key: a.Key, Value: v}
+}
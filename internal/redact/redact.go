@@ -0,0 +1,130 @@
+// Package redact scrubs credentials from log output, errors and stored run
+// records. Flows carry platform tokens in their params, and downstream errors
+// sometimes echo request URLs or payloads back, so every value that leaves the
+// process passes through this package first.
+package redact
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"google.golang.org/grpc/status"
+)
+
+// Placeholder replaces every scrubbed value.
+const Placeholder = "[REDACTED]"
+
+// sensitiveKeys are the param and credential keys known to carry secrets.
+var sensitiveKeys = map[string]bool{
+	"access_token":                true,
+	"app_secret":                  true,
+	"client_secret":               true,
+	"refresh_token":               true,
+	"twitter_bearer_token":        true,
+	"twitter_api_key":             true,
+	"twitter_api_secret":          true,
+	"twitter_access_token":        true,
+	"twitter_access_token_secret": true,
+	"token":                       true,
+	"api_key":                     true,
+	"password":                    true,
+	"authorization":               true,
+}
+
+// sensitiveFragments catch keys that are not listed explicitly but are
+// obviously credentials, e.g. "openai_api_key". Tokens are matched as a
+// suffix instead, e.g. "page_access_token", so token counts such as
+// "prompt_tokens" stay readable.
+var sensitiveFragments = []string{"secret", "password", "api_key", "apikey", "credential"}
+
+// IsSensitiveKey reports whether values stored under key must never be logged.
+func IsSensitiveKey(key string) bool {
+	k := strings.ToLower(key)
+	if sensitiveKeys[k] || strings.HasSuffix(k, "token") {
+		return true
+	}
+	for _, f := range sensitiveFragments {
+		if strings.Contains(k, f) {
+			return true
+		}
+	}
+	return false
+}
+
+// tokenPatterns match credential-shaped values regardless of where they
+// appear in a string.
+var tokenPatterns = []*regexp.Regexp{
+	// Meta Graph API user and page tokens.
+	regexp.MustCompile(`EAA[A-Za-z0-9]{20,}`),
+	// X app-only bearer tokens.
+	regexp.MustCompile(`AAAAAAAAAAAAAAAAAAAAA[A-Za-z0-9%]{20,}`),
+	// Google API keys (Gemini).
+	regexp.MustCompile(`AIza[0-9A-Za-z_\-]{30,}`),
+	// OpenAI keys.
+	regexp.MustCompile(`sk-[A-Za-z0-9_\-]{20,}`),
+	// JWTs.
+	regexp.MustCompile(`eyJ[A-Za-z0-9_\-]+\.eyJ[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]+`),
+	// Authorization headers.
+	regexp.MustCompile(`(?i)(bearer|oauth)\s+[A-Za-z0-9._~+/=%\-]{8,}`),
+}
+
+// keyValuePattern matches sensitive keys followed by a value in query
+// strings, form bodies, JSON and Go map formatting.
+var keyValuePattern = regexp.MustCompile(`(?i)("?[a-z_]*(?:token|secret|password|api_key)"?\s*[:=]\s*"?)([^"&\s,}\]]+)`)
+
+// String scrubs credential-shaped substrings from s.
+func String(s string) string {
+	if s == "" {
+		return s
+	}
+	s = keyValuePattern.ReplaceAllString(s, "${1}"+Placeholder)
+	for _, p := range tokenPatterns {
+		s = p.ReplaceAllString(s, Placeholder)
+	}
+	return s
+}
+
+// Value scrubs a single value stored under key. Secret references
+// (secret://account/key) carry no credential and are kept as-is.
+func Value(key, value string) string {
+	if value == "" || strings.HasPrefix(value, "secret://") {
+		return value
+	}
+	if IsSensitiveKey(key) {
+		return Placeholder
+	}
+	return String(value)
+}
+
+// Params returns a copy of params with sensitive values replaced.
+func Params(params map[string]string) map[string]string {
+	if params == nil {
+		return nil
+	}
+	out := make(map[string]string, len(params))
+	for k, v := range params {
+		out[k] = Value(k, v)
+	}
+	return out
+}
+
+// Error returns err with credentials scrubbed from its message. gRPC status
+// codes are preserved so callers can still classify the failure.
+func Error(err error) error {
+	if err == nil {
+		return nil
+	}
+	if st, ok := status.FromError(err); ok {
+		msg := String(st.Message())
+		if msg == st.Message() {
+			return err
+		}
+		return status.Error(st.Code(), msg)
+	}
+	msg := String(err.Error())
+	if msg == err.Error() {
+		return err
+	}
+	return errors.New(msg)
+}
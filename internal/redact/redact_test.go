@@ -0,0 +1,126 @@
+package redact
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestIsSensitiveKey(t *testing.T) {
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{"access_token", true},
+		{"Page_Access_Token", true},
+		{"twitter_api_secret", true},
+		{"openai_api_key", true},
+		{"db_password", true},
+		{"credentials", true},
+		{"prompt_tokens", false},
+		{"max_tokens", false},
+		{"page_id", false},
+		{"target_account", false},
+	}
+	for _, tt := range tests {
+		if got := IsSensitiveKey(tt.key); got != tt.want {
+			t.Errorf("IsSensitiveKey(%q) = %v, want %v", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"empty", "", ""},
+		{"plain", "fetch failed: not found", "fetch failed: not found"},
+		{"query string", "GET /me?access_token=abc123&fields=id", "GET /me?access_token=" + Placeholder + "&fields=id"},
+		{"json", `{"client_secret": "s3cr3t"}`, `{"client_secret": "` + Placeholder + `"}`},
+		{"meta token", "token EAA" + strings.Repeat("x", 30) + " rejected", "token " + Placeholder + " rejected"},
+		{"openai key", "key sk-" + strings.Repeat("a", 24), "key " + Placeholder},
+		{"bearer header", "Authorization: Bearer abcdefghijkl", "Authorization: " + Placeholder},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := String(tt.in); got != tt.want {
+				t.Errorf("String(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValue(t *testing.T) {
+	tests := []struct {
+		key, value, want string
+	}{
+		{"access_token", "abc", Placeholder},
+		{"access_token", "secret://acme/access_token", "secret://acme/access_token"},
+		{"access_token", "", ""},
+		{"page_id", "12345", "12345"},
+		{"note", "sk-" + strings.Repeat("b", 24), Placeholder},
+	}
+	for _, tt := range tests {
+		if got := Value(tt.key, tt.value); got != tt.want {
+			t.Errorf("Value(%q, %q) = %q, want %q", tt.key, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestParams(t *testing.T) {
+	if Params(nil) != nil {
+		t.Error("Params(nil) is not nil")
+	}
+	in := map[string]string{"access_token": "abc", "page_id": "1"}
+	got := Params(in)
+	if got["access_token"] != Placeholder || got["page_id"] != "1" {
+		t.Errorf("Params = %v", got)
+	}
+	if in["access_token"] != "abc" {
+		t.Error("Params modified its argument")
+	}
+}
+
+func TestError(t *testing.T) {
+	if Error(nil) != nil {
+		t.Error("Error(nil) is not nil")
+	}
+	plain := errors.New("not found")
+	if Error(plain) != plain {
+		t.Error("an error without credentials was replaced")
+	}
+	err := Error(status.Error(codes.Unauthenticated, "bad token=abc123"))
+	if status.Code(err) != codes.Unauthenticated {
+		t.Errorf("code = %v, want Unauthenticated", status.Code(err))
+	}
+	if strings.Contains(err.Error(), "abc123") {
+		t.Errorf("error still carries the token: %v", err)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewHandler(slog.NewTextHandler(&buf, nil)))
+	logger.With("client_secret", "s1").Info("calling with access_token=abc123",
+		"access_token", "t1",
+		"prompt_tokens", 42,
+		"params", map[string]string{"refresh_token": "r1"},
+		slog.Group("req", "api_key", "k1"),
+		"error", errors.New("password=p1"))
+	out := buf.String()
+	for _, leaked := range []string{"s1", "abc123", "t1", "r1", "k1", "p1"} {
+		if strings.Contains(out, "="+leaked) || strings.Contains(out, ":"+leaked) {
+			t.Errorf("log output leaks %q: %s", leaked, out)
+		}
+	}
+	if !strings.Contains(out, "prompt_tokens=42") {
+		t.Errorf("log output lost a token count: %s", out)
+	}
+}
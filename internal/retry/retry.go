@@ -0,0 +1,185 @@
+// Package retry retries failed downstream calls with exponential backoff
+// and jitter. Whether a failure is worth retrying is decided from its gRPC
+// status code by a Classifier; RetryInfo details sent by the downstream
+// override the computed backoff.
+package retry
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand/v2"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Policy is the retry policy of a pipeline step. The config completes step
+// policies from the default one, see Or.
+type Policy struct {
+	MaxAttempts    int           `yaml:"max_attempts"` // including the first
+	InitialBackoff time.Duration `yaml:"initial_backoff"`
+	MaxBackoff     time.Duration `yaml:"max_backoff"`
+	Multiplier     float64       `yaml:"multiplier"`
+	Jitter         float64       `yaml:"jitter"`          // fraction of the backoff, 0..1
+	AttemptTimeout time.Duration `yaml:"attempt_timeout"` // zero means none
+}
+
+// Or fills the zero fields of p from def.
+func (p Policy) Or(def Policy) Policy {
+	if p.MaxAttempts == 0 {
+		p.MaxAttempts = def.MaxAttempts
+	}
+	if p.InitialBackoff == 0 {
+		p.InitialBackoff = def.InitialBackoff
+	}
+	if p.MaxBackoff == 0 {
+		p.MaxBackoff = def.MaxBackoff
+	}
+	if p.Multiplier == 0 {
+		p.Multiplier = def.Multiplier
+	}
+	if p.Jitter == 0 {
+		p.Jitter = def.Jitter
+	}
+	if p.AttemptTimeout == 0 {
+		p.AttemptTimeout = def.AttemptTimeout
+	}
+	return p
+}
+
+// Validate rejects policies that could not run. Zero fields are allowed:
+// they make one attempt, retry at once or set no timeout.
+func (p Policy) Validate() error {
+	switch {
+	case p.MaxAttempts < 0:
+		return errors.New("max_attempts must not be negative")
+	case p.InitialBackoff < 0 || p.MaxBackoff < 0 || p.AttemptTimeout < 0:
+		return errors.New("durations must not be negative")
+	case p.MaxBackoff > 0 && p.InitialBackoff > p.MaxBackoff:
+		return errors.New("initial_backoff must not exceed max_backoff")
+	case p.Multiplier != 0 && p.Multiplier < 1:
+		return errors.New("multiplier must be at least 1")
+	case p.Jitter < 0 || p.Jitter > 1:
+		return errors.New("jitter must be between 0 and 1")
+	}
+	return nil
+}
+
+// Classifier reports whether a failed attempt may be retried.
+type Classifier func(err error) bool
+
+// Transient retries the gRPC codes that signal a temporary condition.
+// Everything else, including errors without a status, is terminal.
+func Transient(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.ResourceExhausted, codes.Aborted, codes.DeadlineExceeded:
+		return true
+	}
+	return false
+}
+
+// BeforeCreate is the classifier for steps that create something, such as
+// a post, and must not run twice. It retries only failures that provably
+// happened before anything was created: calls that never left the process
+// (see NotSent), and ResourceExhausted rejections, which the publisher
+// returns when a platform rate limits a post instead of creating it.
+func BeforeCreate(err error) bool {
+	return IsNotSent(err) || status.Code(err) == codes.ResourceExhausted
+}
+
+type notSentError struct{ err error }
+
+func (e *notSentError) Error() string { return e.err.Error() }
+func (e *notSentError) Unwrap() error { return e.err }
+
+// GRPCStatus keeps the status of the wrapped error visible to
+// status.FromError and status.Code.
+func (e *notSentError) GRPCStatus() *status.Status { return status.Convert(e.err) }
+
+// NotSent marks err as a failure that happened before the request was
+// handed to a transport, so the downstream never saw it.
+func NotSent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &notSentError{err}
+}
+
+// IsNotSent reports whether err was marked with NotSent.
+func IsNotSent(err error) bool {
+	var ns *notSentError
+	return errors.As(err, &ns)
+}
+
+// Delay returns the retry delay a downstream asked for in a RetryInfo
+// detail, if any.
+func Delay(err error) (time.Duration, bool) {
+	st, ok := status.FromError(err)
+	if !ok {
+		return 0, false
+	}
+	for _, d := range st.Details() {
+		if ri, ok := d.(*errdetails.RetryInfo); ok && ri.RetryDelay != nil {
+			return ri.RetryDelay.AsDuration(), true
+		}
+	}
+	return 0, false
+}
+
+// Do calls fn until it succeeds, fails with an error retryable rejects,
+// runs out of attempts or ctx is done. Each attempt gets its own timeout.
+// A RetryInfo delay replaces the computed backoff; one longer than the
+// policy's MaxBackoff ends the retries, since waiting that long would stall
+// the run. onRetry, if set, is called before each wait. The last error is
+// returned unchanged.
+func Do[T any](ctx context.Context, p Policy, retryable Classifier, onRetry func(attempt int, err error, wait time.Duration), fn func(ctx context.Context) (T, error)) (T, error) {
+	attempts := max(p.MaxAttempts, 1)
+	for attempt := 1; ; attempt++ {
+		res, err := call(ctx, p.AttemptTimeout, fn)
+		if err == nil || attempt >= attempts || ctx.Err() != nil || !retryable(err) {
+			return res, err
+		}
+		wait := p.backoff(attempt)
+		if d, ok := Delay(err); ok {
+			if p.MaxBackoff > 0 && d > p.MaxBackoff {
+				return res, err
+			}
+			wait = d
+		}
+		if onRetry != nil {
+			onRetry(attempt, err, wait)
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return res, err
+		case <-timer.C:
+		}
+	}
+}
+
+func call[T any](ctx context.Context, timeout time.Duration, fn func(ctx context.Context) (T, error)) (T, error) {
+	if timeout <= 0 {
+		return fn(ctx)
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return fn(ctx)
+}
+
+// backoff returns the wait after the given failed attempt.
+func (p Policy) backoff(attempt int) time.Duration {
+	mult := math.Max(p.Multiplier, 1)
+	d := float64(p.InitialBackoff) * math.Pow(mult, float64(attempt-1))
+	if p.MaxBackoff > 0 {
+		d = math.Min(d, float64(p.MaxBackoff))
+	}
+	if p.Jitter > 0 {
+		d *= 1 + p.Jitter*(2*rand.Float64()-1)
+	}
+	return time.Duration(d)
+}
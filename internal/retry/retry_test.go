@@ -0,0 +1,180 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func withDelay(code codes.Code, d time.Duration) error {
+	st, err := status.New(code, "slow down").WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(d)})
+	if err != nil {
+		panic(err)
+	}
+	return st.Err()
+}
+
+func TestOr(t *testing.T) {
+	def := Policy{MaxAttempts: 3, InitialBackoff: time.Second, MaxBackoff: 5 * time.Second, Multiplier: 2, Jitter: 0.2, AttemptTimeout: time.Minute}
+	got := Policy{MaxAttempts: 5, AttemptTimeout: 2 * time.Minute}.Or(def)
+	want := Policy{MaxAttempts: 5, InitialBackoff: time.Second, MaxBackoff: 5 * time.Second, Multiplier: 2, Jitter: 0.2, AttemptTimeout: 2 * time.Minute}
+	if got != want {
+		t.Errorf("Or() = %+v, want %+v", got, want)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		p       Policy
+		wantErr bool
+	}{
+		{"zero", Policy{}, false},
+		{"typical", Policy{MaxAttempts: 3, InitialBackoff: time.Second, MaxBackoff: 5 * time.Second, Multiplier: 2, Jitter: 0.2}, false},
+		{"negative attempts", Policy{MaxAttempts: -1}, true},
+		{"negative duration", Policy{AttemptTimeout: -time.Second}, true},
+		{"initial over max", Policy{InitialBackoff: time.Minute, MaxBackoff: time.Second}, true},
+		{"shrinking multiplier", Policy{Multiplier: 0.5}, true},
+		{"jitter over 1", Policy{Jitter: 1.5}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.p.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestClassifiers(t *testing.T) {
+	tests := []struct {
+		name         string
+		err          error
+		transient    bool
+		beforeCreate bool
+	}{
+		{"unavailable", status.Error(codes.Unavailable, "down"), true, false},
+		{"deadline", status.Error(codes.DeadlineExceeded, "slow"), true, false},
+		{"exhausted", status.Error(codes.ResourceExhausted, "rate limited"), true, true},
+		{"invalid", status.Error(codes.InvalidArgument, "bad"), false, false},
+		{"plain", errors.New("boom"), false, false},
+		{"not sent", NotSent(status.Error(codes.Unavailable, "down")), true, true},
+		{"wrapped not sent", fmt.Errorf("publish: %w", NotSent(errors.New("no conn"))), false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Transient(tt.err); got != tt.transient {
+				t.Errorf("Transient() = %v, want %v", got, tt.transient)
+			}
+			if got := BeforeCreate(tt.err); got != tt.beforeCreate {
+				t.Errorf("BeforeCreate() = %v, want %v", got, tt.beforeCreate)
+			}
+		})
+	}
+	if NotSent(nil) != nil {
+		t.Error("NotSent(nil) != nil")
+	}
+	if status.Code(NotSent(status.Error(codes.Unavailable, "down"))) != codes.Unavailable {
+		t.Error("NotSent hides the status code")
+	}
+}
+
+func TestDelay(t *testing.T) {
+	if d, ok := Delay(withDelay(codes.ResourceExhausted, 3*time.Second)); !ok || d != 3*time.Second {
+		t.Errorf("Delay() = %v, %v", d, ok)
+	}
+	if _, ok := Delay(status.Error(codes.Unavailable, "down")); ok {
+		t.Error("Delay() found a delay in a status without RetryInfo")
+	}
+	if _, ok := Delay(errors.New("boom")); ok {
+		t.Error("Delay() found a delay in a plain error")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	p := Policy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second, Multiplier: 3}
+	for attempt, want := range map[int]time.Duration{1: 100 * time.Millisecond, 2: 300 * time.Millisecond, 3: 900 * time.Millisecond, 4: time.Second} {
+		if got := p.backoff(attempt); got != want {
+			t.Errorf("backoff(%d) = %v, want %v", attempt, got, want)
+		}
+	}
+	p.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if got := p.backoff(1); got < 50*time.Millisecond || got > 150*time.Millisecond {
+			t.Fatalf("backoff(1) with jitter = %v, want within 50%% of 100ms", got)
+		}
+	}
+}
+
+func TestDo(t *testing.T) {
+	unavailable := status.Error(codes.Unavailable, "down")
+	tests := []struct {
+		name         string
+		policy       Policy
+		errs         []error // per attempt; nil succeeds
+		wantAttempts int
+		wantErr      bool
+	}{
+		{"first try", Policy{MaxAttempts: 3}, []error{nil}, 1, false},
+		{"recovers", Policy{MaxAttempts: 3}, []error{unavailable, unavailable, nil}, 3, false},
+		{"runs out", Policy{MaxAttempts: 2}, []error{unavailable, unavailable, nil}, 2, true},
+		{"zero attempts tries once", Policy{}, []error{unavailable, nil}, 1, true},
+		{"terminal", Policy{MaxAttempts: 3}, []error{status.Error(codes.InvalidArgument, "bad"), nil}, 1, true},
+		{"short delay honored", Policy{MaxAttempts: 2, MaxBackoff: time.Second}, []error{withDelay(codes.Unavailable, time.Millisecond), nil}, 2, false},
+		{"long delay gives up", Policy{MaxAttempts: 3, MaxBackoff: time.Millisecond}, []error{withDelay(codes.Unavailable, time.Hour), nil}, 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts, retries := 0, 0
+			got, err := Do(context.Background(), tt.policy, Transient,
+				func(int, error, time.Duration) { retries++ },
+				func(context.Context) (int, error) {
+					attempts++
+					return attempts, tt.errs[attempts-1]
+				})
+			if attempts != tt.wantAttempts || retries != attempts-1 {
+				t.Errorf("attempts = %d, retries = %d, want %d attempts", attempts, retries, tt.wantAttempts)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Do() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != attempts {
+				t.Errorf("Do() = %d, want the last attempt's result %d", got, attempts)
+			}
+		})
+	}
+}
+
+func TestDoAttemptTimeout(t *testing.T) {
+	attempts := 0
+	_, err := Do(context.Background(), Policy{MaxAttempts: 2, AttemptTimeout: 10 * time.Millisecond}, Transient, nil,
+		func(ctx context.Context) (struct{}, error) {
+			attempts++
+			<-ctx.Done()
+			return struct{}{}, status.FromContextError(ctx.Err()).Err()
+		})
+	if attempts != 2 || status.Code(err) != codes.DeadlineExceeded {
+		t.Errorf("attempts = %d, err = %v; want 2 timed-out attempts", attempts, err)
+	}
+}
+
+func TestDoStopsWhenCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	attempts := 0
+	_, err := Do(ctx, Policy{MaxAttempts: 5, InitialBackoff: time.Hour}, Transient,
+		func(int, error, time.Duration) { cancel() },
+		func(context.Context) (int, error) {
+			attempts++
+			return 0, status.Error(codes.Unavailable, "down")
+		})
+	if attempts != 1 || err == nil {
+		t.Errorf("attempts = %d, err = %v; want one attempt and its error", attempts, err)
+	}
+}
@@ -0,0 +1,187 @@
+// Package routing decides which model providers a run may use and in what
+// order, so generation can fail over when a provider is rate limited or
+// down.
+package routing
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"os"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the routing section of the server configuration.
+//
+//	routing:
+//	  - name: default
+//	    providers: [gemini, openai]
+//	  - name: acme-split
+//	    tenant: acme
+//	    flow: facebook_echo
+//	    providers: [gemini, openai]
+//	    weights: {gemini: 80, openai: 20}
+type Config struct {
+	Policies []Policy `yaml:"routing"`
+}
+
+// Policy is an ordered fallback list for runs matching its scope. Empty
+// scope fields match everything; the most specific matching policy wins
+// (account, then tenant, then flow), with ties going to the first listed.
+//
+// Weights, when set, split runs that did not ask for a provider: the first
+// provider is drawn by weight and the rest follow in list order.
+type Policy struct {
+	Name      string         `yaml:"name"`
+	Tenant    string         `yaml:"tenant"`
+	Account   string         `yaml:"account"`
+	Flow      string         `yaml:"flow"`
+	Providers []string       `yaml:"providers"`
+	Weights   map[string]int `yaml:"weights"`
+}
+
+func (p *Policy) matches(tenant, account, flow string) bool {
+	return (p.Tenant == "" || p.Tenant == tenant) &&
+		(p.Account == "" || p.Account == account) &&
+		(p.Flow == "" || p.Flow == flow)
+}
+
+func (p *Policy) specificity() int {
+	n := 0
+	if p.Account != "" {
+		n += 4
+	}
+	if p.Tenant != "" {
+		n += 2
+	}
+	if p.Flow != "" {
+		n++
+	}
+	return n
+}
+
+// LoadConfig reads and validates a routing file.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading routing file: %w", err)
+	}
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parsing routing file: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// Validate checks every policy.
+func (c *Config) Validate() error {
+	names := make(map[string]bool)
+	for i, p := range c.Policies {
+		if p.Name == "" {
+			return fmt.Errorf("routing[%d]: name is required", i)
+		}
+		if names[p.Name] {
+			return fmt.Errorf("duplicate routing policy %q", p.Name)
+		}
+		names[p.Name] = true
+		if len(p.Providers) == 0 {
+			return fmt.Errorf("routing policy %q: providers is required", p.Name)
+		}
+		seen := make(map[string]bool)
+		for _, prov := range p.Providers {
+			if prov == "" || seen[prov] {
+				return fmt.Errorf("routing policy %q: providers must be distinct and non-empty", p.Name)
+			}
+			seen[prov] = true
+		}
+		total := 0
+		for prov, w := range p.Weights {
+			if !seen[prov] {
+				return fmt.Errorf("routing policy %q: weight for %s, which is not in providers", p.Name, prov)
+			}
+			if w < 0 {
+				return fmt.Errorf("routing policy %q: negative weight for %s", p.Name, prov)
+			}
+			total += w
+		}
+		if len(p.Weights) > 0 && total == 0 {
+			return fmt.Errorf("routing policy %q: weights must not all be zero", p.Name)
+		}
+	}
+	return nil
+}
+
+// Router picks provider order for runs.
+type Router struct {
+	policies []Policy
+	intn     func(int) int
+}
+
+// NewRouter returns a router over validated policies.
+func NewRouter(policies []Policy) *Router {
+	return &Router{policies: policies, intn: rand.IntN}
+}
+
+// Plan returns the providers to try, in order, for a run. A requested
+// provider always goes first; without a matching policy it is the only one.
+func (r *Router) Plan(tenant, account, flow, requested string) []string {
+	p := r.match(tenant, account, flow)
+	if p == nil {
+		return []string{requested}
+	}
+	first := requested
+	if first == "" {
+		first = r.pick(p)
+	}
+	plan := []string{first}
+	for _, prov := range p.Providers {
+		if prov != first {
+			plan = append(plan, prov)
+		}
+	}
+	return plan
+}
+
+func (r *Router) match(tenant, account, flow string) *Policy {
+	var best *Policy
+	for i := range r.policies {
+		p := &r.policies[i]
+		if p.matches(tenant, account, flow) && (best == nil || p.specificity() > best.specificity()) {
+			best = p
+		}
+	}
+	return best
+}
+
+func (r *Router) pick(p *Policy) string {
+	total := 0
+	for _, prov := range p.Providers {
+		total += p.Weights[prov]
+	}
+	if total == 0 {
+		return p.Providers[0]
+	}
+	n := r.intn(total)
+	for _, prov := range p.Providers {
+		if n < p.Weights[prov] {
+			return prov
+		}
+		n -= p.Weights[prov]
+	}
+	return p.Providers[0]
+}
+
+// ShouldFailover reports whether a generation error means the next provider
+// should be tried: the provider is rate limited or unavailable.
+func ShouldFailover(err error) bool {
+	switch status.Code(err) {
+	case codes.ResourceExhausted, codes.Unavailable:
+		return true
+	}
+	return false
+}
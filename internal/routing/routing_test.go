@@ -0,0 +1,113 @@
+package routing
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  Policy
+		wantErr string
+	}{
+		{"valid", Policy{Name: "p", Providers: []string{"gemini", "openai"}, Weights: map[string]int{"gemini": 1}}, ""},
+		{"no name", Policy{Providers: []string{"gemini"}}, "name is required"},
+		{"no providers", Policy{Name: "p"}, "providers is required"},
+		{"repeated provider", Policy{Name: "p", Providers: []string{"gemini", "gemini"}}, "distinct"},
+		{"weight for unlisted provider", Policy{Name: "p", Providers: []string{"gemini"}, Weights: map[string]int{"openai": 1}}, "not in providers"},
+		{"negative weight", Policy{Name: "p", Providers: []string{"gemini"}, Weights: map[string]int{"gemini": -1}}, "negative weight"},
+		{"all zero weights", Policy{Name: "p", Providers: []string{"gemini"}, Weights: map[string]int{"gemini": 0}}, "all be zero"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := (&Config{Policies: []Policy{tt.policy}}).Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Validate = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPlan(t *testing.T) {
+	r := NewRouter([]Policy{
+		{Name: "default", Providers: []string{"gemini", "openai"}},
+		{Name: "flow", Flow: "twitter_echo", Providers: []string{"openai", "anthropic"}},
+		{Name: "tenant", Tenant: "acme", Providers: []string{"anthropic", "gemini"}},
+		{Name: "account", Account: "vip", Providers: []string{"openai"}},
+	})
+	tests := []struct {
+		name                             string
+		tenant, account, flow, requested string
+		want                             []string
+	}{
+		{"default policy", "", "", "facebook_echo", "", []string{"gemini", "openai"}},
+		{"requested goes first", "", "", "facebook_echo", "openai", []string{"openai", "gemini"}},
+		{"requested outside the policy", "", "", "facebook_echo", "local", []string{"local", "gemini", "openai"}},
+		{"flow beats default", "", "", "twitter_echo", "", []string{"openai", "anthropic"}},
+		{"tenant beats flow", "acme", "", "twitter_echo", "", []string{"anthropic", "gemini"}},
+		{"account beats tenant", "acme", "vip", "twitter_echo", "", []string{"openai"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := r.Plan(tt.tenant, tt.account, tt.flow, tt.requested); !slices.Equal(got, tt.want) {
+				t.Errorf("Plan = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if got := NewRouter(nil).Plan("", "", "x", "gemini"); !slices.Equal(got, []string{"gemini"}) {
+		t.Errorf("Plan without policies = %v", got)
+	}
+}
+
+func TestPlanWeights(t *testing.T) {
+	r := NewRouter([]Policy{{Name: "split", Providers: []string{"gemini", "openai"}, Weights: map[string]int{"gemini": 80, "openai": 20}}})
+	tests := []struct {
+		draw int
+		want string
+	}{
+		{0, "gemini"},
+		{79, "gemini"},
+		{80, "openai"},
+		{99, "openai"},
+	}
+	for _, tt := range tests {
+		r.intn = func(n int) int {
+			if n != 100 {
+				t.Fatalf("drew from %d, want 100", n)
+			}
+			return tt.draw
+		}
+		if got := r.Plan("", "", "x", ""); got[0] != tt.want || len(got) != 2 {
+			t.Errorf("draw %d: Plan = %v, want %s first", tt.draw, got, tt.want)
+		}
+	}
+}
+
+func TestShouldFailover(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{status.Error(codes.ResourceExhausted, "quota"), true},
+		{status.Error(codes.Unavailable, "down"), true},
+		{status.Error(codes.InvalidArgument, "bad prompt"), false},
+		{errors.New("plain"), false},
+	}
+	for _, tt := range tests {
+		if got := ShouldFailover(tt.err); got != tt.want {
+			t.Errorf("ShouldFailover(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package runs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// FileStore keeps one JSON file per run in a directory, so history survives
+// restarts without an external database.
+type FileStore struct {
+	mu  sync.Mutex
+	dir string
+}
+
+// NewFileStore returns a store rooted at dir, creating it if needed.
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("creating runs dir: %w", err)
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+func (f *FileStore) path(id string) string {
+	return filepath.Join(f.dir, filepath.Base(id)+".json")
+}
+
+// Save implements Store. Records are written to a temp file and renamed so a
+// crash never leaves a half-written record behind.
+func (f *FileStore) Save(_ context.Context, r *Record) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding run %s: %w", r.ID, err)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	tmp := f.path(r.ID) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("writing run %s: %w", r.ID, err)
+	}
+	if err := os.Rename(tmp, f.path(r.ID)); err != nil {
+		return fmt.Errorf("writing run %s: %w", r.ID, err)
+	}
+	return nil
+}
+
+// Get implements Store.
+func (f *FileStore) Get(_ context.Context, id string) (*Record, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.read(f.path(id))
+}
+
+func (f *FileStore) read(path string) (*Record, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading run: %w", err)
+	}
+	var r Record
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("decoding %s: %w", filepath.Base(path), err)
+	}
+	return &r, nil
+}
+
+// List implements Store.
+func (f *FileStore) List(_ context.Context, flt Filter) ([]*Record, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	entries, err := os.ReadDir(f.dir)
+	if err != nil {
+		return nil, fmt.Errorf("listing runs: %w", err)
+	}
+	var out []*Record
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		r, err := f.read(filepath.Join(f.dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if flt.match(r) {
+			out = append(out, r)
+		}
+	}
+	return limit(sortRecent(out), flt.Limit), nil
+}
@@ -0,0 +1,227 @@
+// Package runs stores a record of every pipeline run. Records are written
+// with their params already redacted; nothing in this package ever sees a
+// raw credential.
+package runs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Run statuses.
+const (
+	StatusRunning   = "running"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+	// StatusInterrupted marks runs cut short by a server shutdown; they can
+	// be resumed.
+	StatusInterrupted = "interrupted"
+)
+
+// ErrNotFound is returned by Get for unknown run IDs.
+var ErrNotFound = errors.New("run not found")
+
+// Record is the stored outcome of a single pipeline run.
+type Record struct {
+	ID            string            `json:"id"`
+	Flow          string            `json:"flow"`
+	TenantID      string            `json:"tenant_id,omitempty"`
+	AccountID     string            `json:"account_id,omitempty"`
+	User          string            `json:"user,omitempty"`
+	ModelProvider string            `json:"model_provider,omitempty"`
+	Priority      string            `json:"priority,omitempty"`
+	Params        map[string]string `json:"params,omitempty"`
+	Accounts      []string          `json:"accounts,omitempty"`
+	Status        string            `json:"status"`
+	Error         string            `json:"error,omitempty"`
+	OutputURLs    []string          `json:"output_urls,omitempty"`
+	Candidates    []Candidate       `json:"candidates,omitempty"`
+	Revisions     []Revision        `json:"revisions,omitempty"`
+	Steps         []string          `json:"steps,omitempty"`
+	Withheld      []Withheld        `json:"withheld,omitempty"`
+	Posts         []Post            `json:"posts,omitempty"`
+	ResumedFrom   string            `json:"resumed_from,omitempty"`
+	StartedAt     time.Time         `json:"started_at"`
+	FinishedAt    time.Time         `json:"finished_at,omitempty"`
+}
+
+// Candidate is one generated draft of a best-of-N run, kept for review
+// whether or not it was selected.
+type Candidate struct {
+	Provider  string   `json:"model_provider"`
+	Content   string   `json:"content,omitempty"`
+	Score     float64  `json:"score"`
+	RiskScore float32  `json:"risk_score,omitempty"`
+	Sentiment string   `json:"sentiment,omitempty"`
+	Issues    []string `json:"issues,omitempty"`
+	Selected  bool     `json:"selected,omitempty"`
+	Error     string   `json:"error,omitempty"`
+}
+
+// Revision is one iteration of a critique-and-revise loop: the draft as it
+// stood and the critique it received. Iteration 0 is the generated draft.
+type Revision struct {
+	Iteration int     `json:"iteration"`
+	Provider  string  `json:"model_provider,omitempty"`
+	Content   string  `json:"content,omitempty"`
+	Critic    string  `json:"critic"`
+	Score     float64 `json:"score"`
+	Feedback  string  `json:"feedback,omitempty"`
+	Selected  bool    `json:"selected,omitempty"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Post statuses.
+const (
+	PostPublished    = "published"
+	PostDeleted      = "deleted"
+	PostDeleteFailed = "delete_failed"
+)
+
+// Post is a post a run published. Its credentials are kept so it can be
+// deleted again: secret references as they are, plain values as empty
+// strings, to be supplied again.
+type Post struct {
+	Platform    string            `json:"platform"`
+	PostID      string            `json:"post_id"`
+	PostURL     string            `json:"post_url,omitempty"`
+	Credentials map[string]string `json:"credentials,omitempty"`
+	Status      string            `json:"status"`
+	Error       string            `json:"error,omitempty"`
+}
+
+// Withheld is a post a publishing policy, pause or open circuit breaker kept
+// from going out: deferred to the outbox, held there for approval, dropped
+// or discarded.
+type Withheld struct {
+	Platform  string    `json:"platform"`
+	Action    string    `json:"action"` // "defer" or "drop" by policy, "defer" by a breaker, "hold" or "discard" by a pause
+	Reason    string    `json:"reason"`
+	OutboxID  string    `json:"outbox_id,omitempty"`
+	NotBefore time.Time `json:"not_before,omitempty"`
+}
+
+// Filter narrows List results. Zero fields match everything.
+type Filter struct {
+	Flow      string
+	User      string
+	Status    string
+	TenantID  string
+	AccountID string
+	Limit     int
+}
+
+func (f Filter) match(r *Record) bool {
+	return (f.Flow == "" || r.Flow == f.Flow) &&
+		(f.User == "" || r.User == f.User) &&
+		(f.Status == "" || r.Status == f.Status) &&
+		(f.TenantID == "" || r.TenantID == f.TenantID) &&
+		(f.AccountID == "" || r.AccountID == f.AccountID)
+}
+
+// Store persists run records.
+type Store interface {
+	// Save creates or replaces the record with r.ID.
+	Save(ctx context.Context, r *Record) error
+	// Get returns the record with the given ID or ErrNotFound.
+	Get(ctx context.Context, id string) (*Record, error)
+	// List returns matching records, most recent first.
+	List(ctx context.Context, f Filter) ([]*Record, error)
+}
+
+// NewID returns a random run ID.
+func NewID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "run-" + time.Now().UTC().Format("20060102T150405.000000000")
+	}
+	return "run-" + hex.EncodeToString(b)
+}
+
+// MemoryStore keeps the most recent records in memory.
+type MemoryStore struct {
+	mu      sync.Mutex
+	max     int
+	records map[string]*Record
+	order   []string
+}
+
+// NewMemoryStore returns a store that retains at most max records; older
+// records are evicted first.
+func NewMemoryStore(max int) *MemoryStore {
+	return &MemoryStore{max: max, records: make(map[string]*Record)}
+}
+
+// Save implements Store.
+func (m *MemoryStore) Save(_ context.Context, r *Record) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.records[r.ID]; !ok {
+		m.order = append(m.order, r.ID)
+	}
+	m.records[r.ID] = clone(r)
+	for m.max > 0 && len(m.order) > m.max {
+		delete(m.records, m.order[0])
+		m.order = m.order[1:]
+	}
+	return nil
+}
+
+// Get implements Store.
+func (m *MemoryStore) Get(_ context.Context, id string) (*Record, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	r, ok := m.records[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return clone(r), nil
+}
+
+// List implements Store.
+func (m *MemoryStore) List(_ context.Context, f Filter) ([]*Record, error) {
+	m.mu.Lock()
+	var out []*Record
+	for _, r := range m.records {
+		if f.match(r) {
+			out = append(out, clone(r))
+		}
+	}
+	m.mu.Unlock()
+	return limit(sortRecent(out), f.Limit), nil
+}
+
+func sortRecent(rs []*Record) []*Record {
+	sort.Slice(rs, func(i, j int) bool { return rs[i].StartedAt.After(rs[j].StartedAt) })
+	return rs
+}
+
+func limit(rs []*Record, n int) []*Record {
+	if n > 0 && len(rs) > n {
+		return rs[:n]
+	}
+	return rs
+}
+
+func clone(r *Record) *Record {
+	c := *r
+	if r.Params != nil {
+		c.Params = make(map[string]string, len(r.Params))
+		for k, v := range r.Params {
+			c.Params[k] = v
+		}
+	}
+	c.Accounts = append([]string(nil), r.Accounts...)
+	c.OutputURLs = append([]string(nil), r.OutputURLs...)
+	c.Candidates = append([]Candidate(nil), r.Candidates...)
+	c.Revisions = append([]Revision(nil), r.Revisions...)
+	c.Steps = append([]string(nil), r.Steps...)
+	c.Withheld = append([]Withheld(nil), r.Withheld...)
+	c.Posts = append([]Post(nil), r.Posts...)
+	return &c
+}
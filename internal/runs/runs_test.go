@@ -0,0 +1,86 @@
+package runs
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func testStores(t *testing.T) map[string]Store {
+	fs, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return map[string]Store{"memory": NewMemoryStore(0), "file": fs}
+}
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	records := []*Record{
+		{ID: "r1", Flow: "twitter_echo", User: "u1", Status: StatusCompleted, StartedAt: t0},
+		{ID: "r2", Flow: "twitter_echo", User: "u2", Status: StatusFailed, StartedAt: t0.Add(time.Minute)},
+		{ID: "r3", Flow: "facebook_echo", User: "u1", Status: StatusCompleted, StartedAt: t0.Add(2 * time.Minute), Params: map[string]string{"page_id": "p"}},
+	}
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []string
+	}{
+		{"all, most recent first", Filter{}, []string{"r3", "r2", "r1"}},
+		{"by flow", Filter{Flow: "twitter_echo"}, []string{"r2", "r1"}},
+		{"by user and status", Filter{User: "u1", Status: StatusCompleted}, []string{"r3", "r1"}},
+		{"limited", Filter{Limit: 1}, []string{"r3"}},
+		{"no match", Filter{Flow: "cross_pollinator"}, nil},
+	}
+	for name, s := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			for _, r := range records {
+				if err := s.Save(ctx, r); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got, err := s.Get(ctx, "r3")
+			if err != nil || got.Params["page_id"] != "p" {
+				t.Fatalf("Get = %+v, %v", got, err)
+			}
+			got.Params["page_id"] = "changed"
+			if again, _ := s.Get(ctx, "r3"); again.Params["page_id"] != "p" {
+				t.Error("a returned record shares its params with the store")
+			}
+			if _, err := s.Get(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Get(missing) error = %v, want ErrNotFound", err)
+			}
+			for _, tt := range tests {
+				list, err := s.List(ctx, tt.filter)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var ids []string
+				for _, r := range list {
+					ids = append(ids, r.ID)
+				}
+				if !slices.Equal(ids, tt.want) {
+					t.Errorf("%s: List = %v, want %v", tt.name, ids, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestMemoryStoreEvictsOldest(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore(2)
+	for _, id := range []string{"a", "b", "c"} {
+		s.Save(ctx, &Record{ID: id})
+	}
+	s.Save(ctx, &Record{ID: "b", Status: StatusCompleted})
+	if _, err := s.Get(ctx, "a"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("oldest record was kept: %v", err)
+	}
+	if r, err := s.Get(ctx, "b"); err != nil || r.Status != StatusCompleted {
+		t.Errorf("Get(b) = %+v, %v", r, err)
+	}
+}
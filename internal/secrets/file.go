@@ -0,0 +1,264 @@
+package secrets
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Optiq-CTO/orchestrator/internal/statefile"
+)
+
+// MasterKeyEnv names the environment variable holding the base64-encoded
+// 32-byte key that unlocks the file store.
+const MasterKeyEnv = "ORCHESTRATOR_MASTER_KEY"
+
+// fileAAD binds ciphertexts to this file format so they cannot be swapped
+// with blobs encrypted for another purpose under the same key.
+var fileAAD = []byte("orchestrator-secrets-v1")
+
+type envelope struct {
+	Version    int    `json:"version"`
+	Nonce      string `json:"nonce"`
+	Ciphertext string `json:"ciphertext"`
+}
+
+type secretValue struct {
+	Value     string    `json:"value"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// FileStore keeps all secrets in a single AES-256-GCM encrypted file. The
+// whole file is decrypted on every call, under the file's lock, and
+// re-encrypted on every write, which is fine for the handful of accounts
+// one orchestrator manages and lets the processes on one host share it.
+type FileStore struct {
+	mu   sync.Mutex
+	path string
+	aead cipher.AEAD
+}
+
+// secrets are the decrypted contents of the file, by account and key.
+type secrets map[string]map[string]secretValue
+
+func (d secrets) remove(account, key string) {
+	delete(d[account], key)
+	if len(d[account]) == 0 {
+		delete(d, account)
+	}
+}
+
+// MasterKeyFromEnv decodes the master key from MasterKeyEnv.
+func MasterKeyFromEnv() ([]byte, error) {
+	raw := os.Getenv(MasterKeyEnv)
+	if raw == "" {
+		return nil, fmt.Errorf("%s is not set", MasterKeyEnv)
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(raw))
+	if err != nil {
+		return nil, fmt.Errorf("%s is not valid base64: %w", MasterKeyEnv, err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("%s must decode to 32 bytes, got %d", MasterKeyEnv, len(key))
+	}
+	return key, nil
+}
+
+// OpenFileStore opens (or creates on first write) the encrypted store at
+// path. It fails if the file exists but cannot be decrypted with key.
+func OpenFileStore(path string, key []byte) (*FileStore, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("creating cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("creating cipher: %w", err)
+	}
+	fs := &FileStore{path: path, aead: aead}
+	if err := fs.update(func(secrets) (bool, error) { return false, nil }); err != nil {
+		return nil, err
+	}
+	return fs, nil
+}
+
+// update decrypts the file under its lock, runs fn on its contents and
+// saves them if fn reports a change.
+func (f *FileStore) update(fn func(data secrets) (bool, error)) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	unlock, err := statefile.Lock(f.path)
+	if err != nil {
+		return fmt.Errorf("secrets file: %w", err)
+	}
+	defer unlock()
+	data, err := f.load()
+	if err != nil {
+		return err
+	}
+	changed, err := fn(data)
+	if err != nil || !changed {
+		return err
+	}
+	return f.save(data)
+}
+
+// load decrypts the file. Callers hold its lock.
+func (f *FileStore) load() (secrets, error) {
+	data := make(secrets)
+	raw, err := statefile.Read(f.path)
+	if err != nil {
+		return nil, fmt.Errorf("secrets file: %w", err)
+	}
+	if raw == nil {
+		return data, nil
+	}
+	var env envelope
+	if err := json.Unmarshal(raw, &env); err != nil {
+		return nil, fmt.Errorf("decoding secrets file: %w", err)
+	}
+	if env.Version != 1 {
+		return nil, fmt.Errorf("unsupported secrets file version %d", env.Version)
+	}
+	nonce, err := base64.StdEncoding.DecodeString(env.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("decoding secrets file nonce: %w", err)
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(env.Ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("decoding secrets file ciphertext: %w", err)
+	}
+	if len(nonce) != f.aead.NonceSize() {
+		return nil, errors.New("decoding secrets file: bad nonce size")
+	}
+	plain, err := f.aead.Open(nil, nonce, ciphertext, fileAAD)
+	if err != nil {
+		return nil, errors.New("decrypting secrets file: wrong master key or corrupted file")
+	}
+	if err := json.Unmarshal(plain, &data); err != nil {
+		return nil, fmt.Errorf("decoding secrets: %w", err)
+	}
+	return data, nil
+}
+
+// save encrypts data under a fresh nonce and atomically replaces the file.
+// Callers hold its lock.
+func (f *FileStore) save(data secrets) error {
+	plain, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("encoding secrets: %w", err)
+	}
+	nonce := make([]byte, f.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("generating nonce: %w", err)
+	}
+	out, err := json.Marshal(envelope{
+		Version:    1,
+		Nonce:      base64.StdEncoding.EncodeToString(nonce),
+		Ciphertext: base64.StdEncoding.EncodeToString(f.aead.Seal(nil, nonce, plain, fileAAD)),
+	})
+	if err != nil {
+		return fmt.Errorf("encoding secrets file: %w", err)
+	}
+	if err := statefile.Write(f.path, out); err != nil {
+		return fmt.Errorf("secrets file: %w", err)
+	}
+	return nil
+}
+
+// Get implements Store.
+func (f *FileStore) Get(_ context.Context, account, key string) (string, error) {
+	var (
+		v  secretValue
+		ok bool
+	)
+	if err := f.update(func(data secrets) (bool, error) {
+		v, ok = data[account][key]
+		return false, nil
+	}); err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrNotFound, Ref(account, key))
+	}
+	return v.Value, nil
+}
+
+// Credentials implements Store.
+func (f *FileStore) Credentials(_ context.Context, account string) (map[string]string, error) {
+	var out map[string]string
+	err := f.update(func(data secrets) (bool, error) {
+		keys, ok := data[account]
+		if !ok {
+			return false, fmt.Errorf("%w: account %s", ErrNotFound, account)
+		}
+		out = make(map[string]string, len(keys))
+		for k, v := range keys {
+			out[k] = v.Value
+		}
+		return false, nil
+	})
+	return out, err
+}
+
+// Put implements Store.
+func (f *FileStore) Put(_ context.Context, account, key, value string) error {
+	if err := ValidateName("account", account); err != nil {
+		return err
+	}
+	if err := ValidateName("key", key); err != nil {
+		return err
+	}
+	return f.update(func(data secrets) (bool, error) {
+		if data[account] == nil {
+			data[account] = make(map[string]secretValue)
+		}
+		data[account][key] = secretValue{Value: value, UpdatedAt: time.Now().UTC()}
+		return true, nil
+	})
+}
+
+// Delete implements Store.
+func (f *FileStore) Delete(_ context.Context, account, key string) error {
+	return f.update(func(data secrets) (bool, error) {
+		if _, ok := data[account][key]; !ok {
+			return false, fmt.Errorf("%w: %s", ErrNotFound, Ref(account, key))
+		}
+		data.remove(account, key)
+		return true, nil
+	})
+}
+
+// List implements Store.
+func (f *FileStore) List(_ context.Context, account string) ([]Entry, error) {
+	var out []Entry
+	if err := f.update(func(data secrets) (bool, error) {
+		for acc, keys := range data {
+			if account != "" && acc != account {
+				continue
+			}
+			for k, v := range keys {
+				out = append(out, Entry{Account: acc, Key: k, UpdatedAt: v.UpdatedAt})
+			}
+		}
+		return false, nil
+	}); err != nil {
+		return nil, err
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Account != out[j].Account {
+			return out[i].Account < out[j].Account
+		}
+		return out[i].Key < out[j].Key
+	})
+	return out, nil
+}
@@ -0,0 +1,127 @@
+// Package secrets stores platform credentials for the orchestrator so that
+// callers can reference them as secret://account/key instead of sending raw
+// tokens in pipeline params.
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// RefPrefix starts every secret reference.
+const RefPrefix = "secret://"
+
+var (
+	// ErrNotFound is returned when an account or key does not exist.
+	ErrNotFound = errors.New("secret not found")
+	// ErrNoStore is returned when params contain references but the server
+	// runs without a credential store.
+	ErrNoStore = errors.New("no credential store configured")
+)
+
+// Entry describes a stored secret without its value.
+type Entry struct {
+	Account   string
+	Key       string
+	UpdatedAt time.Time
+}
+
+// Ref returns the reference that resolves to this entry.
+func (e Entry) Ref() string {
+	return Ref(e.Account, e.Key)
+}
+
+// Store holds credentials grouped by account.
+type Store interface {
+	// Get returns a single secret value.
+	Get(ctx context.Context, account, key string) (string, error)
+	// Credentials returns every secret stored for an account.
+	Credentials(ctx context.Context, account string) (map[string]string, error)
+	// Put creates or replaces a secret.
+	Put(ctx context.Context, account, key, value string) error
+	// Delete removes a secret. Deleting the last key removes the account.
+	Delete(ctx context.Context, account, key string) error
+	// List describes stored secrets; an empty account lists all accounts.
+	List(ctx context.Context, account string) ([]Entry, error)
+}
+
+// Ref formats a secret reference.
+func Ref(account, key string) string {
+	return RefPrefix + account + "/" + key
+}
+
+// ParseRef splits a secret://account/key reference. ok is false if v is not
+// a reference at all; err is set if it is one but malformed.
+func ParseRef(v string) (account, key string, ok bool, err error) {
+	if !strings.HasPrefix(v, RefPrefix) {
+		return "", "", false, nil
+	}
+	account, key, found := strings.Cut(strings.TrimPrefix(v, RefPrefix), "/")
+	if !found || account == "" || key == "" || strings.Contains(key, "/") {
+		return "", "", true, fmt.Errorf("malformed secret reference %q (want %saccount/key)", v, RefPrefix)
+	}
+	return account, key, true, nil
+}
+
+// ValidateName checks an account or key name used in a reference.
+func ValidateName(kind, name string) error {
+	if name == "" {
+		return fmt.Errorf("%s must not be empty", kind)
+	}
+	if strings.ContainsAny(name, "/ \t\n") {
+		return fmt.Errorf("%s %q must not contain '/' or whitespace", kind, name)
+	}
+	return nil
+}
+
+// Resolve returns a copy of params with every secret reference replaced by
+// its value. Params without references are returned unchanged.
+func Resolve(ctx context.Context, st Store, params map[string]string) (map[string]string, error) {
+	var out map[string]string
+	for k, v := range params {
+		account, key, ok, err := ParseRef(v)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		if st == nil {
+			return nil, ErrNoStore
+		}
+		val, err := st.Get(ctx, account, key)
+		if err != nil {
+			return nil, fmt.Errorf("resolving param %s: %w", k, err)
+		}
+		if out == nil {
+			out = make(map[string]string, len(params))
+			for pk, pv := range params {
+				out[pk] = pv
+			}
+		}
+		out[k] = val
+	}
+	if out == nil {
+		return params, nil
+	}
+	return out, nil
+}
+
+// ReferencedAccounts returns the accounts named by secret references in
+// params, in no particular order.
+func ReferencedAccounts(params map[string]string) []string {
+	seen := make(map[string]bool)
+	var accounts []string
+	for _, v := range params {
+		account, _, ok, err := ParseRef(v)
+		if !ok || err != nil || seen[account] {
+			continue
+		}
+		seen[account] = true
+		accounts = append(accounts, account)
+	}
+	return accounts
+}
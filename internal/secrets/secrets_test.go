@@ -0,0 +1,208 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseRef(t *testing.T) {
+	tests := []struct {
+		in           string
+		account, key string
+		ok, wantErr  bool
+	}{
+		{"plain-token", "", "", false, false},
+		{"secret://acme/access_token", "acme", "access_token", true, false},
+		{"secret://acme", "", "", true, true},
+		{"secret:///key", "", "", true, true},
+		{"secret://acme/", "", "", true, true},
+		{"secret://acme/a/b", "", "", true, true},
+	}
+	for _, tt := range tests {
+		account, key, ok, err := ParseRef(tt.in)
+		if account != tt.account || key != tt.key || ok != tt.ok || (err != nil) != tt.wantErr {
+			t.Errorf("ParseRef(%q) = %q, %q, %v, %v", tt.in, account, key, ok, err)
+		}
+	}
+}
+
+func TestValidateName(t *testing.T) {
+	for name, wantErr := range map[string]bool{"acme": false, "": true, "a/b": true, "a b": true} {
+		if err := ValidateName("account", name); (err != nil) != wantErr {
+			t.Errorf("ValidateName(%q) = %v, want error %v", name, err, wantErr)
+		}
+	}
+}
+
+func testKey() []byte { return bytes.Repeat([]byte{7}, 32) }
+
+func TestFileStore(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "secrets.json")
+	fs, err := OpenFileStore(path, testKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range []struct{ account, key, value string }{
+		{"acme", "access_token", "tok-1"},
+		{"acme", "page_id", "42"},
+		{"beta", "access_token", "tok-2"},
+	} {
+		if err := fs.Put(ctx, e.account, e.key, e.value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := fs.Put(ctx, "a/b", "k", "v"); err == nil {
+		t.Error("Put accepted an account name with a slash")
+	}
+	raw, _ := os.ReadFile(path)
+	if bytes.Contains(raw, []byte("tok-1")) {
+		t.Fatal("secrets file stores values in plain text")
+	}
+
+	reopened, err := OpenFileStore(path, testKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, err := reopened.Get(ctx, "acme", "access_token"); err != nil || v != "tok-1" {
+		t.Errorf("Get = %q, %v", v, err)
+	}
+	creds, err := reopened.Credentials(ctx, "acme")
+	if err != nil || len(creds) != 2 || creds["page_id"] != "42" {
+		t.Errorf("Credentials = %v, %v", creds, err)
+	}
+	entries, _ := reopened.List(ctx, "")
+	if len(entries) != 3 || entries[0].Ref() != "secret://acme/access_token" || entries[2].Account != "beta" {
+		t.Errorf("List = %+v", entries)
+	}
+
+	if err := reopened.Delete(ctx, "beta", "access_token"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reopened.Credentials(ctx, "beta"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("deleting the last key kept the account: %v", err)
+	}
+	if err := reopened.Delete(ctx, "beta", "access_token"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Delete(missing) = %v, want ErrNotFound", err)
+	}
+
+	if _, err := OpenFileStore(path, bytes.Repeat([]byte{8}, 32)); err == nil {
+		t.Error("OpenFileStore accepted the wrong master key")
+	}
+}
+
+func TestFileStoreFailedSaveLeavesMemoryUnchanged(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "secrets.json")
+	fs, err := OpenFileStore(path, testKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Put(ctx, "acme", "access_token", "tok-1"); err != nil {
+		t.Fatal(err)
+	}
+	// A directory in the way of the temp file makes every save fail.
+	if err := os.Mkdir(path+".tmp", 0o700); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		op   func() error
+	}{
+		{"replace", func() error { return fs.Put(ctx, "acme", "access_token", "tok-2") }},
+		{"add key", func() error { return fs.Put(ctx, "acme", "page_id", "42") }},
+		{"add account", func() error { return fs.Put(ctx, "beta", "access_token", "tok-3") }},
+		{"delete", func() error { return fs.Delete(ctx, "acme", "access_token") }},
+	}
+	for _, tt := range tests {
+		if err := tt.op(); err == nil {
+			t.Fatalf("%s: save did not fail", tt.name)
+		}
+		entries, _ := fs.List(ctx, "")
+		if len(entries) != 1 || entries[0].Ref() != "secret://acme/access_token" {
+			t.Errorf("%s: List = %+v", tt.name, entries)
+		}
+		if v, err := fs.Get(ctx, "acme", "access_token"); err != nil || v != "tok-1" {
+			t.Errorf("%s: Get = %q, %v", tt.name, v, err)
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	ctx := context.Background()
+	fs, err := OpenFileStore(filepath.Join(t.TempDir(), "secrets.json"), testKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs.Put(ctx, "acme", "access_token", "tok-1")
+
+	plain := map[string]string{"page_id": "42"}
+	tests := []struct {
+		name    string
+		store   Store
+		params  map[string]string
+		want    map[string]string
+		wantErr error
+	}{
+		{"no refs", fs, plain, plain, nil},
+		{"ref", fs, map[string]string{"access_token": "secret://acme/access_token", "page_id": "42"}, map[string]string{"access_token": "tok-1", "page_id": "42"}, nil},
+		{"missing", fs, map[string]string{"access_token": "secret://acme/other"}, nil, ErrNotFound},
+		{"no store", nil, map[string]string{"access_token": "secret://acme/access_token"}, nil, ErrNoStore},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Resolve(ctx, tt.store, tt.params)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Resolve error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Resolve = %v, want %v", got, tt.want)
+			}
+			for k, v := range tt.want {
+				if got[k] != v {
+					t.Errorf("Resolve[%s] = %q, want %q", k, got[k], v)
+				}
+			}
+		})
+	}
+}
+
+func TestReferencedAccounts(t *testing.T) {
+	got := ReferencedAccounts(map[string]string{
+		"a": "secret://acme/x",
+		"b": "secret://acme/y",
+		"c": "plain",
+		"d": "secret://bad",
+	})
+	if len(got) != 1 || got[0] != "acme" {
+		t.Errorf("ReferencedAccounts = %v, want [acme]", got)
+	}
+}
+
+func TestMasterKeyFromEnv(t *testing.T) {
+	tests := []struct {
+		value   string
+		wantErr bool
+	}{
+		{"", true},
+		{"not base64!", true},
+		{"c2hvcnQ=", true},
+		{"BwcHBwcHBwcHBwcHBwcHBwcHBwcHBwcHBwcHBwcHBwc=", false},
+	}
+	for _, tt := range tests {
+		t.Setenv(MasterKeyEnv, tt.value)
+		if _, err := MasterKeyFromEnv(); (err != nil) != tt.wantErr {
+			t.Errorf("MasterKeyFromEnv(%q) error = %v, want error %v", tt.value, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+// Package selection scores generated drafts so the orchestrator can pick
+// the best of several candidates. Scores combine the analyzer's risk and
+// sentiment with platform-fit checks: length limits and the "meta-talk"
+// models add around a post (design log 05).
+package selection
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	analyzer "github.com/Optiq-CTO/analyzer/api/proto"
+)
+
+// platformLimits are the maximum post lengths, in characters, we publish to.
+var platformLimits = map[string]int{
+	"twitter":   280,
+	"linkedin":  3000,
+	"instagram": 2200,
+	"facebook":  63206,
+}
+
+// PlatformLimit returns the maximum post length for platform, or 0 if it is
+// not known.
+func PlatformLimit(platform string) int {
+	return platformLimits[platform]
+}
+
+var metaTalk = []struct {
+	issue string
+	re    *regexp.Regexp
+}{
+	{"preamble", regexp.MustCompile(`(?i)^\s*(here('s| is| are)\b|sure[!,.]|certainly[!,.]|of course[!,.])`)},
+	{"post_tags", regexp.MustCompile(`(?i)</?post>`)},
+	{"explanation", regexp.MustCompile(`(?i)(why this works|this (post|reply) (works|is designed)|i hope this helps|let me know if)`)},
+	{"self_reference", regexp.MustCompile(`(?i)\bas an ai\b`)},
+	{"placeholder", regexp.MustCompile(`\[(actual content|insert [^\]]*|your [^\]]*)\]`)},
+}
+
+// Result is a scored candidate. Higher scores are better; disqualified
+// candidates are never selected.
+type Result struct {
+	Score        float64
+	Issues       []string
+	Disqualified bool
+}
+
+// Score rates content for platform. analysis may be nil when the analyzer is
+// unavailable, in which case only platform fit counts.
+func Score(platform, content string, analysis *analyzer.AnalyzeContentResponse) Result {
+	r := Result{Score: 1}
+	if strings.TrimSpace(content) == "" {
+		return Result{Issues: []string{"empty"}, Disqualified: true}
+	}
+	if limit := PlatformLimit(platform); limit > 0 {
+		if n := utf8.RuneCountInString(content); n > limit {
+			r.Issues = append(r.Issues, fmt.Sprintf("too_long(%d>%d)", n, limit))
+			r.Disqualified = true
+		}
+	}
+	for _, m := range metaTalk {
+		if m.re.MatchString(content) {
+			r.Issues = append(r.Issues, m.issue)
+			r.Score -= 0.3
+		}
+	}
+	if analysis != nil {
+		r.Score -= 0.5 * float64(analysis.RiskScore)
+		if strings.EqualFold(analysis.Sentiment, "negative") {
+			r.Issues = append(r.Issues, "negative_sentiment")
+			r.Score -= 0.2
+		}
+	}
+	if r.Score < 0 {
+		r.Score = 0
+	}
+	return r
+}
+
+// Best returns the index of the highest scoring qualified result, or -1 if
+// every result is disqualified. Ties go to the earliest result.
+func Best(results []Result) int {
+	best := -1
+	for i, r := range results {
+		if r.Disqualified {
+			continue
+		}
+		if best < 0 || r.Score > results[best].Score {
+			best = i
+		}
+	}
+	return best
+}
@@ -0,0 +1,63 @@
+package selection
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	analyzer "github.com/Optiq-CTO/analyzer/api/proto"
+)
+
+func TestScore(t *testing.T) {
+	tests := []struct {
+		name         string
+		platform     string
+		content      string
+		analysis     *analyzer.AnalyzeContentResponse
+		wantScore    float64
+		wantIssues   []string
+		disqualified bool
+	}{
+		{"clean", "twitter", "Great point about sleep.", nil, 1, nil, false},
+		{"empty", "twitter", "  ", nil, 0, []string{"empty"}, true},
+		{"too long", "twitter", strings.Repeat("a", 281), nil, 1, []string{"too_long(281>280)"}, true},
+		{"unknown platform has no limit", "myspace", strings.Repeat("a", 5000), nil, 1, nil, false},
+		{"preamble", "twitter", "Here's a reply: nice!", nil, 0.7, []string{"preamble"}, false},
+		{"tags and explanation", "twitter", "<post>Hi</post> Why this works: brevity", nil, 1 - 0.6, []string{"post_tags", "explanation"}, false},
+		{"risky and negative", "twitter", "Hmm.", &analyzer.AnalyzeContentResponse{RiskScore: 0.4, Sentiment: "Negative"}, 1 - 0.2 - 0.2, []string{"negative_sentiment"}, false},
+		{"floored at zero", "twitter", "Sure! As an AI, [insert joke]", &analyzer.AnalyzeContentResponse{RiskScore: 1}, 0, []string{"preamble", "self_reference", "placeholder"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Score(tt.platform, tt.content, tt.analysis)
+			if diff := got.Score - tt.wantScore; diff > 1e-6 || diff < -1e-6 {
+				t.Errorf("Score = %v, want %v", got.Score, tt.wantScore)
+			}
+			if !slices.Equal(got.Issues, tt.wantIssues) {
+				t.Errorf("Issues = %v, want %v", got.Issues, tt.wantIssues)
+			}
+			if got.Disqualified != tt.disqualified {
+				t.Errorf("Disqualified = %v, want %v", got.Disqualified, tt.disqualified)
+			}
+		})
+	}
+}
+
+func TestBest(t *testing.T) {
+	tests := []struct {
+		name    string
+		results []Result
+		want    int
+	}{
+		{"none", nil, -1},
+		{"all disqualified", []Result{{Score: 1, Disqualified: true}}, -1},
+		{"highest wins", []Result{{Score: 0.5}, {Score: 0.9}, {Score: 0.7}}, 1},
+		{"tie goes to first", []Result{{Score: 0.9}, {Score: 0.9}}, 0},
+		{"skips disqualified", []Result{{Score: 1, Disqualified: true}, {Score: 0.2}}, 1},
+	}
+	for _, tt := range tests {
+		if got := Best(tt.results); got != tt.want {
+			t.Errorf("%s: Best = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/Optiq-CTO/orchestrator/internal/admission"
+	"github.com/Optiq-CTO/orchestrator/internal/logging"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// admit waits for the run's turn under the admission limits and returns the
+// func that frees its slot.
+func (s *OrchestratorService) admit(ctx context.Context, req admission.Request) (func(), error) {
+	if _, ok := admission.Rank(req.Priority); !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "unknown priority %q; want interactive, scheduled or backfill", req.Priority)
+	}
+	if s.admission == nil {
+		return func() {}, nil
+	}
+	logger := logging.FromContext(ctx)
+	start := time.Now()
+	release, err := s.admission.Acquire(ctx, req)
+	if err != nil {
+		logger.Warn("run not admitted", "priority", req.Priority, "error", err)
+		return nil, err
+	}
+	if wait := time.Since(start); wait >= time.Second {
+		logger.Info("run admitted after queueing", "priority", req.Priority, "wait", wait)
+	}
+	return release, nil
+}
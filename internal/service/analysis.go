@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+
+	fetcher "github.com/Optiq-CTO/orchestrator/api/proto/external/fetcher"
+	"github.com/Optiq-CTO/orchestrator/internal/analysiscache"
+	"github.com/Optiq-CTO/orchestrator/internal/logging"
+	"github.com/Optiq-CTO/orchestrator/internal/retry"
+	"github.com/Optiq-CTO/orchestrator/internal/usage"
+	"google.golang.org/grpc/metadata"
+)
+
+// skipAnalysisHeader asks the fetcher to return items unanalyzed. Fetchers
+// that do not know it analyze as usual.
+const skipAnalysisHeader = "x-skip-analysis"
+
+// fetch calls the fetcher, retrying transient failures. With an analysis
+// cache, the fetcher is asked to skip analysis and the orchestrator analyzes
+// only the items the flow will use (the first req.Limit), reusing cached
+// results by source item.
+func (s *OrchestratorService) fetch(ctx context.Context, req *fetcher.FetchRequest) (*fetcher.FetchResponse, error) {
+	skip := s.analysisCache != nil && s.analyzer != nil
+	fetchCtx := ctx
+	if skip {
+		// Usage is recorded below, by what the fetcher actually analyzed.
+		fetchCtx = metadata.AppendToOutgoingContext(usage.WithoutKey(ctx), skipAnalysisHeader, "true")
+	}
+	res, err := retryStep(fetchCtx, s, "fetch", retry.Transient, func(ctx context.Context) (*fetcher.FetchResponse, error) {
+		return s.fetcher.FetchContent(ctx, req)
+	})
+	s.reportAuthFailure(ctx, req.Platform, req.Credentials, err)
+	if err != nil || !skip {
+		return res, err
+	}
+	s.countFetcherAnalyses(ctx, res.Items)
+
+	items := res.Items
+	if n := int(req.Limit); n > 0 && n < len(items) {
+		items = items[:n]
+	}
+	logger := logging.FromContext(ctx)
+	for _, item := range items {
+		platform := item.Platform
+		if platform == "" {
+			platform = req.Platform
+		}
+		key := analysiscache.Key(platform, item.SourceId, req.ModelProvider)
+		cacheable := item.SourceId != ""
+		// Fetchers that ignore the header still analyze; keep their work.
+		if item.Analysis != nil {
+			if cacheable {
+				s.putAnalysis(ctx, key, item)
+			}
+			continue
+		}
+		if cacheable {
+			if a, ok := s.analysisCache.Get(ctx, key); ok {
+				item.Analysis = a
+				continue
+			}
+		}
+		a, err := s.analyze(ctx, item.ContentText, req.ModelProvider)
+		if err != nil {
+			// Flows already cope with unanalyzed items.
+			logger.Warn("analysis failed", "step", "analyze", "source_id", item.SourceId, "error", err)
+			continue
+		}
+		item.Analysis = a
+		if cacheable {
+			s.putAnalysis(ctx, key, item)
+		}
+	}
+	return res, nil
+}
+
+// countFetcherAnalyses records the analyses a fetcher ran despite being asked
+// to skip them, one AI call per analyzed item.
+func (s *OrchestratorService) countFetcherAnalyses(ctx context.Context, items []*fetcher.FetchedItem) {
+	key, ok := usage.KeyFromContext(ctx)
+	if !ok || s.usage == nil {
+		return
+	}
+	var n int64
+	for _, item := range items {
+		if item.Analysis != nil {
+			n++
+		}
+	}
+	if n > 0 {
+		s.usage.Observe(key, usage.Counts{Calls: n})
+	}
+}
+
+func (s *OrchestratorService) putAnalysis(ctx context.Context, key string, item *fetcher.FetchedItem) {
+	if err := s.analysisCache.Put(ctx, key, item.Analysis); err != nil {
+		logging.FromContext(ctx).Warn("failed to cache analysis", "source_id", item.SourceId, "error", err)
+	}
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+
+	"github.com/Optiq-CTO/orchestrator/internal/auth"
+	"github.com/Optiq-CTO/orchestrator/internal/runs"
+	"github.com/Optiq-CTO/orchestrator/internal/tenant"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Scope checks happen in the auth interceptor; the checks here apply the
+// per-key flow and account allowlists, which depend on request contents.
+
+// authorizeRun checks that the caller may run flow with the given accounts.
+// Keys restricted to accounts must use stored credentials, since raw tokens
+// in params cannot be attributed to an account, and tenant keys must run a
+// registered account of their tenant and only use its stored credentials.
+func (s *OrchestratorService) authorizeRun(ctx context.Context, flow string, acct *tenant.Account, accounts []string) error {
+	p, ok := auth.FromContext(ctx)
+	if !ok {
+		return nil
+	}
+	if !p.AllowsFlow(flow) {
+		return status.Errorf(codes.PermissionDenied, "api key %q may not run flow %s", p.Name, flow)
+	}
+	if p.Tenant != "" && (acct == nil || acct.TenantID != p.Tenant) {
+		return status.Errorf(codes.PermissionDenied, "api key %q may only run accounts of tenant %s", p.Name, p.Tenant)
+	}
+	for _, a := range accounts {
+		if p.Tenant != "" && (s.tenants == nil || !s.tenants.OwnsCredentials(p.Tenant, a)) {
+			return status.Errorf(codes.PermissionDenied, "api key %q may not use credentials of account %s outside tenant %s", p.Name, a, p.Tenant)
+		}
+	}
+	if p.RestrictsAccounts() && len(accounts) == 0 {
+		return status.Errorf(codes.PermissionDenied, "api key %q may only run with stored credentials of its accounts", p.Name)
+	}
+	for _, a := range accounts {
+		if !p.AllowsAccount(a) {
+			return status.Errorf(codes.PermissionDenied, "api key %q may not act for account %s", p.Name, a)
+		}
+	}
+	return nil
+}
+
+// authorizeAccount checks that the caller may manage an account. An empty
+// account (meaning "all") is only allowed for unrestricted keys.
+func authorizeAccount(ctx context.Context, account string) error {
+	p, ok := auth.FromContext(ctx)
+	if !ok || !p.RestrictsAccounts() {
+		return nil
+	}
+	if account == "" || !p.AllowsAccount(account) {
+		return status.Errorf(codes.PermissionDenied, "api key %q may not manage account %q", p.Name, account)
+	}
+	return nil
+}
+
+// canSeeAccount reports whether the caller's account allowlist covers account.
+func canSeeAccount(ctx context.Context, account string) bool {
+	p, ok := auth.FromContext(ctx)
+	return !ok || p.AllowsAccount(account)
+}
+
+// canSeeRun reports whether the caller's allowlists cover a run record.
+func canSeeRun(ctx context.Context, rec *runs.Record) bool {
+	p, ok := auth.FromContext(ctx)
+	if !ok {
+		return true
+	}
+	if !p.AllowsFlow(rec.Flow) || !p.AllowsTenant(rec.TenantID) {
+		return false
+	}
+	if p.RestrictsAccounts() && len(rec.Accounts) == 0 {
+		return false
+	}
+	for _, a := range rec.Accounts {
+		if !p.AllowsAccount(a) {
+			return false
+		}
+	}
+	return true
+}
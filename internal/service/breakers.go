@@ -0,0 +1,41 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	pb "github.com/Optiq-CTO/orchestrator/api/proto"
+	"github.com/Optiq-CTO/orchestrator/internal/breaker"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *OrchestratorService) ListCircuitBreakers(ctx context.Context, req *pb.ListCircuitBreakersRequest) (*pb.ListCircuitBreakersResponse, error) {
+	if s.breakers == nil {
+		return nil, status.Error(codes.FailedPrecondition, "circuit breakers are not enabled")
+	}
+	res := &pb.ListCircuitBreakersResponse{}
+	for _, b := range s.breakers.Statuses() {
+		if req.Service != "" && b.Service != req.Service {
+			continue
+		}
+		res.Breakers = append(res.Breakers, breakerToProto(b))
+	}
+	return res, nil
+}
+
+func breakerToProto(b breaker.Status) *pb.CircuitBreaker {
+	r := &pb.CircuitBreaker{
+		Service:  b.Service,
+		Key:      b.Key,
+		State:    b.State.String(),
+		Failures: int32(b.Failures),
+	}
+	if !b.OpenedAt.IsZero() {
+		r.OpenedAt = b.OpenedAt.UTC().Format(time.RFC3339)
+	}
+	if !b.RetryAt.IsZero() {
+		r.RetryAt = b.RetryAt.UTC().Format(time.RFC3339)
+	}
+	return r
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	analyzer "github.com/Optiq-CTO/analyzer/api/proto"
+	creator "github.com/Optiq-CTO/orchestrator/api/proto/external/creator"
+	"github.com/Optiq-CTO/orchestrator/internal/logging"
+	"github.com/Optiq-CTO/orchestrator/internal/retry"
+	"github.com/Optiq-CTO/orchestrator/internal/runs"
+	"github.com/Optiq-CTO/orchestrator/internal/selection"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// maxCandidates bounds best-of-N, since every candidate costs a generation
+// and an analysis call.
+const maxCandidates = 5
+
+// candidateParams reads the best-of-N params: "candidates" is how many
+// drafts to generate and "candidate_providers" an optional comma-separated
+// list of providers to spread them across, round-robin.
+func candidateParams(params map[string]string) (int, []string, error) {
+	v := params["candidates"]
+	if v == "" {
+		return 1, nil, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 || n > maxCandidates {
+		return 0, nil, status.Errorf(codes.InvalidArgument, "candidates must be between 1 and %d", maxCandidates)
+	}
+	var providers []string
+	for _, p := range strings.Split(params["candidate_providers"], ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			providers = append(providers, p)
+		}
+	}
+	return n, providers, nil
+}
+
+// generateDraft produces the content for one post. In best-of-N runs it
+// generates several candidates, scores each with the analyzer and
+// platform-fit checks, and takes the best; every candidate is kept for the
+// run record. The draft then goes through critique-and-revise if enabled.
+func (s *OrchestratorService) generateDraft(ctx context.Context, platform, tone, modelProvider string, call func(ctx context.Context, provider string) (*creator.GenerateResponse, error)) (*creator.GenerateResponse, error) {
+	route := routeFromContext(ctx)
+	if route == nil || route.candidates <= 1 {
+		res, provider, err := s.generate(ctx, modelProvider, "", call)
+		if err != nil {
+			return nil, err
+		}
+		return s.revise(ctx, platform, tone, modelProvider, provider, res), nil
+	}
+
+	logger := logging.FromContext(ctx)
+	var (
+		drafts    []runs.Candidate
+		responses []*creator.GenerateResponse
+		results   []selection.Result
+		lastErr   error
+	)
+	for i := 0; i < route.candidates; i++ {
+		preferred := ""
+		if len(route.candidateProviders) > 0 {
+			preferred = route.candidateProviders[i%len(route.candidateProviders)]
+		}
+		res, provider, err := s.generate(ctx, modelProvider, preferred, call)
+		if err != nil {
+			logger.Warn("candidate generation failed", "candidate", i, "model_provider", provider, "error", err)
+			lastErr = err
+			drafts = append(drafts, runs.Candidate{Provider: provider, Error: err.Error()})
+			responses = append(responses, nil)
+			results = append(results, selection.Result{Disqualified: true})
+			continue
+		}
+		d := runs.Candidate{Provider: provider, Content: res.Content}
+		analysis, err := s.analyze(ctx, res.Content, provider)
+		if err != nil {
+			// Score on platform fit alone rather than lose the candidate.
+			d.Error = fmt.Sprintf("analysis failed: %v", err)
+		} else if analysis != nil {
+			d.RiskScore, d.Sentiment = analysis.RiskScore, analysis.Sentiment
+		}
+		r := selection.Score(platform, res.Content, analysis)
+		d.Score, d.Issues = r.Score, r.Issues
+		drafts = append(drafts, d)
+		responses = append(responses, res)
+		results = append(results, r)
+	}
+
+	best := selection.Best(results)
+	if best >= 0 {
+		drafts[best].Selected = true
+		route.used = drafts[best].Provider
+	}
+	route.drafts = append(route.drafts, drafts...)
+	if best < 0 && allFailed(responses) {
+		return nil, lastErr
+	}
+	if best < 0 {
+		return nil, status.Errorf(codes.FailedPrecondition, "none of %d candidates fits the platform", route.candidates)
+	}
+	logger.Info("selected candidate", "candidate", best, "model_provider", drafts[best].Provider, "score", drafts[best].Score)
+	return s.revise(ctx, platform, tone, modelProvider, drafts[best].Provider, responses[best]), nil
+}
+
+// analyze scores content with the analyzer, if one is configured.
+func (s *OrchestratorService) analyze(ctx context.Context, content, modelProvider string) (*analyzer.AnalyzeContentResponse, error) {
+	if s.analyzer == nil {
+		return nil, nil
+	}
+	return retryStep(ctx, s, "analyze", retry.Transient, func(ctx context.Context) (*analyzer.AnalyzeContentResponse, error) {
+		return s.analyzer.AnalyzeContent(ctx, &analyzer.AnalyzeContentRequest{
+			Content:       &analyzer.AnalyzeContentRequest_Text{Text: content},
+			ModelProvider: modelProvider,
+		})
+	})
+}
+
+func allFailed(responses []*creator.GenerateResponse) bool {
+	for _, r := range responses {
+		if r != nil {
+			return false
+		}
+	}
+	return true
+}
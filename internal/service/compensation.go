@@ -0,0 +1,268 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	pb "github.com/Optiq-CTO/orchestrator/api/proto"
+	publisher "github.com/Optiq-CTO/orchestrator/api/proto/external/publisher"
+	"github.com/Optiq-CTO/orchestrator/internal/logging"
+	"github.com/Optiq-CTO/orchestrator/internal/metrics"
+	"github.com/Optiq-CTO/orchestrator/internal/publishing"
+	"github.com/Optiq-CTO/orchestrator/internal/redact"
+	"github.com/Optiq-CTO/orchestrator/internal/retry"
+	"github.com/Optiq-CTO/orchestrator/internal/runs"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// rollbackTimeout bounds the compensations of a failed run, which run even
+// if the run's own context is done.
+const rollbackTimeout = time.Minute
+
+// compensation undoes a completed step of an all-or-nothing run.
+type compensation struct {
+	step string
+	undo func(ctx context.Context) error
+}
+
+// compensate registers how to undo a step the run completed.
+func (r *activeRun) compensate(step string, undo func(ctx context.Context) error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.compensations = append(r.compensations, compensation{step, undo})
+}
+
+// takeCompensations returns the registered compensations, newest first,
+// and clears them.
+func (r *activeRun) takeCompensations() []compensation {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]compensation, 0, len(r.compensations))
+	for i := len(r.compensations) - 1; i >= 0; i-- {
+		out = append(out, r.compensations[i])
+	}
+	r.compensations = nil
+	return out
+}
+
+// addPost records a post the run published and returns its index.
+func (r *activeRun) addPost(p runs.Post) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.posts = append(r.posts, p)
+	return len(r.posts) - 1
+}
+
+func (r *activeRun) setPostStatus(i int, status, errMsg string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.posts[i].Status, r.posts[i].Error = status, errMsg
+}
+
+func (r *activeRun) publishedPosts() []runs.Post {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]runs.Post(nil), r.posts...)
+}
+
+// allOrNothing reports whether a run rolls back its posts if it fails: the
+// all_or_nothing param decides, else the flow setting.
+func (s *OrchestratorService) allOrNothing(req *pb.PipelineRequest) bool {
+	if v, ok := req.Params["all_or_nothing"]; ok {
+		return v == "true"
+	}
+	return s.flowSettings(req.FlowName).AllOrNothing
+}
+
+func allOrNothingRun(ctx context.Context) bool {
+	run := activeRunFromContext(ctx)
+	return run != nil && run.allOrNothing
+}
+
+// recordPost records a published post on the run and registers its
+// deletion as the compensation of the publish step.
+func (s *OrchestratorService) recordPost(ctx context.Context, req *publisher.PublishRequest, res *publisher.PublishResponse) {
+	run := activeRunFromContext(ctx)
+	if run == nil {
+		return
+	}
+	stored, _ := s.splitCredentials(ctx, req.Credentials)
+	i := run.addPost(runs.Post{
+		Platform:    req.Platform,
+		PostID:      res.PostId,
+		PostURL:     res.PostUrl,
+		Credentials: stored,
+		Status:      runs.PostPublished,
+	})
+	run.compensate("publish", func(ctx context.Context) error {
+		err := s.deletePost(ctx, req.Platform, res.PostId, req.Credentials)
+		if err != nil {
+			run.setPostStatus(i, runs.PostDeleteFailed, redact.String(err.Error()))
+			return err
+		}
+		run.setPostStatus(i, runs.PostDeleted, "")
+		return nil
+	})
+}
+
+// rollback runs the compensations of an all-or-nothing run that failed,
+// newest first. Failed compensations are logged and recorded on the run;
+// UnpublishRun can retry them.
+func (s *OrchestratorService) rollback(ctx context.Context, run *activeRun) {
+	comps := run.takeCompensations()
+	if len(comps) == 0 {
+		return
+	}
+	logger := logging.FromContext(ctx)
+	logger.Warn("all-or-nothing run failed, rolling back", "steps", len(comps))
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), rollbackTimeout)
+	defer cancel()
+	for _, c := range comps {
+		if err := c.undo(ctx); err != nil {
+			metrics.Compensations.Inc(c.step, "failed")
+			logger.Error("compensation failed", "step", c.step, "error", err)
+			continue
+		}
+		metrics.Compensations.Inc(c.step, "succeeded")
+	}
+}
+
+// deletePost deletes a published post. Deleting is idempotent, so
+// transient failures are retried.
+func (s *OrchestratorService) deletePost(ctx context.Context, platform, postID string, creds map[string]string) error {
+	if postID == "" {
+		return fmt.Errorf("the publisher returned no post id for the %s post", platform)
+	}
+	_, err := retryStep(ctx, s, "delete", retry.Transient, func(ctx context.Context) (*publisher.DeleteResponse, error) {
+		return s.publisher.DeleteContent(ctx, &publisher.DeleteRequest{PostId: postID, Platform: platform, Credentials: creds})
+	})
+	return err
+}
+
+func (s *OrchestratorService) UnpublishRun(ctx context.Context, req *pb.UnpublishRunRequest) (*pb.UnpublishRunResponse, error) {
+	rec, err := s.runs.Get(ctx, req.RunId)
+	if errors.Is(err, runs.ErrNotFound) || (err == nil && !canSeeRun(ctx, rec)) {
+		return nil, status.Errorf(codes.NotFound, "run %s not found", req.RunId)
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "reading run: %v", err)
+	}
+	if rec.Status == runs.StatusRunning {
+		return nil, status.Errorf(codes.FailedPrecondition, "run %s is still running", rec.ID)
+	}
+	logger := logging.FromContext(ctx).With("run_id", rec.ID)
+	res := &pb.UnpublishRunResponse{}
+
+	// Outbox entries first, so none is delivered while the run's posts are
+	// being deleted.
+	var delivered []publishing.Entry
+	if s.outbox != nil {
+		for _, e := range s.outbox.Entries("") {
+			if e.RunID != rec.ID {
+				continue
+			}
+			switch e.Status {
+			case publishing.StatusPending, publishing.StatusHeld:
+				from := e.Status
+				e.Status = publishing.StatusCancelled
+				if err := s.outbox.Update(e, from); err != nil {
+					return nil, outboxUpdateError(err)
+				}
+				res.CancelledOutboxIds = append(res.CancelledOutboxIds, e.ID)
+			case publishing.StatusPublished:
+				delivered = append(delivered, e)
+			}
+		}
+	}
+
+	changed := false
+	for i := range rec.Posts {
+		p := &rec.Posts[i]
+		if p.Status != runs.PostDeleted {
+			creds, err := s.postCredentials(ctx, p.Credentials, req.Credentials)
+			if err == nil {
+				err = s.deletePost(ctx, p.Platform, p.PostID, creds)
+			}
+			p.Status, p.Error = runs.PostDeleted, ""
+			if err != nil {
+				p.Status, p.Error = runs.PostDeleteFailed, redact.String(err.Error())
+			}
+			changed = true
+		}
+		res.Posts = append(res.Posts, postToProto(p, ""))
+	}
+	if changed {
+		if err := s.runs.Save(ctx, rec); err != nil {
+			logger.Error("failed to save run", "error", err)
+		}
+	}
+
+	for _, e := range delivered {
+		p := runs.Post{Platform: e.Platform, PostID: e.PostID, PostURL: e.PostURL, Status: runs.PostDeleted}
+		creds, err := s.entryCredentials(ctx, &e)
+		if err == nil {
+			err = s.deletePost(ctx, e.Platform, e.PostID, creds)
+		}
+		if err != nil {
+			p.Status, p.Error = runs.PostDeleteFailed, redact.String(err.Error())
+		} else {
+			e.Status = publishing.StatusDeleted
+			if err := s.outbox.Update(e, publishing.StatusPublished); err != nil {
+				logger.Error("failed to update outbox entry", "outbox_id", e.ID, "error", err)
+			}
+		}
+		res.Posts = append(res.Posts, postToProto(&p, e.ID))
+	}
+
+	for _, p := range res.Posts {
+		result := "succeeded"
+		if p.Status != runs.PostDeleted {
+			result = "failed"
+		}
+		metrics.Compensations.Inc("unpublish", result)
+	}
+	logger.Info("run unpublished", "posts", len(res.Posts), "cancelled_outbox_entries", len(res.CancelledOutboxIds))
+	return res, nil
+}
+
+// postCredentials resolves the stored credentials of a post, taking plain
+// values, stored empty, from supplied.
+func (s *OrchestratorService) postCredentials(ctx context.Context, stored, supplied map[string]string) (map[string]string, error) {
+	refs := make(map[string]string, len(stored))
+	var missing []string
+	for k, v := range stored {
+		switch {
+		case v != "":
+			refs[k] = v
+		case supplied[k] == "":
+			missing = append(missing, k)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("credentials %v were passed as plain values; supply them again to delete the post", missing)
+	}
+	creds, err := s.resolveParams(ctx, refs)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range stored {
+		if v == "" {
+			creds[k] = supplied[k]
+		}
+	}
+	return creds, nil
+}
+
+func postToProto(p *runs.Post, outboxID string) *pb.PublishedPost {
+	return &pb.PublishedPost{
+		Platform: p.Platform,
+		PostId:   p.PostID,
+		PostUrl:  p.PostURL,
+		Status:   p.Status,
+		Error:    p.Error,
+		OutboxId: outboxID,
+	}
+}
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	pb "github.com/Optiq-CTO/orchestrator/api/proto"
+	"github.com/Optiq-CTO/orchestrator/internal/deadletter"
+	"github.com/Optiq-CTO/orchestrator/internal/logging"
+	"github.com/Optiq-CTO/orchestrator/internal/metrics"
+	"github.com/Optiq-CTO/orchestrator/internal/redact"
+	"github.com/Optiq-CTO/orchestrator/internal/runs"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// deadLetter records a failed item, filled in from the run it failed in.
+func (s *OrchestratorService) deadLetter(ctx context.Context, e deadletter.Entry, err error) {
+	if s.deadLetters == nil {
+		return
+	}
+	logger := logging.FromContext(ctx)
+	_, base, _ := runOf(ctx)
+	e.RunID = base.ID
+	e.Flow = base.Flow
+	e.Tenant = base.TenantID
+	e.AccountID = base.AccountID
+	e.Accounts = base.Accounts
+	e.Error = redact.String(err.Error())
+	wasQuarantined := s.deadLetters.Quarantined(e.Key)
+	stored, serr := s.deadLetters.Fail(e)
+	if serr != nil {
+		logger.Error("failed to save dead letter", "source_id", e.SourceID, "error", serr)
+		return
+	}
+	metrics.DeadLetters.Inc(flowLabel(e.Flow), e.Step)
+	logger.Info("item dead-lettered", "dead_letter_id", stored.ID, "source_id", e.SourceID, "attempts", stored.Attempts)
+	if stored.Status == deadletter.StatusQuarantined && !wasQuarantined {
+		metrics.ItemsQuarantined.Inc(flowLabel(e.Flow))
+		logger.Warn("item quarantined after repeated failures; runs skip it until it is retried or discarded",
+			"dead_letter_id", stored.ID, "source_id", e.SourceID, "attempts", stored.Attempts)
+	}
+}
+
+// resolveDeadLetter forgets the failures of an item that succeeded.
+func (s *OrchestratorService) resolveDeadLetter(ctx context.Context, key string) {
+	if s.deadLetters == nil {
+		return
+	}
+	if err := s.deadLetters.Resolve(key); err != nil {
+		logging.FromContext(ctx).Warn("failed to resolve dead letter", "key", key, "error", err)
+	}
+}
+
+// quarantined reports whether runs skip the item key.
+func (s *OrchestratorService) quarantined(key string) bool {
+	return s.deadLetters != nil && s.deadLetters.Quarantined(key)
+}
+
+func (s *OrchestratorService) ListDeadLetters(ctx context.Context, req *pb.ListDeadLettersRequest) (*pb.ListDeadLettersResponse, error) {
+	if s.deadLetters == nil {
+		return nil, status.Error(codes.FailedPrecondition, "no dead letter store is configured")
+	}
+	res := &pb.ListDeadLettersResponse{}
+	for _, e := range s.deadLetters.Entries(req.Status) {
+		if (req.FlowName != "" && e.Flow != req.FlowName) || !canSeeDeadLetter(ctx, &e) {
+			continue
+		}
+		res.DeadLetters = append(res.DeadLetters, deadLetterToProto(&e))
+	}
+	return res, nil
+}
+
+// RetryDeadLetter runs the item's flow again for that item alone. The
+// entry is removed if it succeeds and counts another attempt if not.
+func (s *OrchestratorService) RetryDeadLetter(ctx context.Context, req *pb.RetryDeadLetterRequest) (*pb.PipelineResponse, error) {
+	e, err := s.deadLetterFor(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	params := make(map[string]string, len(e.Params)+1)
+	for k, v := range e.Params {
+		params[k] = v
+	}
+	params["dead_letter_id"] = e.ID
+	logging.FromContext(ctx).Info("retrying dead letter", "dead_letter_id", e.ID, "source_id", e.SourceID)
+	return s.runPipeline(ctx, &pb.PipelineRequest{
+		FlowName:  e.Flow,
+		Params:    params,
+		AccountId: e.AccountID,
+		Priority:  req.Priority,
+	}, "")
+}
+
+func (s *OrchestratorService) DiscardDeadLetter(ctx context.Context, req *pb.DiscardDeadLetterRequest) (*pb.DeadLetter, error) {
+	if _, err := s.deadLetterFor(ctx, req.Id); err != nil {
+		return nil, err
+	}
+	e, err := s.deadLetters.Discard(req.Id)
+	if errors.Is(err, deadletter.ErrNotFound) {
+		return nil, status.Errorf(codes.NotFound, "dead letter %s not found", req.Id)
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "updating dead letters: %v", err)
+	}
+	logging.FromContext(ctx).Info("dead letter discarded", "dead_letter_id", e.ID)
+	return deadLetterToProto(&e), nil
+}
+
+// deadLetterFor returns an entry the caller may see.
+func (s *OrchestratorService) deadLetterFor(ctx context.Context, id string) (deadletter.Entry, error) {
+	if s.deadLetters == nil {
+		return deadletter.Entry{}, status.Error(codes.FailedPrecondition, "no dead letter store is configured")
+	}
+	e, err := s.deadLetters.Get(id)
+	if errors.Is(err, deadletter.ErrNotFound) || (err == nil && !canSeeDeadLetter(ctx, &e)) {
+		return deadletter.Entry{}, status.Errorf(codes.NotFound, "dead letter %s not found", id)
+	}
+	return e, err
+}
+
+// canSeeDeadLetter applies the caller's allowlists to the run e failed in.
+func canSeeDeadLetter(ctx context.Context, e *deadletter.Entry) bool {
+	return canSeeRun(ctx, &runs.Record{Flow: e.Flow, TenantID: e.Tenant, Accounts: e.Accounts})
+}
+
+func deadLetterToProto(e *deadletter.Entry) *pb.DeadLetter {
+	return &pb.DeadLetter{
+		Id:             e.ID,
+		FlowName:       e.Flow,
+		RunId:          e.RunID,
+		TenantId:       e.Tenant,
+		AccountId:      e.AccountID,
+		Params:         e.Params,
+		SourcePlatform: e.SourcePlatform,
+		SourceId:       e.SourceID,
+		SourceContent:  e.Source,
+		Step:           e.Step,
+		Draft:          e.Draft,
+		Error:          e.Error,
+		Attempts:       int32(e.Attempts),
+		Status:         e.Status,
+		FirstFailedAt:  e.FirstFailedAt.Format(time.RFC3339),
+		LastFailedAt:   e.LastFailedAt.Format(time.RFC3339),
+	}
+}
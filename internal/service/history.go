@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	pb "github.com/Optiq-CTO/orchestrator/api/proto"
+	"github.com/Optiq-CTO/orchestrator/internal/runs"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const defaultListRunsLimit = 50
+
+func (s *OrchestratorService) GetRun(ctx context.Context, req *pb.GetRunRequest) (*pb.RunRecord, error) {
+	rec, err := s.runs.Get(ctx, req.RunId)
+	if errors.Is(err, runs.ErrNotFound) {
+		return nil, status.Errorf(codes.NotFound, "run %s not found", req.RunId)
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "reading run: %v", err)
+	}
+	// Runs outside the caller's allowlists look the same as missing ones.
+	if !canSeeRun(ctx, rec) {
+		return nil, status.Errorf(codes.NotFound, "run %s not found", req.RunId)
+	}
+	return runToProto(rec), nil
+}
+
+func (s *OrchestratorService) ListRuns(ctx context.Context, req *pb.ListRunsRequest) (*pb.ListRunsResponse, error) {
+	limit := int(req.Limit)
+	if limit <= 0 {
+		limit = defaultListRunsLimit
+	}
+	// Allowlist filtering happens after the store query, so fetch everything
+	// matching and apply the limit last.
+	recs, err := s.runs.List(ctx, runs.Filter{
+		Flow:      req.FlowName,
+		User:      req.User,
+		Status:    req.Status,
+		TenantID:  req.TenantId,
+		AccountID: req.AccountId,
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "listing runs: %v", err)
+	}
+	res := &pb.ListRunsResponse{}
+	for _, rec := range recs {
+		if len(res.Runs) == limit {
+			break
+		}
+		if canSeeRun(ctx, rec) {
+			res.Runs = append(res.Runs, runToProto(rec))
+		}
+	}
+	return res, nil
+}
+
+func withheldToProto(withheld []runs.Withheld) []*pb.WithheldPost {
+	var out []*pb.WithheldPost
+	for _, w := range withheld {
+		wp := &pb.WithheldPost{
+			Platform: w.Platform,
+			Action:   w.Action,
+			Reason:   w.Reason,
+			OutboxId: w.OutboxID,
+		}
+		if !w.NotBefore.IsZero() {
+			wp.NotBefore = w.NotBefore.Format(time.RFC3339)
+		}
+		out = append(out, wp)
+	}
+	return out
+}
+
+func runToProto(rec *runs.Record) *pb.RunRecord {
+	r := &pb.RunRecord{
+		RunId:         rec.ID,
+		FlowName:      rec.Flow,
+		User:          rec.User,
+		ModelProvider: rec.ModelProvider,
+		Params:        rec.Params,
+		Status:        rec.Status,
+		ErrorMessage:  rec.Error,
+		OutputUrls:    rec.OutputURLs,
+		StartedAt:     rec.StartedAt.Format(time.RFC3339),
+		Accounts:      rec.Accounts,
+		TenantId:      rec.TenantID,
+		AccountId:     rec.AccountID,
+		Steps:         rec.Steps,
+		ResumedFrom:   rec.ResumedFrom,
+		Priority:      rec.Priority,
+	}
+	if !rec.FinishedAt.IsZero() {
+		r.FinishedAt = rec.FinishedAt.Format(time.RFC3339)
+	}
+	for _, c := range rec.Candidates {
+		r.Candidates = append(r.Candidates, &pb.Candidate{
+			ModelProvider: c.Provider,
+			Content:       c.Content,
+			Score:         c.Score,
+			RiskScore:     c.RiskScore,
+			Sentiment:     c.Sentiment,
+			Issues:        c.Issues,
+			Selected:      c.Selected,
+			Error:         c.Error,
+		})
+	}
+	r.Withheld = withheldToProto(rec.Withheld)
+	for i := range rec.Posts {
+		r.Posts = append(r.Posts, postToProto(&rec.Posts[i], ""))
+	}
+	for _, rev := range rec.Revisions {
+		r.Revisions = append(r.Revisions, &pb.Revision{
+			Iteration:     int32(rev.Iteration),
+			ModelProvider: rev.Provider,
+			Content:       rev.Content,
+			Critic:        rev.Critic,
+			Score:         rev.Score,
+			Feedback:      rev.Feedback,
+			Selected:      rev.Selected,
+			Error:         rev.Error,
+		})
+	}
+	return r
+}
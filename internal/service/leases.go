@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	pb "github.com/Optiq-CTO/orchestrator/api/proto"
+	"github.com/Optiq-CTO/orchestrator/internal/lease"
+	"github.com/Optiq-CTO/orchestrator/internal/logging"
+	"github.com/Optiq-CTO/orchestrator/internal/metrics"
+	"github.com/Optiq-CTO/orchestrator/internal/runs"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errLeaseLost is the cancellation cause of runs whose lease expired and was
+// taken by another run.
+var errLeaseLost = errors.New("run lease lost")
+
+// leasePoll is how often a run waiting for a lease checks it again.
+const leasePoll = 500 * time.Millisecond
+
+// leaseLost reports whether the run was cancelled for losing its lease.
+func leaseLost(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), errLeaseLost)
+}
+
+// acquireLease takes the lease on the run's flow and the account it posts
+// as (see publishTarget), so no two runs of a flow work on the same account
+// at once. Runs that name no account are rejected. The returned context is
+// cancelled if the lease is lost.
+//
+// When another run holds the lease, the param "on_conflict" or the configured
+// policy decides: reject fails with Aborted, wait runs once the lease is
+// free, and coalesce waits too but returns the other run's result instead of
+// running again.
+func (s *OrchestratorService) acquireLease(ctx context.Context, req *pb.PipelineRequest, rec *runs.Record, account string) (context.Context, func(), *pb.PipelineResponse, error) {
+	if _, known := requiredParams[rec.Flow]; s.leases == nil || !known {
+		// Unknown flows fail when they run.
+		return ctx, func() {}, nil, nil
+	}
+	if account == "" {
+		return nil, nil, nil, status.Errorf(codes.InvalidArgument, "flow %s run names no account to publish as; nothing to lease", rec.Flow)
+	}
+	policy := s.leaseConflict
+	if p := req.Params["on_conflict"]; p != "" {
+		policy = p
+	}
+	if !lease.ValidOnConflict(policy) {
+		return nil, nil, nil, status.Errorf(codes.InvalidArgument, "unknown on_conflict %q; want reject, wait or coalesce", policy)
+	}
+
+	logger := logging.FromContext(ctx)
+	flow := flowLabel(rec.Flow)
+	key := rec.Flow + "/" + account
+	ctx, cancel := context.WithCancelCause(ctx)
+	onLost := func(error) {
+		metrics.LeasesLost.Inc(flow)
+		logger.Error("run lease lost; cancelling the run", "lease", key)
+		cancel(errLeaseLost)
+	}
+
+	// waitingOn is the run whose result a coalescing run returns.
+	var waitingOn string
+	conflicted := false
+	for {
+		held, cur, err := lease.Hold(ctx, s.leases, key, rec.ID, s.leaseTTL, onLost)
+		if err == nil {
+			if res := s.coalesced(ctx, waitingOn); res != nil {
+				held.Release(context.WithoutCancel(ctx))
+				cancel(nil)
+				return nil, nil, res, nil
+			}
+			release := func() {
+				if err := held.Release(context.WithoutCancel(ctx)); err != nil && !errors.Is(err, lease.ErrNotHeld) {
+					logger.Warn("failed to release run lease", "lease", key, "error", err)
+				}
+				cancel(nil)
+			}
+			return ctx, release, nil, nil
+		}
+		if !errors.Is(err, lease.ErrHeld) {
+			cancel(nil)
+			return nil, nil, nil, status.Errorf(codes.Unavailable, "acquiring run lease: %v", err)
+		}
+		if !conflicted {
+			conflicted = true
+			metrics.LeaseConflicts.Inc(flow, policy)
+			logger.Info("account and flow leased by another run", "lease", key, "holder", cur.Holder, "on_conflict", policy)
+		}
+		switch policy {
+		case lease.OnConflictReject:
+			cancel(nil)
+			return nil, nil, nil, status.Errorf(codes.Aborted, "flow %s is already running for account %s in run %s", rec.Flow, account, cur.Holder)
+		case lease.OnConflictCoalesce:
+			if cur.Holder != waitingOn {
+				if res := s.coalesced(ctx, waitingOn); res != nil {
+					cancel(nil)
+					return nil, nil, res, nil
+				}
+				waitingOn = cur.Holder
+			}
+		}
+		select {
+		case <-ctx.Done():
+			cancel(nil)
+			return nil, nil, nil, status.FromContextError(ctx.Err()).Err()
+		case <-time.After(leasePoll):
+		}
+	}
+}
+
+// coalesced returns the result of run id if it has finished, for a
+// coalescing run to return as its own.
+func (s *OrchestratorService) coalesced(ctx context.Context, id string) *pb.PipelineResponse {
+	if id == "" {
+		return nil
+	}
+	rec, err := s.runs.Get(ctx, id)
+	if err != nil || rec.Status == runs.StatusRunning {
+		return nil
+	}
+	logging.FromContext(ctx).Info("coalesced into another run", "coalesced_run", id, "status", rec.Status)
+	return &pb.PipelineResponse{
+		PipelineId:    rec.ID,
+		Status:        rec.Status,
+		OutputUrls:    rec.OutputURLs,
+		ErrorMessage:  rec.Error,
+		ModelProvider: rec.ModelProvider,
+		Withheld:      withheldToProto(rec.Withheld),
+	}
+}
@@ -3,13 +3,36 @@ package service
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	analyzer "github.com/Optiq-CTO/analyzer/api/proto"
 	pb "github.com/Optiq-CTO/orchestrator/api/proto"
 	aicontext "github.com/Optiq-CTO/orchestrator/api/proto/external/aicontext"
 	creator "github.com/Optiq-CTO/orchestrator/api/proto/external/creator"
 	fetcher "github.com/Optiq-CTO/orchestrator/api/proto/external/fetcher"
 	publisher "github.com/Optiq-CTO/orchestrator/api/proto/external/publisher"
+	"github.com/Optiq-CTO/orchestrator/internal/admission"
+	"github.com/Optiq-CTO/orchestrator/internal/analysiscache"
+	"github.com/Optiq-CTO/orchestrator/internal/breaker"
+	"github.com/Optiq-CTO/orchestrator/internal/config"
+	"github.com/Optiq-CTO/orchestrator/internal/deadletter"
+	"github.com/Optiq-CTO/orchestrator/internal/lease"
+	"github.com/Optiq-CTO/orchestrator/internal/logging"
+	"github.com/Optiq-CTO/orchestrator/internal/metrics"
+	"github.com/Optiq-CTO/orchestrator/internal/pause"
+	"github.com/Optiq-CTO/orchestrator/internal/publishing"
+	"github.com/Optiq-CTO/orchestrator/internal/ratelimit"
+	"github.com/Optiq-CTO/orchestrator/internal/redact"
+	"github.com/Optiq-CTO/orchestrator/internal/retry"
+	"github.com/Optiq-CTO/orchestrator/internal/routing"
+	"github.com/Optiq-CTO/orchestrator/internal/runs"
+	"github.com/Optiq-CTO/orchestrator/internal/secrets"
+	"github.com/Optiq-CTO/orchestrator/internal/tenant"
+	"github.com/Optiq-CTO/orchestrator/internal/tokens"
+	"github.com/Optiq-CTO/orchestrator/internal/usage"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
@@ -20,31 +43,432 @@ type OrchestratorService struct {
 	creator   creator.CreatorServiceClient
 	publisher publisher.PublisherServiceClient
 	aicontext aicontext.AIContextServiceClient
+
+	logger  *slog.Logger
+	runs    runs.Store
+	secrets secrets.Store
+	tokens  *tokens.Manager
+	tenants *tenant.Registry
+	usage   *usage.Tracker
+	router  *routing.Router
+
+	analyzer      analyzer.AnalyzerServiceClient
+	analysisCache *analysiscache.Cache
+
+	retries   map[string]retry.Policy
+	breakers  *breaker.Set
+	limiters  *ratelimit.Limiters
+	admission *admission.Controller
+	policies  *publishing.Config
+	outbox    *publishing.Store
+	pauses    *pause.Store
+	leases    lease.Store
+	// leaseTTL and leaseConflict configure the leases; see WithLeases.
+	leaseTTL      time.Duration
+	leaseConflict string
+	deadLetters   *deadletter.Store
+	// pauseDrafts is what paused publishing does with drafts, a
+	// pause.Drafts* value.
+	pauseDrafts atomic.Pointer[string]
+
+	// Flow settings; replaced on config reload.
+	flows atomic.Pointer[map[string]config.Flow]
+
+	// In-flight runs, for draining on shutdown.
+	mu       sync.Mutex
+	draining bool
+	active   map[string]*activeRun
+	inflight sync.WaitGroup
+}
+
+// Option configures optional dependencies of the OrchestratorService.
+type Option func(*OrchestratorService)
+
+// WithLogger sets the base logger; per-run fields are added to it.
+func WithLogger(l *slog.Logger) Option {
+	return func(s *OrchestratorService) { s.logger = l }
+}
+
+// WithSecretStore enables secret://account/key references in pipeline
+// params and the credential admin RPCs.
+func WithSecretStore(st secrets.Store) Option {
+	return func(s *OrchestratorService) { s.secrets = st }
+}
+
+// WithTokenManager blocks runs for accounts that need reauthorization and
+// enables the token lifecycle RPCs.
+func WithTokenManager(m *tokens.Manager) Option {
+	return func(s *OrchestratorService) { s.tokens = m }
+}
+
+// WithRunStore sets where run records are kept. The default keeps the most
+// recent runs in memory.
+func WithRunStore(st runs.Store) Option {
+	return func(s *OrchestratorService) { s.runs = st }
+}
+
+// WithTenants registers tenants and their accounts, so runs can name an
+// account_id instead of passing params and credentials.
+func WithTenants(r *tenant.Registry) Option {
+	return func(s *OrchestratorService) { s.tenants = r }
+}
+
+// WithUsageTracker attributes AI calls to runs, enforces AI budgets and
+// enables the usage RPC. The tracker's client interceptor must be installed
+// on the AI-backed downstream connections for calls to be counted.
+func WithUsageTracker(t *usage.Tracker) Option {
+	return func(s *OrchestratorService) { s.usage = t }
+}
+
+// WithRouter sets provider fallback and weighted routing policies. Without
+// it runs only use the provider they ask for.
+func WithRouter(r *routing.Router) Option {
+	return func(s *OrchestratorService) { s.router = r }
+}
+
+// WithAnalyzer lets best-of-N runs score candidates by risk and sentiment.
+// Without it candidates are scored on platform fit alone.
+func WithAnalyzer(c analyzer.AnalyzerServiceClient) Option {
+	return func(s *OrchestratorService) { s.analyzer = c }
+}
+
+// WithAnalysisCache makes flows analyze fetched items through the
+// orchestrator's analyzer connection, reusing cached analyses of items seen
+// in earlier runs. It needs WithAnalyzer.
+func WithAnalysisCache(c *analysiscache.Cache) Option {
+	return func(s *OrchestratorService) { s.analysisCache = c }
+}
+
+// WithRetryPolicies sets the retry policy of each pipeline step, keyed as
+// config.Config.Retries and used as given; steps without a policy use the
+// "default" one. The defaults are those of config.Default.
+func WithRetryPolicies(p map[string]retry.Policy) Option {
+	return func(s *OrchestratorService) { s.retries = p }
 }
 
-func NewOrchestratorService(f fetcher.FetcherServiceClient, c creator.CreatorServiceClient, p publisher.PublisherServiceClient, ac aicontext.AIContextServiceClient) *OrchestratorService {
-	return &OrchestratorService{
+// WithBreakers enables the circuit breaker RPC. The set's client
+// interceptors must be installed on the downstream connections for the
+// breakers to guard anything.
+func WithBreakers(b *breaker.Set) Option {
+	return func(s *OrchestratorService) { s.breakers = b }
+}
+
+// WithRateLimits attributes platform calls to each run's account, for the
+// limiters' client interceptors on the downstream connections, and enables
+// the rate limit RPC.
+func WithRateLimits(l *ratelimit.Limiters) Option {
+	return func(s *OrchestratorService) { s.limiters = l }
+}
+
+// WithAdmission bounds concurrent runs with c; runs over its limits queue.
+func WithAdmission(c *admission.Controller) Option {
+	return func(s *OrchestratorService) { s.admission = c }
+}
+
+// WithOutbox keeps post history and the posts held back by publishing
+// policies and pauses in store.
+func WithOutbox(store *publishing.Store) Option {
+	return func(s *OrchestratorService) { s.outbox = store }
+}
+
+// WithPublishingPolicies enforces publishing policies before every post.
+// It needs WithOutbox.
+func WithPublishingPolicies(cfg *publishing.Config) Option {
+	return func(s *OrchestratorService) { s.policies = cfg }
+}
+
+// WithPauses enables the publishing kill switch, keeping pauses in store.
+// drafts says what paused publishing does with drafts; see SetPauseDrafts.
+func WithPauses(store *pause.Store, drafts string) Option {
+	return func(s *OrchestratorService) {
+		s.pauses = store
+		s.SetPauseDrafts(drafts)
+		s.observePauses()
+	}
+}
+
+// WithLeases leases each run's account and flow in store for the length of
+// the run, renewed every third of ttl. onConflict is what a run does when
+// another run holds the lease, a lease.OnConflict* value; runs may override
+// it with the param "on_conflict".
+func WithLeases(store lease.Store, ttl time.Duration, onConflict string) Option {
+	return func(s *OrchestratorService) {
+		s.leases = store
+		s.leaseTTL = ttl
+		s.leaseConflict = onConflict
+	}
+}
+
+// WithDeadLetters keeps the items that fail within runs in store, to be
+// retried or discarded.
+func WithDeadLetters(store *deadletter.Store) Option {
+	return func(s *OrchestratorService) { s.deadLetters = store }
+}
+
+// WithFlows sets the flows' tones, limits and timeouts. The defaults are
+// config.DefaultFlows.
+func WithFlows(flows map[string]config.Flow) Option {
+	return func(s *OrchestratorService) { s.SetFlows(flows) }
+}
+
+func NewOrchestratorService(f fetcher.FetcherServiceClient, c creator.CreatorServiceClient, p publisher.PublisherServiceClient, ac aicontext.AIContextServiceClient, opts ...Option) *OrchestratorService {
+	s := &OrchestratorService{
 		fetcher:   f,
 		creator:   c,
 		publisher: p,
 		aicontext: ac,
+		logger:    slog.Default(),
+		runs:      runs.NewMemoryStore(1000),
+		retries:   config.Default().Retries,
+		active:    make(map[string]*activeRun),
+	}
+	s.SetFlows(config.DefaultFlows())
+	for _, opt := range opts {
+		opt(s)
 	}
+	return s
 }
 
 func (s *OrchestratorService) RunPipeline(ctx context.Context, req *pb.PipelineRequest) (*pb.PipelineResponse, error) {
-	log.Printf("Running pipeline: %s", req.FlowName)
+	return s.runPipeline(ctx, req, "")
+}
+
+// runPipeline runs a flow and records it; resumedFrom names the interrupted
+// run it resumes, if any.
+func (s *OrchestratorService) runPipeline(ctx context.Context, req *pb.PipelineRequest, resumedFrom string) (*pb.PipelineResponse, error) {
+	req, acct, err := s.expandAccount(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	accounts := runAccounts(req.Params)
+	if err := s.authorizeRun(ctx, req.FlowName, acct, accounts); err != nil {
+		return nil, err
+	}
+
+	rec := &runs.Record{
+		ID:            runs.NewID(),
+		Flow:          req.FlowName,
+		AccountID:     req.AccountId,
+		User:          runUser(req.Params),
+		ModelProvider: req.ModelProvider,
+		Priority:      req.Priority,
+		Params:        redact.Params(req.Params),
+		Accounts:      accounts,
+		Status:        runs.StatusRunning,
+		ResumedFrom:   resumedFrom,
+		StartedAt:     time.Now().UTC(),
+	}
+	if acct != nil {
+		rec.TenantID = acct.TenantID
+	}
+	logger := s.logger.With("run_id", rec.ID, "flow", rec.Flow, "user", rec.User)
+	if acct != nil {
+		logger = logger.With("tenant", acct.TenantID, "account", acct.ID)
+	}
+	ctx = logging.WithLogger(ctx, logger)
+	account := publishTarget(req.FlowName, rec.AccountID, req.Params)
+	ctx = ratelimit.WithAccount(ctx, account)
+	ctx, unlease, coalesced, err := s.acquireLease(ctx, req, rec, account)
+	if err != nil || coalesced != nil {
+		return coalesced, err
+	}
+	defer unlease()
+	release, err := s.admit(ctx, admission.Request{Priority: req.Priority, Tenant: rec.TenantID, Account: account})
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	ctx, run, err := s.startRun(ctx, rec)
+	if err != nil {
+		return nil, err
+	}
+	defer s.endRun(rec.ID, run)
+	run.target = account
+	run.allOrNothing = s.allOrNothing(req)
+	logger.Info("running pipeline", "model_provider", req.ModelProvider)
+	s.saveRun(ctx, rec)
+
+	flow := flowLabel(req.FlowName)
+	metrics.RunsInFlight.Inc(flow)
+	defer metrics.RunsInFlight.Dec(flow)
+
+	var res *pb.PipelineResponse
+	key := usageKey(req, acct, accounts)
+	ctx, err = s.applyBudget(ctx, req, rec, key)
+	if err == nil {
+		var route *providerRoute
+		if ctx, route, err = s.planRoute(ctx, req, key); err == nil {
+			res, err = s.runFlow(ctx, req)
+		}
+		if route.used != "" {
+			rec.ModelProvider = route.used
+		}
+		rec.Candidates = route.drafts
+		rec.Revisions = route.revisions
+	}
+	if leaseLost(ctx) {
+		res, err = nil, status.Errorf(codes.Aborted, "run %s lost its lease on flow %s for account %s to another run", rec.ID, rec.Flow, account)
+	}
+	if run.allOrNothing && (err != nil || interrupted(ctx)) {
+		s.rollback(ctx, run)
+	}
+	err = redact.Error(err)
+	rec.Steps = run.completedSteps()
+	rec.Posts = run.publishedPosts()
+	rec.Withheld = run.withheldPosts()
+	run.finish()
+	if interrupted(ctx) {
+		// Flows skip items that fail, so an interrupted run may look like
+		// it completed; it is recorded as interrupted either way.
+		if res != nil {
+			rec.OutputURLs = res.OutputUrls
+		}
+		res, err = nil, status.Errorf(codes.Unavailable, "run %s interrupted by server shutdown; resume it with ResumeRun", rec.ID)
+		metrics.RunsTotal.Inc(flow, runs.StatusInterrupted)
+	} else {
+		metrics.RunsTotal.Inc(flow, runStatus(res, err))
+	}
+	s.finishRun(ctx, rec, res, err)
+	if res != nil {
+		res.PipelineId = rec.ID
+		res.ModelProvider = rec.ModelProvider
+		res.ErrorMessage = redact.String(res.ErrorMessage)
+		res.Withheld = withheldToProto(rec.Withheld)
+	}
+	return res, err
+}
+
+// finishRun records the outcome of a run and logs it.
+func (s *OrchestratorService) finishRun(ctx context.Context, rec *runs.Record, res *pb.PipelineResponse, err error) {
+	rec.FinishedAt = time.Now().UTC()
+	logger := logging.FromContext(ctx).With("duration", rec.FinishedAt.Sub(rec.StartedAt))
+	switch {
+	case interrupted(ctx):
+		rec.Status = runs.StatusInterrupted
+		rec.Error = err.Error()
+		logger.Warn("pipeline interrupted", "steps", rec.Steps)
+	case err != nil:
+		rec.Status = runs.StatusFailed
+		rec.Error = err.Error()
+		logger.Error("pipeline failed", "error", err)
+	default:
+		rec.Status = res.Status
+		rec.Error = redact.String(res.ErrorMessage)
+		rec.OutputURLs = res.OutputUrls
+		logger.Info("pipeline finished", "status", res.Status, "outputs", len(res.OutputUrls))
+	}
+	s.saveRun(ctx, rec)
+}
 
-	switch req.FlowName {
+func (s *OrchestratorService) saveRun(ctx context.Context, rec *runs.Record) {
+	if err := s.runs.Save(ctx, rec); err != nil {
+		logging.FromContext(ctx).Warn("failed to save run record", "error", err)
+	}
+}
+
+// publishTarget names the account a run's posts go out as, which
+// publishing policies, pauses, leases and rate limits are keyed by: the
+// registered account, else the page, Twitter user or credential store
+// account the flow publishes to. A param given as a secret reference stands
+// for its store account. cross_pollinator runs without a target_account
+// post as the publisher's own account on the target platform.
+func publishTarget(flow, accountID string, params map[string]string) string {
+	if accountID != "" {
+		return accountID
+	}
+	var v string
+	switch flow {
+	case "facebook_echo":
+		v = params["page_id"]
+	case "twitter_echo":
+		v = params["twitter_user_id"]
 	case "cross_pollinator":
-		return s.runCrossPollinator(ctx, req.Params, req.ModelProvider)
+		v = params["target_account"]
+		if v == "" && params["target_platform"] != "" {
+			return "publisher/" + params["target_platform"]
+		}
+	}
+	if account, _, ok, _ := secrets.ParseRef(v); ok {
+		return account
+	}
+	return v
+}
+
+// runUser identifies the account a run acts for in logs and run records.
+func runUser(params map[string]string) string {
+	for _, k := range []string{"user_id", "page_id", "twitter_user_id"} {
+		if v := params[k]; v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// SetPauseDrafts sets what paused publishing does with drafts: hold them in
+// the outbox for approval, or discard them.
+func (s *OrchestratorService) SetPauseDrafts(drafts string) {
+	s.pauseDrafts.Store(&drafts)
+}
+
+// SetFlows replaces the flow settings; runs already going keep theirs.
+func (s *OrchestratorService) SetFlows(flows map[string]config.Flow) {
+	s.flows.Store(&flows)
+}
+
+// filterItems drops the items the flow's filters reject.
+func filterItems(ctx context.Context, flow string, settings config.Flow, items []*fetcher.FetchedItem) []*fetcher.FetchedItem {
+	kept := items[:0:0]
+	for _, item := range items {
+		if reason := settings.Filters.Reject(item.ContentText, item.GetAnalysis().GetSentiment()); reason != "" {
+			logging.FromContext(ctx).Info("item filtered out", "source_id", item.SourceId, "reason", reason)
+			metrics.ItemsSkipped.Inc(flow, "filtered")
+			continue
+		}
+		kept = append(kept, item)
+	}
+	return kept
+}
+
+// flowSettings returns the settings of a flow, falling back to the defaults
+// for flows the configuration leaves out.
+func (s *OrchestratorService) flowSettings(name string) config.Flow {
+	if f, ok := (*s.flows.Load())[name]; ok {
+		return f
+	}
+	return config.DefaultFlows()[name]
+}
+
+func (s *OrchestratorService) runFlow(ctx context.Context, req *pb.PipelineRequest) (*pb.PipelineResponse, error) {
+	params, err := s.resolveParams(ctx, req.Params)
+	if err != nil {
+		return nil, err
+	}
+	ctx = withCredentialRefs(ctx, paramRefs(req.Params, params))
+	accounts := runAccounts(req.Params)
+	if err := s.checkAccounts(ctx, accounts); err != nil {
+		return nil, err
+	}
+
+	if timeout := s.flowSettings(req.FlowName).Timeout; timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return s.dispatch(ctx, req.FlowName, params, req.ModelProvider)
+}
+
+func (s *OrchestratorService) dispatch(ctx context.Context, flowName string, params map[string]string, modelProvider string) (*pb.PipelineResponse, error) {
+	switch flowName {
+	case "cross_pollinator":
+		return s.runCrossPollinator(ctx, params, modelProvider)
 	case "facebook_echo":
-		return s.runFacebookEcho(ctx, req.Params, req.ModelProvider)
+		return s.runFacebookEcho(ctx, params, modelProvider)
 	case "twitter_echo":
-		return s.runTwitterEcho(ctx, req.Params, req.ModelProvider)
+		return s.runTwitterEcho(ctx, params, modelProvider)
 	case "trend_jacker":
 		return nil, status.Error(codes.Unimplemented, "trend_jacker not implemented yet")
 	default:
-		return nil, status.Errorf(codes.InvalidArgument, "unknown flow: %s", req.FlowName)
+		return nil, status.Errorf(codes.InvalidArgument, "unknown flow: %s", flowName)
 	}
 }
 
@@ -52,72 +476,159 @@ func (s *OrchestratorService) RunPipeline(ctx context.Context, req *pb.PipelineR
 func (s *OrchestratorService) runCrossPollinator(ctx context.Context, params map[string]string, modelProvider string) (*pb.PipelineResponse, error) {
 	query := params["query"]
 	targetPlatform := params["target_platform"]
+	targetAccount := params["target_account"]
 	if query == "" || targetPlatform == "" {
 		return nil, status.Error(codes.InvalidArgument, "missing params: query, target_platform")
 	}
+	// Without a target account the publisher posts with its own credentials.
+	publishCreds := map[string]string{"internal_call": "true"}
+	if targetAccount != "" {
+		if s.secrets == nil {
+			return nil, status.Error(codes.FailedPrecondition, "target_account needs a credential store but none is configured")
+		}
+		stored, err := s.secrets.Credentials(ctx, targetAccount)
+		if err != nil {
+			return nil, status.Errorf(codes.FailedPrecondition, "loading credentials for target account: %v", err)
+		}
+		refs := make(map[string]string, len(stored))
+		for k, v := range stored {
+			refs[v] = secrets.Ref(targetAccount, k)
+		}
+		ctx = withCredentialRefs(ctx, refs)
+		publishCreds = stored
+	}
 
-	// 1. Fetch from Reddit
-	log.Printf("[Orchestrator] Step 1: Fetching from Reddit (query=%s)", query)
-	fetchRes, err := s.fetcher.FetchContent(ctx, &fetcher.FetchRequest{
-		Platform:      "reddit",
-		Query:         query,
-		ModelProvider: modelProvider,
-		Limit:         3,
-	})
-	if err != nil {
-		return nil, fmt.Errorf("fetch failed: %w", err)
+	logger := logging.FromContext(ctx)
+	settings := s.flowSettings("cross_pollinator")
+
+	// Items that fail are dead-lettered with what is needed to retry them.
+	retryParams := map[string]string{"query": query, "target_platform": targetPlatform}
+	if targetAccount != "" {
+		retryParams["target_account"] = targetAccount
+	}
+	itemKey := func(item *fetcher.FetchedItem) string {
+		return "cross_pollinator/" + targetAccount + "/" + targetPlatform + "/" + item.SourceId
+	}
+
+	// 1. Fetch from Reddit, or retry one dead-lettered item
+	var items []*fetcher.FetchedItem
+	var retryDraft string
+	if id := params["dead_letter_id"]; id != "" {
+		dl, err := s.deadLetterFor(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		logger.Info("retrying dead-lettered item", "dead_letter_id", dl.ID, "source_id", dl.SourceID, "failed_step", dl.Step)
+		items = []*fetcher.FetchedItem{{SourceId: dl.SourceID, ContentText: dl.Source}}
+		retryDraft = dl.Draft
+	} else {
+		logger.Info("fetching from reddit", "step", "fetch", "query", query)
+		start := time.Now()
+		fetchRes, err := s.fetch(ctx, &fetcher.FetchRequest{
+			Platform:      "reddit",
+			Query:         query,
+			ModelProvider: modelProvider,
+			Limit:         int32(settings.FetchLimit),
+		})
+		observeStep(ctx, "cross_pollinator", "fetch", start)
+		if err != nil {
+			return nil, fmt.Errorf("fetch failed: %w", err)
+		}
+		items = filterItems(ctx, "cross_pollinator", settings, fetchRes.Items)
 	}
 
 	var outputURLs []string
 
-	// 2. Process Items (Limit to top 1 for demo/MVP to avoid spamming)
-	limit := 1
-	for i, item := range fetchRes.Items {
-		if i >= limit {
+	// 2. Process Items (limited to avoid spamming)
+	// Quarantined items don't count against the limit, so they can't crowd
+	// out the items after them.
+	limit := settings.ItemLimit
+	processed := 0
+	for i, item := range items {
+		itemLogger := logger.With("source_id", item.SourceId)
+		if params["dead_letter_id"] == "" && s.quarantined(itemKey(item)) {
+			itemLogger.Info("item quarantined, skipping it")
+			metrics.ItemsSkipped.Inc("cross_pollinator", "quarantined")
+			continue
+		}
+		if processed >= limit {
+			metrics.ItemsSkipped.Add(float64(len(items)-i), "cross_pollinator", "over_limit")
 			break
 		}
-
-		log.Printf("[Orchestrator] Processing item: %s", item.ContentText[:min(50, len(item.ContentText))])
+		processed++
+		itemLogger.Info("processing item", "chars", len(item.ContentText))
 
 		// Use Summary if available, else raw text
 		contentToRemix := item.ContentText
 		if item.Analysis != nil && item.Analysis.Summary != "" {
 			contentToRemix = item.Analysis.Summary
 		}
+		failed := deadletter.Entry{
+			Key:            itemKey(item),
+			Params:         retryParams,
+			SourcePlatform: "reddit",
+			SourceID:       item.SourceId,
+			Source:         contentToRemix,
+		}
 
-		// 3. Remix Content
-		log.Printf("[Orchestrator] Step 2: Remixing for %s", targetPlatform)
-		remixRes, err := s.creator.RemixContent(ctx, &creator.RemixRequest{
-			OriginalContent: contentToRemix,
-			SourcePlatform:  "reddit",
-			TargetPlatform:  targetPlatform,
-			Tone:            "professional", // default for LinkedIn
-			ModelProvider:   modelProvider,
-		})
-		if err != nil {
-			log.Printf("Remix failed for item %s: %v", item.SourceId, err)
-			continue
+		// 3. Remix Content, unless a retried item already has a draft
+		draft := retryDraft
+		if draft == "" {
+			itemLogger.Info("remixing", "step", "remix", "target_platform", targetPlatform)
+			start := time.Now()
+			remixRes, err := s.generateDraft(ctx, targetPlatform, settings.Tone, modelProvider, func(ctx context.Context, provider string) (*creator.GenerateResponse, error) {
+				return s.creator.RemixContent(ctx, &creator.RemixRequest{
+					OriginalContent: contentToRemix,
+					SourcePlatform:  "reddit",
+					TargetPlatform:  targetPlatform,
+					Tone:            settings.Tone,
+					ModelProvider:   provider,
+				})
+			})
+			observeStep(ctx, "cross_pollinator", "remix", start)
+			if err != nil {
+				itemLogger.Warn("remix failed, skipping item", "step", "remix", "error", err)
+				metrics.ItemsSkipped.Inc("cross_pollinator", "remix_failed")
+				failed.Step = "remix"
+				s.deadLetter(ctx, failed, err)
+				continue
+			}
+			draft = remixRes.Content
 		}
 
 		// 4. Publish
-		log.Printf("[Orchestrator] Step 3: Publishing to %s", targetPlatform)
-		pubRes, err := s.publisher.PublishContent(ctx, &publisher.PublishRequest{
-			Content:  remixRes.Content,
-			Platform: targetPlatform,
-			// For MVP, passing dummy internal credential. In real world, Orchestrator might fetch this from Vault.
-			Credentials: map[string]string{"internal_call": "true"},
+		itemLogger.Info("publishing", "step", "publish", "platform", targetPlatform)
+		start := time.Now()
+		pubRes, err := s.publish(ctx, &publisher.PublishRequest{
+			Content:     draft,
+			Platform:    targetPlatform,
+			Credentials: publishCreds,
 		})
+		observeStep(ctx, "cross_pollinator", "publish", start)
+		if isWithheld(err) {
+			itemLogger.Info("post withheld, skipping item", "step", "publish", "reason", err)
+			metrics.ItemsSkipped.Inc("cross_pollinator", "publish_withheld")
+			s.resolveDeadLetter(ctx, failed.Key)
+			continue
+		}
+		if err != nil && allOrNothingRun(ctx) {
+			return nil, fmt.Errorf("publish failed: %w", err)
+		}
 		if err != nil {
-			log.Printf("Publish failed for item %s: %v", item.SourceId, err)
+			itemLogger.Warn("publish failed, skipping item", "step", "publish", "error", err)
+			metrics.ItemsSkipped.Inc("cross_pollinator", "publish_failed")
+			failed.Step, failed.Draft = "publish", draft
+			s.deadLetter(ctx, failed, err)
 			continue
 		}
 
-		log.Printf("Successfully published: %s", pubRes.PostUrl)
+		itemLogger.Info("published", "step", "publish", "post_url", pubRes.PostUrl)
+		metrics.PostsPublished.Inc(targetPlatform)
 		outputURLs = append(outputURLs, pubRes.PostUrl)
+		s.resolveDeadLetter(ctx, failed.Key)
 	}
 
 	return &pb.PipelineResponse{
-		PipelineId: "pipeline-123", // UUID in future
 		Status:     "completed",
 		OutputUrls: outputURLs,
 	}, nil
@@ -131,38 +642,53 @@ func (s *OrchestratorService) runFacebookEcho(ctx context.Context, params map[st
 		return nil, status.Error(codes.InvalidArgument, "missing params: page_id, access_token")
 	}
 
+	logger := logging.FromContext(ctx)
+	settings := s.flowSettings("facebook_echo")
+
 	// 1. Fetch from Facebook
-	log.Printf("[Orchestrator] Step 1: Fetching from Facebook page %s", pageID)
-	fetchRes, err := s.fetcher.FetchContent(ctx, &fetcher.FetchRequest{
+	logger.Info("fetching from facebook", "step", "fetch", "page_id", pageID)
+	start := time.Now()
+	fetchRes, err := s.fetch(ctx, &fetcher.FetchRequest{
 		Platform: "meta",
 		Query:    pageID,
 		Credentials: map[string]string{
 			"access_token": accessToken,
 		},
 		ModelProvider: modelProvider,
-		Limit:         1,
+		Limit:         int32(settings.FetchLimit),
 	})
+	observeStep(ctx, "facebook_echo", "fetch", start)
 	if err != nil {
 		return nil, fmt.Errorf("fetch failed: %w", err)
 	}
 
 	if len(fetchRes.Items) == 0 {
 		return &pb.PipelineResponse{
-			PipelineId:   "pipeline-fb-echo",
 			Status:       "completed",
 			ErrorMessage: "No posts found on the page",
 		}, nil
 	}
+	items := filterItems(ctx, "facebook_echo", settings, fetchRes.Items)
+	if len(items) == 0 {
+		return &pb.PipelineResponse{
+			Status:       "completed",
+			ErrorMessage: "No posts on the page pass the flow's filters",
+		}, nil
+	}
 
 	// Get the most recent post
-	latestPost := fetchRes.Items[0]
-	log.Printf("[Orchestrator] Processing latest post: %s", latestPost.ContentText[:min(50, len(latestPost.ContentText))])
+	latestPost := items[0]
+	logger.Info("processing latest post", "source_id", latestPost.SourceId, "chars", len(latestPost.ContentText))
 
 	// 2. Get AI Context
-	log.Printf("[Orchestrator] Step 2: Fetching AI context for page %s", pageID)
-	ctxRes, _ := s.aicontext.GetUserContext(ctx, &aicontext.GetUserContextRequest{
-		User: &aicontext.User{Platform: "facebook", UserId: pageID},
+	logger.Info("fetching AI context", "step", "get_context")
+	start = time.Now()
+	ctxRes, _ := retryStep(ctx, s, "get_context", retry.Transient, func(ctx context.Context) (*aicontext.UserContext, error) {
+		return s.aicontext.GetUserContext(ctx, &aicontext.GetUserContextRequest{
+			User: &aicontext.User{Platform: "facebook", UserId: pageID},
+		})
 	})
+	observeStep(ctx, "facebook_echo", "get_context", start)
 
 	var analysisContext string
 	if latestPost.Analysis != nil {
@@ -177,20 +703,25 @@ func (s *OrchestratorService) runFacebookEcho(ctx context.Context, params map[st
 	}
 
 	// 3. Generate contextual response
-	log.Printf("[Orchestrator] Step 3: Generating response based on analysis and context")
-	generateRes, err := s.creator.GenerateContent(ctx, &creator.GenerateRequest{
-		Topic:         prompt,
-		Platform:      "facebook",
-		Tone:          "friendly",
-		ModelProvider: modelProvider,
+	logger.Info("generating response", "step", "generate")
+	start = time.Now()
+	generateRes, err := s.generateDraft(ctx, "facebook", settings.Tone, modelProvider, func(ctx context.Context, provider string) (*creator.GenerateResponse, error) {
+		return s.creator.GenerateContent(ctx, &creator.GenerateRequest{
+			Topic:         prompt,
+			Platform:      "facebook",
+			Tone:          settings.Tone,
+			ModelProvider: provider,
+		})
 	})
+	observeStep(ctx, "facebook_echo", "generate", start)
 	if err != nil {
 		return nil, fmt.Errorf("content generation failed: %w", err)
 	}
 
 	// 4. Publish response to Facebook
-	log.Printf("[Orchestrator] Step 4: Publishing response to Facebook")
-	pubRes, err := s.publisher.PublishContent(ctx, &publisher.PublishRequest{
+	logger.Info("publishing response", "step", "publish", "platform", "facebook")
+	start = time.Now()
+	pubRes, err := s.publish(ctx, &publisher.PublishRequest{
 		Content:  generateRes.Content,
 		Platform: "facebook",
 		Credentials: map[string]string{
@@ -198,13 +729,19 @@ func (s *OrchestratorService) runFacebookEcho(ctx context.Context, params map[st
 			"access_token": accessToken,
 		},
 	})
+	observeStep(ctx, "facebook_echo", "publish", start)
+	if isWithheld(err) {
+		return &pb.PipelineResponse{Status: "completed", ErrorMessage: err.Error()}, nil
+	}
 	if err != nil {
 		return nil, fmt.Errorf("publish failed: %w", err)
 	}
+	metrics.PostsPublished.Inc("facebook")
 
 	// 5. Update AI Context
-	log.Printf("[Orchestrator] Step 5: Updating AI context with new interaction")
-	s.aicontext.UpdateUserContext(ctx, &aicontext.UpdateUserContextRequest{
+	logger.Info("updating AI context", "step", "update_context")
+	start = time.Now()
+	_, err = s.aicontext.UpdateUserContext(ctx, &aicontext.UpdateUserContextRequest{
 		User: &aicontext.User{Platform: "facebook", UserId: pageID},
 		NewInteraction: &aicontext.Interaction{
 			PostId:          pubRes.PostId,
@@ -213,11 +750,17 @@ func (s *OrchestratorService) runFacebookEcho(ctx context.Context, params map[st
 			AnalysisSummary: analysisContext, // Or some other summary
 		},
 	})
+	observeStep(ctx, "facebook_echo", "update_context", start)
+	if err != nil {
+		if allOrNothingRun(ctx) {
+			return nil, fmt.Errorf("context update failed: %w", err)
+		}
+		logger.Warn("context update failed", "step", "update_context", "error", err)
+	}
 
-	log.Printf("Successfully published echo response: %s", pubRes.PostUrl)
+	logger.Info("published echo response", "post_url", pubRes.PostUrl)
 
 	return &pb.PipelineResponse{
-		PipelineId: "pipeline-fb-echo",
 		Status:     "completed",
 		OutputUrls: []string{pubRes.PostUrl},
 	}, nil
@@ -236,36 +779,51 @@ func (s *OrchestratorService) runTwitterEcho(ctx context.Context, params map[str
 		return nil, status.Error(codes.InvalidArgument, "missing params: twitter_user_id, twitter_bearer_token")
 	}
 
+	logger := logging.FromContext(ctx)
+	settings := s.flowSettings("twitter_echo")
+
 	// 1. Fetch from Twitter
-	log.Printf("[Orchestrator] Step 1: Fetching from Twitter user %s", userID)
-	fetchRes, err := s.fetcher.FetchContent(ctx, &fetcher.FetchRequest{
+	logger.Info("fetching from twitter", "step", "fetch", "twitter_user_id", userID)
+	start := time.Now()
+	fetchRes, err := s.fetch(ctx, &fetcher.FetchRequest{
 		Platform: "twitter",
 		Query:    "id:" + userID,
 		Credentials: map[string]string{
 			"twitter_bearer_token": bearerToken,
 		},
 		ModelProvider: modelProvider,
-		Limit:         1,
+		Limit:         int32(settings.FetchLimit),
 	})
+	observeStep(ctx, "twitter_echo", "fetch", start)
 	if err != nil {
 		return nil, fmt.Errorf("fetch failed: %w", err)
 	}
 
 	if len(fetchRes.Items) == 0 {
 		return &pb.PipelineResponse{
-			PipelineId:   "pipeline-tw-echo",
 			Status:       "completed",
 			ErrorMessage: "No tweets found for the user",
 		}, nil
 	}
+	items := filterItems(ctx, "twitter_echo", settings, fetchRes.Items)
+	if len(items) == 0 {
+		return &pb.PipelineResponse{
+			Status:       "completed",
+			ErrorMessage: "No tweets of the user pass the flow's filters",
+		}, nil
+	}
 
-	latestTweet := fetchRes.Items[0]
+	latestTweet := items[0]
 
 	// 2. Get AI Context
-	log.Printf("[Orchestrator] Step 2: Fetching AI context for twitter user %s", userID)
-	ctxRes, _ := s.aicontext.GetUserContext(ctx, &aicontext.GetUserContextRequest{
-		User: &aicontext.User{Platform: "twitter", UserId: userID},
+	logger.Info("fetching AI context", "step", "get_context")
+	start = time.Now()
+	ctxRes, _ := retryStep(ctx, s, "get_context", retry.Transient, func(ctx context.Context) (*aicontext.UserContext, error) {
+		return s.aicontext.GetUserContext(ctx, &aicontext.GetUserContextRequest{
+			User: &aicontext.User{Platform: "twitter", UserId: userID},
+		})
 	})
+	observeStep(ctx, "twitter_echo", "get_context", start)
 
 	prompt := fmt.Sprintf("Create a short, engaging tweet in response to this: '%s'. Keep it under 280 chars.", latestTweet.ContentText)
 	if ctxRes != nil && ctxRes.Summary != "" {
@@ -273,20 +831,25 @@ func (s *OrchestratorService) runTwitterEcho(ctx context.Context, params map[str
 	}
 
 	// 3. Generate
-	log.Printf("[Orchestrator] Step 3: Generating tweet")
-	generateRes, err := s.creator.GenerateContent(ctx, &creator.GenerateRequest{
-		Topic:         prompt,
-		Platform:      "twitter",
-		Tone:          "witty",
-		ModelProvider: modelProvider,
+	logger.Info("generating tweet", "step", "generate")
+	start = time.Now()
+	generateRes, err := s.generateDraft(ctx, "twitter", settings.Tone, modelProvider, func(ctx context.Context, provider string) (*creator.GenerateResponse, error) {
+		return s.creator.GenerateContent(ctx, &creator.GenerateRequest{
+			Topic:         prompt,
+			Platform:      "twitter",
+			Tone:          settings.Tone,
+			ModelProvider: provider,
+		})
 	})
+	observeStep(ctx, "twitter_echo", "generate", start)
 	if err != nil {
 		return nil, fmt.Errorf("content generation failed: %w", err)
 	}
 
 	// 4. Publish
-	log.Printf("[Orchestrator] Step 4: Publishing to X")
-	pubRes, err := s.publisher.PublishContent(ctx, &publisher.PublishRequest{
+	logger.Info("publishing tweet", "step", "publish", "platform", "twitter")
+	start = time.Now()
+	pubRes, err := s.publish(ctx, &publisher.PublishRequest{
 		Content:  generateRes.Content,
 		Platform: "twitter",
 		Credentials: map[string]string{
@@ -296,12 +859,19 @@ func (s *OrchestratorService) runTwitterEcho(ctx context.Context, params map[str
 			"twitter_access_token_secret": accessSecret,
 		},
 	})
+	observeStep(ctx, "twitter_echo", "publish", start)
+	if isWithheld(err) {
+		return &pb.PipelineResponse{Status: "completed", ErrorMessage: err.Error()}, nil
+	}
 	if err != nil {
 		return nil, fmt.Errorf("publish failed: %w", err)
 	}
+	metrics.PostsPublished.Inc("twitter")
 
 	// 5. Update AI Context
-	s.aicontext.UpdateUserContext(ctx, &aicontext.UpdateUserContextRequest{
+	logger.Info("updating AI context", "step", "update_context")
+	start = time.Now()
+	_, err = s.aicontext.UpdateUserContext(ctx, &aicontext.UpdateUserContextRequest{
 		User: &aicontext.User{Platform: "twitter", UserId: userID},
 		NewInteraction: &aicontext.Interaction{
 			PostId:    pubRes.PostId,
@@ -309,17 +879,50 @@ func (s *OrchestratorService) runTwitterEcho(ctx context.Context, params map[str
 			Direction: "outbound",
 		},
 	})
+	observeStep(ctx, "twitter_echo", "update_context", start)
+	if err != nil {
+		if allOrNothingRun(ctx) {
+			return nil, fmt.Errorf("context update failed: %w", err)
+		}
+		logger.Warn("context update failed", "step", "update_context", "error", err)
+	}
+
+	logger.Info("published tweet", "post_url", pubRes.PostUrl)
 
 	return &pb.PipelineResponse{
-		PipelineId: "pipeline-tw-echo",
 		Status:     "completed",
 		OutputUrls: []string{pubRes.PostUrl},
 	}, nil
 }
 
-func min(a, b int) int {
-	if a < b {
-		return a
+// knownFlows bounds the flow label on metrics so arbitrary flow names sent by
+// clients cannot create unbounded series.
+var knownFlows = map[string]bool{
+	"cross_pollinator": true,
+	"facebook_echo":    true,
+	"twitter_echo":     true,
+	"trend_jacker":     true,
+}
+
+func flowLabel(name string) string {
+	if knownFlows[name] {
+		return name
+	}
+	return "unknown"
+}
+
+// runStatus maps a flow outcome to the status label of orchestrator_runs_total.
+func runStatus(res *pb.PipelineResponse, err error) string {
+	if err != nil {
+		return "failed"
+	}
+	return res.Status
+}
+
+// observeStep records a step's duration and, for runs, that it was reached.
+func observeStep(ctx context.Context, flow, step string, start time.Time) {
+	metrics.StepDuration.Observe(time.Since(start).Seconds(), flow, step)
+	if run := activeRunFromContext(ctx); run != nil {
+		run.stepDone(step)
 	}
-	return b
 }
@@ -0,0 +1,422 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	pb "github.com/Optiq-CTO/orchestrator/api/proto"
+	publisher "github.com/Optiq-CTO/orchestrator/api/proto/external/publisher"
+	"github.com/Optiq-CTO/orchestrator/internal/breaker"
+	"github.com/Optiq-CTO/orchestrator/internal/logging"
+	"github.com/Optiq-CTO/orchestrator/internal/metrics"
+	"github.com/Optiq-CTO/orchestrator/internal/pause"
+	"github.com/Optiq-CTO/orchestrator/internal/publishing"
+	"github.com/Optiq-CTO/orchestrator/internal/ratelimit"
+	"github.com/Optiq-CTO/orchestrator/internal/redact"
+	"github.com/Optiq-CTO/orchestrator/internal/retry"
+	"github.com/Optiq-CTO/orchestrator/internal/runs"
+	"github.com/Optiq-CTO/orchestrator/internal/secrets"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// maxDeliveryAttempts bounds how often an outbox entry is retried after
+// failures that provably posted nothing.
+const maxDeliveryAttempts = 5
+
+// withheldError reports a post a publishing policy kept from going out.
+type withheldError struct{ w runs.Withheld }
+
+func (e *withheldError) Error() string {
+	switch e.w.Action {
+	case publishing.ActionDefer:
+		return fmt.Sprintf("post to %s deferred to outbox entry %s until %s: %s", e.w.Platform, e.w.OutboxID, e.w.NotBefore.Format(time.RFC3339), e.w.Reason)
+	case pause.DraftsHold:
+		return fmt.Sprintf("post to %s held for approval as outbox entry %s: %s", e.w.Platform, e.w.OutboxID, e.w.Reason)
+	case pause.DraftsDiscard:
+		return fmt.Sprintf("post to %s discarded: %s", e.w.Platform, e.w.Reason)
+	}
+	return fmt.Sprintf("post to %s dropped by publishing policy: %s", e.w.Platform, e.w.Reason)
+}
+
+// GRPCStatus implements the interface status.FromError looks for.
+func (e *withheldError) GRPCStatus() *status.Status {
+	return status.New(codes.FailedPrecondition, e.Error())
+}
+
+func isWithheld(err error) bool {
+	var w *withheldError
+	return errors.As(err, &w)
+}
+
+// checkPolicy enforces the publishing policy of the run's account right
+// before a post goes out. A post the policy allows is counted at once;
+// release must be called with whether it may have been posted, so provably
+// failed posts are not counted. A post it does not allow is deferred to the
+// outbox or dropped, recorded on the run, and reported as a withheldError.
+func (s *OrchestratorService) checkPolicy(ctx context.Context, req *publisher.PublishRequest) (release func(posted bool), err error) {
+	if s.policies == nil {
+		return func(bool) {}, nil
+	}
+	logger := logging.FromContext(ctx)
+	run, _, account := runOf(ctx)
+	d, undo, err := s.outbox.Reserve(s.policies.Policy(account, req.Platform), account, req.Platform)
+	if err != nil {
+		logger.Warn("failed to persist post history", "error", err)
+	}
+	if d.Allowed {
+		return func(posted bool) {
+			if !posted {
+				undo()
+			}
+		}, nil
+	}
+
+	w := runs.Withheld{Platform: req.Platform, Action: d.Action, Reason: d.Reason}
+	if w.Action == publishing.ActionDefer && d.Retry.IsZero() {
+		w.Action = publishing.ActionDrop
+		w.Reason += "; no allowed time in sight"
+	}
+	if w.Action == publishing.ActionDefer {
+		return nil, s.deferPost(ctx, req, w.Reason, d.Retry)
+	}
+	if run != nil {
+		run.withhold(w)
+	}
+	metrics.PostsWithheld.Inc(req.Platform, w.Action)
+	logger.Info("post withheld by publishing policy", "platform", req.Platform, "action", w.Action, "reason", w.Reason)
+	return nil, &withheldError{w}
+}
+
+// deferPost holds a post in the outbox until notBefore, records it on the
+// run, and reports it as a withheldError.
+func (s *OrchestratorService) deferPost(ctx context.Context, req *publisher.PublishRequest, reason string, notBefore time.Time) error {
+	logger := logging.FromContext(ctx)
+	run, base, account := runOf(ctx)
+	e := s.outboxEntry(ctx, req, base, account, reason)
+	e.NotBefore = notBefore
+	e, err := s.outbox.Defer(e)
+	if err != nil {
+		logger.Error("failed to persist outbox", "error", err)
+	}
+	w := runs.Withheld{Platform: req.Platform, Action: publishing.ActionDefer, Reason: reason, OutboxID: e.ID, NotBefore: e.NotBefore}
+	if run != nil {
+		run.withhold(w)
+	}
+	metrics.PostsWithheld.Inc(req.Platform, w.Action)
+	logger.Info("post deferred to outbox", "platform", req.Platform, "reason", reason, "outbox_id", e.ID, "not_before", e.NotBefore)
+	return &withheldError{w}
+}
+
+// runOf returns the run a publish belongs to, its record as started, and
+// the account its policies and pauses are keyed by; see publishTarget.
+func runOf(ctx context.Context) (*activeRun, runs.Record, string) {
+	run := activeRunFromContext(ctx)
+	if run == nil {
+		return nil, runs.Record{}, ""
+	}
+	return run, run.base, run.target
+}
+
+// outboxEntry describes a post held back from a run.
+func (s *OrchestratorService) outboxEntry(ctx context.Context, req *publisher.PublishRequest, base runs.Record, account, reason string) publishing.Entry {
+	creds, plain := s.splitCredentials(ctx, req.Credentials)
+	return publishing.Entry{
+		RunID:       base.ID,
+		Flow:        base.Flow,
+		Tenant:      base.TenantID,
+		Account:     account,
+		Accounts:    base.Accounts,
+		Platform:    req.Platform,
+		Content:     req.Content,
+		Credentials: creds,
+		Plain:       plain,
+		Reason:      reason,
+	}
+}
+
+// splitCredentials prepares publish credentials for the outbox: values that
+// came from secret references are stored as the references, and plain
+// values are kept in memory only, stored as empty strings.
+func (s *OrchestratorService) splitCredentials(ctx context.Context, creds map[string]string) (stored, plain map[string]string) {
+	refs := credentialRefs(ctx)
+	stored = make(map[string]string, len(creds))
+	for k, v := range creds {
+		if ref, ok := refs[v]; ok {
+			stored[k] = ref
+			continue
+		}
+		stored[k] = ""
+		if plain == nil {
+			plain = make(map[string]string)
+		}
+		plain[k] = v
+	}
+	return stored, plain
+}
+
+type credentialRefsKey struct{}
+
+// withCredentialRefs remembers which secret reference each resolved
+// credential value came from, so deferred posts are stored without them.
+func withCredentialRefs(ctx context.Context, refs map[string]string) context.Context {
+	merged := make(map[string]string, len(refs))
+	for v, ref := range credentialRefs(ctx) {
+		merged[v] = ref
+	}
+	for v, ref := range refs {
+		merged[v] = ref
+	}
+	return context.WithValue(ctx, credentialRefsKey{}, merged)
+}
+
+func credentialRefs(ctx context.Context) map[string]string {
+	refs, _ := ctx.Value(credentialRefsKey{}).(map[string]string)
+	return refs
+}
+
+// paramRefs maps the resolved values of params given as secret references
+// back to the references.
+func paramRefs(raw, resolved map[string]string) map[string]string {
+	refs := make(map[string]string)
+	for k, v := range raw {
+		if _, _, ok, _ := secrets.ParseRef(v); ok && resolved[k] != "" {
+			refs[resolved[k]] = v
+		}
+	}
+	return refs
+}
+
+// DeliverOutbox publishes deferred posts once their policies allow them,
+// checking every interval until ctx is done.
+func (s *OrchestratorService) DeliverOutbox(ctx context.Context, interval time.Duration) {
+	if s.outbox == nil {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		for _, e := range s.outbox.Due() {
+			if ctx.Err() != nil {
+				return
+			}
+			s.deliver(ctx, e)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// deliver publishes one due outbox entry, checking its policy again first:
+// the post may be deferred again, or dropped.
+func (s *OrchestratorService) deliver(ctx context.Context, e publishing.Entry) {
+	logger := s.logger.With("outbox_id", e.ID, "run_id", e.RunID, "platform", e.Platform, "account", e.Account)
+	ctx = ratelimit.WithAccount(logging.WithLogger(ctx, logger), e.Account)
+	if s.pauses != nil {
+		if p, ok := s.pauses.Match(pause.Target{Platform: e.Platform, Account: e.Account, Flow: e.Flow}); ok {
+			logger.Debug("publishing paused, deferred post waits", "pause", p.Describe())
+			return
+		}
+	}
+
+	// Claim the entry, so it cannot be cancelled or delivered twice while
+	// it is being sent. Every outcome below is recorded from sending.
+	e, err := s.outbox.Claim(e.ID)
+	if errors.Is(err, publishing.ErrConflict) || errors.Is(err, publishing.ErrNotFound) {
+		logger.Debug("outbox entry changed before delivery, skipping it", "error", err)
+		return
+	}
+	if err != nil {
+		logger.Warn("failed to persist outbox claim", "error", err)
+	}
+	e.Status = publishing.StatusPending
+
+	var policy publishing.Policy
+	if s.policies != nil {
+		policy = s.policies.Policy(e.Account, e.Platform)
+	}
+	d, undo, err := s.outbox.Reserve(policy, e.Account, e.Platform)
+	if err != nil {
+		logger.Warn("failed to persist post history", "error", err)
+	}
+	result := ""
+	switch {
+	case !d.Allowed && (d.Action == publishing.ActionDrop || d.Retry.IsZero()):
+		e.Status, e.Reason = publishing.StatusDropped, d.Reason
+		result = "dropped"
+		logger.Info("deferred post dropped by publishing policy", "reason", d.Reason)
+	case !d.Allowed:
+		e.NotBefore, e.Reason = d.Retry, d.Reason
+		logger.Info("deferred post deferred again", "reason", d.Reason, "not_before", d.Retry)
+	default:
+		result = s.send(ctx, &e, undo)
+	}
+	if result != "" {
+		metrics.OutboxDeliveries.Inc(e.Platform, result)
+	}
+	if err := s.outbox.Update(e, publishing.StatusSending); err != nil {
+		logger.Error("failed to update outbox entry", "error", err)
+	}
+}
+
+func (s *OrchestratorService) send(ctx context.Context, e *publishing.Entry, undo func()) (result string) {
+	logger := logging.FromContext(ctx)
+	creds, err := s.entryCredentials(ctx, e)
+	if err != nil {
+		undo()
+		e.Status, e.Error = publishing.StatusFailed, err.Error()
+		logger.Warn("deferred post cannot be delivered", "error", err)
+		return "failed"
+	}
+	res, err := s.sendPost(ctx, &publisher.PublishRequest{Content: e.Content, Platform: e.Platform, Credentials: creds})
+	if retryAt, open := breaker.OpenUntil(err); open {
+		// Nothing was sent; wait out the cool-down without using an attempt.
+		undo()
+		e.NotBefore, e.Error = retryAt, redact.String(err.Error())
+		logger.Info("circuit breaker open, deferred post waits", "not_before", retryAt)
+		return "deferred"
+	}
+	e.Attempts++
+	if err != nil {
+		e.Error = redact.String(err.Error())
+		if retry.BeforeCreate(err) && e.Attempts < maxDeliveryAttempts {
+			// Nothing was posted; try again on the next pass.
+			undo()
+			logger.Warn("deferred post not delivered, will retry", "attempt", e.Attempts, "error", err)
+			return "retried"
+		}
+		if retry.BeforeCreate(err) {
+			undo()
+		}
+		e.Status = publishing.StatusFailed
+		logger.Warn("deferred post failed", "error", err)
+		return "failed"
+	}
+	e.Status, e.Error, e.PostID, e.PostURL = publishing.StatusPublished, "", res.PostId, res.PostUrl
+	metrics.PostsPublished.Inc(e.Platform)
+	logger.Info("deferred post published", "post_url", res.PostUrl)
+	return "published"
+}
+
+// entryCredentials resolves an entry's stored secret references and fills
+// in its plain credentials, which do not survive a restart.
+func (s *OrchestratorService) entryCredentials(ctx context.Context, e *publishing.Entry) (map[string]string, error) {
+	refs := make(map[string]string, len(e.Credentials))
+	for k, v := range e.Credentials {
+		if v != "" {
+			refs[k] = v
+		}
+	}
+	creds, err := s.resolveParams(ctx, refs)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range e.Credentials {
+		if v != "" {
+			continue
+		}
+		plain, ok := e.Plain[k]
+		if !ok {
+			return nil, fmt.Errorf("credential %s was passed as a plain value and did not survive a restart; pass credentials as secret references", k)
+		}
+		creds[k] = plain
+	}
+	return creds, nil
+}
+
+func (s *OrchestratorService) ListOutbox(ctx context.Context, req *pb.ListOutboxRequest) (*pb.ListOutboxResponse, error) {
+	if s.outbox == nil {
+		return nil, status.Error(codes.FailedPrecondition, "no outbox is configured")
+	}
+	res := &pb.ListOutboxResponse{}
+	for _, e := range s.outbox.Entries(req.Status) {
+		if (req.Account != "" && e.Account != req.Account) || !canSeeEntry(ctx, &e) {
+			continue
+		}
+		res.Entries = append(res.Entries, outboxEntryToProto(&e))
+	}
+	return res, nil
+}
+
+func (s *OrchestratorService) CancelOutboxEntry(ctx context.Context, req *pb.CancelOutboxEntryRequest) (*pb.OutboxEntry, error) {
+	e, err := s.outboxEntryFor(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	if e.Status != publishing.StatusPending && e.Status != publishing.StatusHeld {
+		return nil, status.Errorf(codes.FailedPrecondition, "outbox entry %s is %s; only pending and held entries can be cancelled", e.ID, e.Status)
+	}
+	from := e.Status
+	e.Status = publishing.StatusCancelled
+	if err := s.outbox.Update(e, from); err != nil {
+		return nil, outboxUpdateError(err)
+	}
+	logging.FromContext(ctx).Info("outbox entry cancelled", "outbox_id", e.ID)
+	return outboxEntryToProto(&e), nil
+}
+
+func (s *OrchestratorService) ApproveOutboxEntry(ctx context.Context, req *pb.ApproveOutboxEntryRequest) (*pb.OutboxEntry, error) {
+	e, err := s.outboxEntryFor(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	if e.Status != publishing.StatusHeld {
+		return nil, status.Errorf(codes.FailedPrecondition, "outbox entry %s is %s; only held entries need approval", e.ID, e.Status)
+	}
+	// Delivery still waits for the pause to lift and checks the policy.
+	e.Status, e.NotBefore = publishing.StatusPending, time.Now().UTC()
+	if err := s.outbox.Update(e, publishing.StatusHeld); err != nil {
+		return nil, outboxUpdateError(err)
+	}
+	logging.FromContext(ctx).Info("outbox entry approved", "outbox_id", e.ID)
+	return outboxEntryToProto(&e), nil
+}
+
+func outboxUpdateError(err error) error {
+	if errors.Is(err, publishing.ErrConflict) {
+		return status.Errorf(codes.Aborted, "%v; read it again and retry", err)
+	}
+	return status.Errorf(codes.Internal, "updating outbox: %v", err)
+}
+
+// outboxEntryFor returns an entry the caller may see.
+func (s *OrchestratorService) outboxEntryFor(ctx context.Context, id string) (publishing.Entry, error) {
+	if s.outbox == nil {
+		return publishing.Entry{}, status.Error(codes.FailedPrecondition, "no outbox is configured")
+	}
+	e, err := s.outbox.Get(id)
+	if errors.Is(err, publishing.ErrNotFound) || (err == nil && !canSeeEntry(ctx, &e)) {
+		return publishing.Entry{}, status.Errorf(codes.NotFound, "outbox entry %s not found", id)
+	}
+	return e, err
+}
+
+// canSeeEntry applies the caller's allowlists to the run that deferred e.
+func canSeeEntry(ctx context.Context, e *publishing.Entry) bool {
+	return canSeeRun(ctx, &runs.Record{Flow: e.Flow, TenantID: e.Tenant, Accounts: e.Accounts})
+}
+
+func outboxEntryToProto(e *publishing.Entry) *pb.OutboxEntry {
+	return &pb.OutboxEntry{
+		Id:        e.ID,
+		RunId:     e.RunID,
+		FlowName:  e.Flow,
+		TenantId:  e.Tenant,
+		Account:   e.Account,
+		Platform:  e.Platform,
+		Content:   e.Content,
+		Reason:    e.Reason,
+		NotBefore: e.NotBefore.Format(time.RFC3339),
+		CreatedAt: e.CreatedAt.Format(time.RFC3339),
+		UpdatedAt: e.UpdatedAt.Format(time.RFC3339),
+		Status:    e.Status,
+		Attempts:  int32(e.Attempts),
+		Error:     e.Error,
+		PostUrl:   e.PostURL,
+	}
+}
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	pb "github.com/Optiq-CTO/orchestrator/api/proto"
+	publisher "github.com/Optiq-CTO/orchestrator/api/proto/external/publisher"
+	"github.com/Optiq-CTO/orchestrator/internal/auth"
+	"github.com/Optiq-CTO/orchestrator/internal/logging"
+	"github.com/Optiq-CTO/orchestrator/internal/metrics"
+	"github.com/Optiq-CTO/orchestrator/internal/pause"
+	"github.com/Optiq-CTO/orchestrator/internal/runs"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// checkPause holds back a post while publishing is paused for its
+// platform, account or flow, or globally. The draft is held in the outbox
+// for approval or discarded, per the pause configuration, and recorded on
+// the run.
+func (s *OrchestratorService) checkPause(ctx context.Context, req *publisher.PublishRequest) error {
+	if s.pauses == nil {
+		return nil
+	}
+	run, base, account := runOf(ctx)
+	p, ok := s.pauses.Match(pause.Target{Platform: req.Platform, Account: account, Flow: base.Flow})
+	if !ok {
+		return nil
+	}
+	logger := logging.FromContext(ctx)
+	reason := "publishing paused (" + p.Describe() + ")"
+	if p.Reason != "" {
+		reason += ": " + p.Reason
+	}
+	w := runs.Withheld{Platform: req.Platform, Action: pause.DraftsDiscard, Reason: reason}
+	if *s.pauseDrafts.Load() == pause.DraftsHold && s.outbox != nil {
+		e, err := s.outbox.Hold(s.outboxEntry(ctx, req, base, account, reason))
+		if err != nil {
+			logger.Error("failed to persist outbox", "error", err)
+		}
+		w.Action, w.OutboxID = pause.DraftsHold, e.ID
+	}
+	if run != nil {
+		run.withhold(w)
+	}
+	metrics.PostsWithheld.Inc(req.Platform, w.Action)
+	logger.Info("post withheld, publishing is paused", "platform", req.Platform, "pause", p.Describe(), "action", w.Action, "outbox_id", w.OutboxID)
+	return &withheldError{w}
+}
+
+func (s *OrchestratorService) PausePublishing(ctx context.Context, req *pb.PausePublishingRequest) (*pb.PublishingPause, error) {
+	if s.pauses == nil {
+		return nil, status.Error(codes.FailedPrecondition, "the publishing kill switch is not enabled")
+	}
+	if err := s.authorizePause(ctx, req.Scope, req.Key); err != nil {
+		return nil, err
+	}
+	p := pause.Pause{Scope: req.Scope, Key: req.Key, Reason: req.Reason, PausedAt: time.Now().UTC()}
+	if principal, ok := auth.FromContext(ctx); ok {
+		p.PausedBy = principal.Name
+	}
+	if err := s.pauses.Pause(p); err != nil {
+		return nil, status.Errorf(codes.Internal, "publishing is paused but the pause was not saved: %v", err)
+	}
+	s.observePauses()
+	logging.FromContext(ctx).Warn("publishing paused", "pause", p.Describe(), "reason", p.Reason)
+	return pauseToProto(p), nil
+}
+
+func (s *OrchestratorService) ResumePublishing(ctx context.Context, req *pb.ResumePublishingRequest) (*pb.PublishingPause, error) {
+	if s.pauses == nil {
+		return nil, status.Error(codes.FailedPrecondition, "the publishing kill switch is not enabled")
+	}
+	if err := s.authorizePause(ctx, req.Scope, req.Key); err != nil {
+		return nil, err
+	}
+	p, err := s.pauses.Resume(req.Scope, req.Key)
+	if errors.Is(err, pause.ErrNotPaused) {
+		return nil, status.Errorf(codes.NotFound, "publishing is not paused for %s %s", req.Scope, req.Key)
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "publishing resumed but the change was not saved: %v", err)
+	}
+	s.observePauses()
+	logging.FromContext(ctx).Warn("publishing resumed", "pause", p.Describe())
+	return pauseToProto(p), nil
+}
+
+func (s *OrchestratorService) ListPublishingPauses(ctx context.Context, req *pb.ListPublishingPausesRequest) (*pb.ListPublishingPausesResponse, error) {
+	if s.pauses == nil {
+		return nil, status.Error(codes.FailedPrecondition, "the publishing kill switch is not enabled")
+	}
+	res := &pb.ListPublishingPausesResponse{}
+	for _, p := range s.pauses.List() {
+		res.Pauses = append(res.Pauses, pauseToProto(p))
+	}
+	return res, nil
+}
+
+// authorizePause lets API keys confined to a tenant or to accounts pause
+// only their own accounts.
+func (s *OrchestratorService) authorizePause(ctx context.Context, scope, key string) error {
+	if err := pause.Validate(scope, key); err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	p, ok := auth.FromContext(ctx)
+	if !ok || (p.Tenant == "" && !p.RestrictsAccounts()) {
+		return nil
+	}
+	if scope != pause.ScopeAccount {
+		return status.Errorf(codes.PermissionDenied, "api key %q may only pause its own accounts", p.Name)
+	}
+	if err := authorizeAccount(ctx, key); err != nil {
+		return err
+	}
+	if p.Tenant != "" {
+		if s.tenants == nil {
+			return status.Errorf(codes.PermissionDenied, "api key %q may not manage account %q", p.Name, key)
+		}
+		for _, a := range s.tenants.Accounts(p.Tenant) {
+			if a.ID == key {
+				return nil
+			}
+		}
+		return status.Errorf(codes.PermissionDenied, "api key %q may not manage account %q", p.Name, key)
+	}
+	return nil
+}
+
+// observePauses updates the pause gauge.
+func (s *OrchestratorService) observePauses() {
+	counts := make(map[string]int, len(pause.Scopes))
+	for _, p := range s.pauses.List() {
+		counts[p.Scope]++
+	}
+	for _, scope := range pause.Scopes {
+		metrics.PublishingPauses.Set(float64(counts[scope]), scope)
+	}
+}
+
+func pauseToProto(p pause.Pause) *pb.PublishingPause {
+	return &pb.PublishingPause{
+		Scope:    p.Scope,
+		Key:      p.Key,
+		Reason:   p.Reason,
+		PausedBy: p.PausedBy,
+		PausedAt: p.PausedAt.Format(time.RFC3339),
+	}
+}
@@ -0,0 +1,30 @@
+package service
+
+import (
+	"context"
+
+	pb "github.com/Optiq-CTO/orchestrator/api/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *OrchestratorService) ListRateLimits(ctx context.Context, req *pb.ListRateLimitsRequest) (*pb.ListRateLimitsResponse, error) {
+	if s.limiters == nil {
+		return nil, status.Error(codes.FailedPrecondition, "rate limiting is not enabled")
+	}
+	res := &pb.ListRateLimitsResponse{}
+	for _, st := range s.limiters.States() {
+		if req.Kind != "" && st.Kind != req.Kind {
+			continue
+		}
+		res.Limiters = append(res.Limiters, &pb.RateLimiter{
+			Kind:      st.Kind,
+			Key:       st.Key,
+			PerMinute: st.Limit.PerMinute,
+			Burst:     int32(st.Limit.Burst),
+			Tokens:    st.Tokens,
+			Waiting:   int32(st.Waiting),
+		})
+	}
+	return res, nil
+}
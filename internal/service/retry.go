@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	publisher "github.com/Optiq-CTO/orchestrator/api/proto/external/publisher"
+	"github.com/Optiq-CTO/orchestrator/internal/breaker"
+	"github.com/Optiq-CTO/orchestrator/internal/logging"
+	"github.com/Optiq-CTO/orchestrator/internal/metrics"
+	"github.com/Optiq-CTO/orchestrator/internal/retry"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// retryPolicy returns the policy of a step, or the default one for steps
+// without their own.
+func (s *OrchestratorService) retryPolicy(step string) retry.Policy {
+	if p, ok := s.retries[step]; ok {
+		return p
+	}
+	return s.retries["default"]
+}
+
+// retryStep runs fn under the retry policy of step, logging and counting
+// each retry.
+func retryStep[T any](ctx context.Context, s *OrchestratorService, step string, retryable retry.Classifier, fn func(ctx context.Context) (T, error)) (T, error) {
+	logger := logging.FromContext(ctx)
+	return retry.Do(ctx, s.retryPolicy(step), retryable, func(attempt int, err error, wait time.Duration) {
+		metrics.StepRetries.Inc(step, status.Code(err).String())
+		logger.Warn("step failed, retrying", "step", step, "attempt", attempt, "wait", wait, "error", err)
+	}, fn)
+}
+
+// publish posts content, unless publishing is paused or the account's
+// publishing policy does not allow it; see checkPause and checkPolicy.
+func (s *OrchestratorService) publish(ctx context.Context, req *publisher.PublishRequest) (*publisher.PublishResponse, error) {
+	if err := s.checkPause(ctx, req); err != nil {
+		return nil, err
+	}
+	release, err := s.checkPolicy(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	res, err := s.sendPost(ctx, req)
+	if retryAt, open := breaker.OpenUntil(err); open && s.outbox != nil {
+		release(false)
+		return nil, s.deferPost(ctx, req, err.Error(), retryAt)
+	}
+	release(err == nil || !retry.BeforeCreate(err))
+	if err == nil {
+		s.recordPost(ctx, req, res)
+	}
+	return res, err
+}
+
+// sendPost calls the publisher. A post must never go out twice, so only
+// failures that provably happened before the post was created are retried:
+// calls that never got a transport stream, and rate-limit rejections. Calls
+// an open circuit breaker rejected are not retried; callers defer the post
+// until the breaker's cool-down ends. A drain does not interrupt it; see
+// publishContext.
+func (s *OrchestratorService) sendPost(ctx context.Context, req *publisher.PublishRequest) (*publisher.PublishResponse, error) {
+	ctx, release := publishContext(ctx)
+	defer release()
+	res, err := retryStep(ctx, s, "publish", beforeCreateClosed, func(ctx context.Context) (*publisher.PublishResponse, error) {
+		var p peer.Peer
+		res, err := s.publisher.PublishContent(ctx, req, grpc.Peer(&p))
+		if err != nil && p.Addr == nil {
+			err = retry.NotSent(err)
+		}
+		return res, err
+	})
+	s.reportAuthFailure(ctx, req.Platform, req.Credentials, err)
+	return res, err
+}
+
+// beforeCreateClosed is retry.BeforeCreate for calls through a closed
+// breaker; retrying into an open one would only use up attempts.
+func beforeCreateClosed(err error) bool {
+	if _, open := breaker.OpenUntil(err); open {
+		return false
+	}
+	return retry.BeforeCreate(err)
+}
@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"strconv"
+
+	creator "github.com/Optiq-CTO/orchestrator/api/proto/external/creator"
+	"github.com/Optiq-CTO/orchestrator/internal/critique"
+	"github.com/Optiq-CTO/orchestrator/internal/logging"
+	"github.com/Optiq-CTO/orchestrator/internal/runs"
+	"github.com/Optiq-CTO/orchestrator/internal/selection"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// maxReviseIterations bounds the critique-and-revise loop; each iteration
+// costs a critique and a generation call.
+const maxReviseIterations = 5
+
+// reviseSettings configure the critique-and-revise loop. Iterations is the
+// number of rewrites allowed; zero disables the loop.
+type reviseSettings struct {
+	iterations  int
+	threshold   float64
+	critic      string
+	judgePrompt string
+	rules       []string
+}
+
+// reviseParams reads the critique-and-revise params: "revise_iterations",
+// "revise_threshold" (0..1, default 0.8), "critic" ("analyzer" or "judge"),
+// "judge_prompt" and "brand_rules" (one rule per line).
+func reviseParams(params map[string]string) (reviseSettings, error) {
+	rs := reviseSettings{
+		threshold:   0.8,
+		critic:      critique.CriticAnalyzer,
+		judgePrompt: params["judge_prompt"],
+		rules:       critique.ParseRules(params["brand_rules"]),
+	}
+	if v := params["revise_iterations"]; v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 || n > maxReviseIterations {
+			return rs, status.Errorf(codes.InvalidArgument, "revise_iterations must be between 0 and %d", maxReviseIterations)
+		}
+		rs.iterations = n
+	}
+	if v := params["revise_threshold"]; v != "" {
+		t, err := strconv.ParseFloat(v, 64)
+		if err != nil || t < 0 || t > 1 {
+			return rs, status.Error(codes.InvalidArgument, "revise_threshold must be between 0 and 1")
+		}
+		rs.threshold = t
+	}
+	switch v := params["critic"]; v {
+	case "":
+	case critique.CriticAnalyzer, critique.CriticJudge:
+		rs.critic = v
+	default:
+		return rs, status.Errorf(codes.InvalidArgument, "critic must be %q or %q", critique.CriticAnalyzer, critique.CriticJudge)
+	}
+	return rs, nil
+}
+
+// revise runs the critique-and-revise loop on a generated draft: critique
+// it against the brand rules, rewrite it with the feedback, and repeat until
+// a version meets the threshold or iterations run out. The best-scoring
+// version wins, since a rewrite can make things worse. Every version is kept
+// for the run record. Failures end the loop with the best version so far.
+func (s *OrchestratorService) revise(ctx context.Context, platform, tone, modelProvider, provider string, draft *creator.GenerateResponse) *creator.GenerateResponse {
+	route := routeFromContext(ctx)
+	if route == nil || route.revise.iterations == 0 {
+		return draft
+	}
+	rs := route.revise
+	logger := logging.FromContext(ctx)
+
+	var (
+		versions []*creator.GenerateResponse
+		history  []runs.Revision
+	)
+	current := draft
+	for i := 0; ; i++ {
+		rev := runs.Revision{Iteration: i, Provider: provider, Content: current.Content, Critic: rs.critic}
+		verdict, err := s.critique(ctx, rs, platform, current.Content, provider)
+		if err != nil {
+			rev.Error = err.Error()
+			history = append(history, rev)
+			versions = append(versions, current)
+			logger.Warn("critique failed, keeping best draft so far", "step", "critique", "iteration", i, "error", err)
+			break
+		}
+		rev.Score, rev.Feedback = verdict.Score, verdict.Feedback
+		history = append(history, rev)
+		versions = append(versions, current)
+		logger.Info("critiqued draft", "step", "critique", "iteration", i, "score", verdict.Score)
+		if verdict.Score >= rs.threshold || i == rs.iterations || verdict.Feedback == "" {
+			break
+		}
+
+		prompt := critique.RevisePrompt(platform, rs.rules, current.Content, verdict.Feedback)
+		next, used, err := s.generate(ctx, modelProvider, provider, func(ctx context.Context, provider string) (*creator.GenerateResponse, error) {
+			return s.creator.GenerateContent(ctx, &creator.GenerateRequest{
+				Topic:         prompt,
+				Platform:      platform,
+				Tone:          tone,
+				ModelProvider: provider,
+			})
+		})
+		if err != nil {
+			logger.Warn("revision failed, keeping best draft so far", "step", "revise", "iteration", i+1, "error", err)
+			break
+		}
+		current, provider = next, used
+	}
+
+	best := 0
+	for i, rev := range history {
+		if rev.Error == "" && rev.Score > history[best].Score {
+			best = i
+		}
+	}
+	history[best].Selected = true
+	route.revisions = append(route.revisions, history...)
+	if history[best].Provider != "" {
+		route.used = history[best].Provider
+	}
+	return versions[best]
+}
+
+// critique grades a draft with the configured critic.
+func (s *OrchestratorService) critique(ctx context.Context, rs reviseSettings, platform, content, provider string) (critique.Verdict, error) {
+	if rs.critic == critique.CriticJudge {
+		res, err := s.creator.GenerateContent(ctx, &creator.GenerateRequest{
+			Topic:         critique.JudgePrompt(rs.judgePrompt, platform, rs.rules, content),
+			Platform:      platform,
+			ModelProvider: provider,
+		})
+		if err != nil {
+			return critique.Verdict{}, err
+		}
+		return critique.ParseJudge(res.Content)
+	}
+
+	analysis, err := s.analyze(ctx, content, provider)
+	if err != nil {
+		return critique.Verdict{}, err
+	}
+	r := selection.Score(platform, content, analysis)
+	v := critique.Verdict{Score: r.Score}
+	if r.Disqualified {
+		v.Score = 0
+	}
+	var risk float32
+	if analysis != nil {
+		risk = analysis.RiskScore
+	}
+	v.Feedback = critique.IssueFeedback(r.Issues, risk)
+	return v, nil
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+
+	pb "github.com/Optiq-CTO/orchestrator/api/proto"
+	creator "github.com/Optiq-CTO/orchestrator/api/proto/external/creator"
+	"github.com/Optiq-CTO/orchestrator/internal/logging"
+	"github.com/Optiq-CTO/orchestrator/internal/metrics"
+	"github.com/Optiq-CTO/orchestrator/internal/retry"
+	"github.com/Optiq-CTO/orchestrator/internal/routing"
+	"github.com/Optiq-CTO/orchestrator/internal/runs"
+	"github.com/Optiq-CTO/orchestrator/internal/usage"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// providerRoute carries a run's provider plan and generation settings to
+// its generation steps, and collects what they produced for the run record.
+type providerRoute struct {
+	flow      string
+	providers []string
+	used      string
+
+	// Best-of-N settings and the drafts generated under them.
+	candidates         int
+	candidateProviders []string
+	drafts             []runs.Candidate
+
+	// Critique-and-revise settings and every version it produced.
+	revise    reviseSettings
+	revisions []runs.Revision
+}
+
+type routeKey struct{}
+
+// planRoute decides the providers a run may generate with and attaches the
+// plan to ctx.
+func (s *OrchestratorService) planRoute(ctx context.Context, req *pb.PipelineRequest, key usage.Key) (context.Context, *providerRoute, error) {
+	route := &providerRoute{flow: req.FlowName, providers: []string{req.ModelProvider}}
+	if s.router != nil {
+		route.providers = s.router.Plan(key.Tenant, key.Account, req.FlowName, req.ModelProvider)
+	}
+	var err error
+	if route.candidates, route.candidateProviders, err = candidateParams(req.Params); err != nil {
+		return ctx, route, err
+	}
+	if route.revise, err = reviseParams(req.Params); err != nil {
+		return ctx, route, err
+	}
+	return context.WithValue(ctx, routeKey{}, route), route, nil
+}
+
+// retried wraps a generation call in the generate step's retry policy.
+func retried(s *OrchestratorService, call func(ctx context.Context, provider string) (*creator.GenerateResponse, error)) func(ctx context.Context, provider string) (*creator.GenerateResponse, error) {
+	return func(ctx context.Context, provider string) (*creator.GenerateResponse, error) {
+		return retryStep(ctx, s, "generate", retry.Transient, func(ctx context.Context) (*creator.GenerateResponse, error) {
+			return call(ctx, provider)
+		})
+	}
+}
+
+func routeFromContext(ctx context.Context) *providerRoute {
+	route, _ := ctx.Value(routeKey{}).(*providerRoute)
+	return route
+}
+
+// generate calls the creator with each provider in the run's plan until one
+// succeeds, failing over only when a provider is rate limited or down.
+// preferred, if set, is tried first. Providers other than the one budgets
+// approved for the run are skipped when their own AI budget is exhausted.
+// Outside a planned run, modelProvider is used alone. It returns the
+// provider that produced the response.
+func (s *OrchestratorService) generate(ctx context.Context, modelProvider, preferred string, call func(ctx context.Context, provider string) (*creator.GenerateResponse, error)) (*creator.GenerateResponse, string, error) {
+	// Each provider gets its retries before the next one is tried.
+	call = retried(s, call)
+	route := routeFromContext(ctx)
+	if route == nil {
+		res, err := call(ctx, modelProvider)
+		return res, modelProvider, err
+	}
+	providers := route.providers
+	if preferred != "" {
+		providers = []string{preferred}
+		for _, p := range route.providers {
+			if p != preferred {
+				providers = append(providers, p)
+			}
+		}
+	}
+
+	logger := logging.FromContext(ctx)
+	var lastErr error
+	for i, provider := range providers {
+		callCtx := ctx
+		if key, ok := usage.KeyFromContext(ctx); ok && provider != key.Provider {
+			key.Provider = provider
+			if s.usage != nil {
+				d, err := s.usage.Check(key)
+				if err == nil && d.DowngradedBy != "" {
+					err = status.Errorf(codes.ResourceExhausted, "ai budget %s exhausted for %s", d.DowngradedBy, provider)
+				}
+				if err != nil {
+					logger.Warn("skipping provider over budget", "model_provider", provider, "error", err)
+					lastErr = err
+					continue
+				}
+			}
+			callCtx = usage.WithKey(ctx, key)
+		}
+		res, err := call(callCtx, provider)
+		if err == nil {
+			route.used = provider
+			return res, provider, nil
+		}
+		lastErr = err
+		if !routing.ShouldFailover(err) {
+			return nil, provider, err
+		}
+		if i+1 < len(providers) {
+			next := providers[i+1]
+			metrics.ProviderFailovers.Inc(flowLabel(route.flow), provider, next)
+			logger.Warn("model provider failed, failing over", "model_provider", provider, "next", next, "error", err)
+		}
+	}
+	return nil, "", lastErr
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	pb "github.com/Optiq-CTO/orchestrator/api/proto"
+	"github.com/Optiq-CTO/orchestrator/internal/logging"
+	"github.com/Optiq-CTO/orchestrator/internal/secrets"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *OrchestratorService) PutSecret(ctx context.Context, req *pb.PutSecretRequest) (*pb.PutSecretResponse, error) {
+	if s.secrets == nil {
+		return nil, status.Error(codes.FailedPrecondition, secrets.ErrNoStore.Error())
+	}
+	if err := secrets.ValidateName("account", req.Account); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := authorizeAccount(ctx, req.Account); err != nil {
+		return nil, err
+	}
+	if err := secrets.ValidateName("key", req.Key); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if req.Value == "" {
+		return nil, status.Error(codes.InvalidArgument, "value must not be empty")
+	}
+	if err := s.secrets.Put(ctx, req.Account, req.Key, req.Value); err != nil {
+		return nil, status.Errorf(codes.Internal, "storing secret: %v", err)
+	}
+	logging.FromContext(ctx).Info("secret stored", "account", req.Account, "key", req.Key)
+	// New credentials may fix what got the account flagged; the next
+	// rejection flags it again.
+	if s.tokens != nil {
+		if err := s.tokens.ClearReauth(ctx, req.Account); err != nil {
+			logging.FromContext(ctx).Error("failed to clear reauthorization flag", "account", req.Account, "error", err)
+		}
+	}
+	return &pb.PutSecretResponse{Ref: secrets.Ref(req.Account, req.Key)}, nil
+}
+
+func (s *OrchestratorService) DeleteSecret(ctx context.Context, req *pb.DeleteSecretRequest) (*pb.DeleteSecretResponse, error) {
+	if s.secrets == nil {
+		return nil, status.Error(codes.FailedPrecondition, secrets.ErrNoStore.Error())
+	}
+	if err := authorizeAccount(ctx, req.Account); err != nil {
+		return nil, err
+	}
+	if err := s.secrets.Delete(ctx, req.Account, req.Key); err != nil {
+		if errors.Is(err, secrets.ErrNotFound) {
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+		return nil, status.Errorf(codes.Internal, "deleting secret: %v", err)
+	}
+	logging.FromContext(ctx).Info("secret deleted", "account", req.Account, "key", req.Key)
+	return &pb.DeleteSecretResponse{}, nil
+}
+
+func (s *OrchestratorService) ListSecrets(ctx context.Context, req *pb.ListSecretsRequest) (*pb.ListSecretsResponse, error) {
+	if s.secrets == nil {
+		return nil, status.Error(codes.FailedPrecondition, secrets.ErrNoStore.Error())
+	}
+	entries, err := s.secrets.List(ctx, req.Account)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "listing secrets: %v", err)
+	}
+	res := &pb.ListSecretsResponse{}
+	for _, e := range entries {
+		if !canSeeAccount(ctx, e.Account) {
+			continue
+		}
+		res.Secrets = append(res.Secrets, &pb.SecretInfo{
+			Account:   e.Account,
+			Key:       e.Key,
+			Ref:       e.Ref(),
+			UpdatedAt: e.UpdatedAt.Format(time.RFC3339),
+		})
+	}
+	return res, nil
+}
+
+// resolveParams replaces secret references in params with stored values.
+func (s *OrchestratorService) resolveParams(ctx context.Context, params map[string]string) (map[string]string, error) {
+	resolved, err := secrets.Resolve(ctx, s.secrets, params)
+	if errors.Is(err, secrets.ErrNoStore) {
+		return nil, status.Error(codes.FailedPrecondition, "params reference secrets but no credential store is configured")
+	}
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return resolved, nil
+}
@@ -0,0 +1,268 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	pb "github.com/Optiq-CTO/orchestrator/api/proto"
+	"github.com/Optiq-CTO/orchestrator/internal/logging"
+	"github.com/Optiq-CTO/orchestrator/internal/redact"
+	"github.com/Optiq-CTO/orchestrator/internal/runs"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errShuttingDown is the cancellation cause of runs cut short by a drain.
+var errShuttingDown = errors.New("server shutting down")
+
+// interruptGrace is how long interrupted runs get to record their own
+// outcome after being cancelled, before Drain records it for them.
+const interruptGrace = 5 * time.Second
+
+// activeRun is an in-flight pipeline run, tracked so a shutdown can wait
+// for it, cancel it and record where it stopped.
+type activeRun struct {
+	parent context.Context // the run's context before drain cancellation
+	cancel context.CancelCauseFunc
+	base   runs.Record
+	target string // the account posts go out as; see publishTarget
+
+	// allOrNothing runs roll their completed steps back if they fail.
+	allOrNothing bool
+
+	mu            sync.Mutex
+	steps         []string
+	withheld      []runs.Withheld
+	posts         []runs.Post
+	compensations []compensation
+	finished      bool
+}
+
+type activeRunKey struct{}
+
+func activeRunFromContext(ctx context.Context) *activeRun {
+	run, _ := ctx.Value(activeRunKey{}).(*activeRun)
+	return run
+}
+
+// startRun registers rec as in flight and returns the context the run
+// executes under, which Drain cancels once its deadline passes. It fails
+// with Unavailable once the server has started draining.
+func (s *OrchestratorService) startRun(ctx context.Context, rec *runs.Record) (context.Context, *activeRun, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.draining {
+		return ctx, nil, status.Error(codes.Unavailable, "server is shutting down; retry on another instance")
+	}
+	run := &activeRun{parent: ctx, base: *rec}
+	ctx, run.cancel = context.WithCancelCause(ctx)
+	ctx = context.WithValue(ctx, activeRunKey{}, run)
+	s.active[rec.ID] = run
+	s.inflight.Add(1)
+	return ctx, run, nil
+}
+
+// endRun unregisters a run once its outcome is saved.
+func (s *OrchestratorService) endRun(id string, run *activeRun) {
+	s.mu.Lock()
+	delete(s.active, id)
+	s.mu.Unlock()
+	run.cancel(nil)
+	s.inflight.Done()
+}
+
+// interrupted reports whether a drain cut the run short.
+func interrupted(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), errShuttingDown)
+}
+
+// stepDone records that a run got through step.
+func (r *activeRun) stepDone(step string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.steps = append(r.steps, step)
+}
+
+func (r *activeRun) completedSteps() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.steps...)
+}
+
+// withhold records a post a publishing policy kept back.
+func (r *activeRun) withhold(w runs.Withheld) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.withheld = append(r.withheld, w)
+}
+
+func (r *activeRun) withheldPosts() []runs.Withheld {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]runs.Withheld(nil), r.withheld...)
+}
+
+// finish marks the run as recording its own outcome, so Drain leaves it
+// alone. If Drain got there first, the run's own outcome is saved over it.
+func (r *activeRun) finish() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.finished = true
+}
+
+// publishContext shields a publish call from drain cancellation: a post on
+// its way out is allowed to finish, so an interrupted run never leaves a
+// publish with an unknown outcome. Client cancellation still applies. The
+// returned func releases the context.
+func publishContext(ctx context.Context) (context.Context, func()) {
+	run := activeRunFromContext(ctx)
+	if run == nil {
+		return ctx, func() {}
+	}
+	pctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	stop := context.AfterFunc(run.parent, cancel)
+	return pctx, func() {
+		stop()
+		cancel()
+	}
+}
+
+// Drain stops the service accepting runs, turns away queued ones, and waits for in-flight runs to
+// finish. Runs still going when ctx is done are cancelled and recorded as
+// interrupted, so they can be resumed with ResumeRun.
+func (s *OrchestratorService) Drain(ctx context.Context) {
+	if s.admission != nil {
+		s.admission.Close()
+	}
+	s.mu.Lock()
+	s.draining = true
+	n := len(s.active)
+	s.mu.Unlock()
+	s.logger.Info("draining pipeline runs", "in_flight", n)
+
+	done := make(chan struct{})
+	go func() {
+		s.inflight.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		s.logger.Info("all pipeline runs finished")
+		return
+	case <-ctx.Done():
+	}
+
+	s.mu.Lock()
+	for id, run := range s.active {
+		s.logger.Warn("drain deadline passed, interrupting run", "run_id", id)
+		run.cancel(errShuttingDown)
+	}
+	s.mu.Unlock()
+	select {
+	case <-done:
+		return
+	case <-time.After(interruptGrace):
+	}
+
+	// Runs stuck past the grace period are recorded here, from what is
+	// known of them, before the process exits.
+	s.mu.Lock()
+	stuck := make(map[string]*activeRun, len(s.active))
+	for id, run := range s.active {
+		stuck[id] = run
+	}
+	s.mu.Unlock()
+	for id, run := range stuck {
+		run.mu.Lock()
+		if !run.finished {
+			rec := run.base
+			rec.Status = runs.StatusInterrupted
+			rec.Error = errShuttingDown.Error()
+			rec.Steps = append([]string(nil), run.steps...)
+			rec.Withheld = append([]runs.Withheld(nil), run.withheld...)
+			rec.Posts = append([]runs.Post(nil), run.posts...)
+			rec.FinishedAt = time.Now().UTC()
+			if err := s.runs.Save(context.Background(), &rec); err != nil {
+				s.logger.Error("failed to save interrupted run", "run_id", id, "error", err)
+			}
+		}
+		run.mu.Unlock()
+	}
+}
+
+// publishSteps are steps after which a run may have posted; resuming such a
+// run could post twice.
+var publishSteps = map[string]bool{"publish": true, "update_context": true}
+
+func (s *OrchestratorService) ResumeRun(ctx context.Context, req *pb.ResumeRunRequest) (*pb.PipelineResponse, error) {
+	rec, err := s.runs.Get(ctx, req.RunId)
+	if errors.Is(err, runs.ErrNotFound) || (err == nil && !canSeeRun(ctx, rec)) {
+		return nil, status.Errorf(codes.NotFound, "run %s not found", req.RunId)
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "reading run: %v", err)
+	}
+	if rec.Status != runs.StatusInterrupted {
+		return nil, status.Errorf(codes.FailedPrecondition, "run %s is %s; only interrupted runs can be resumed", rec.ID, rec.Status)
+	}
+	for _, step := range rec.Steps {
+		if publishSteps[step] {
+			return nil, status.Errorf(codes.FailedPrecondition, "run %s already reached %s; resuming could post twice", rec.ID, step)
+		}
+	}
+	if len(rec.OutputURLs) > 0 {
+		return nil, status.Errorf(codes.FailedPrecondition, "run %s already published; resuming could post twice", rec.ID)
+	}
+
+	params, err := s.resumeParams(rec, req.Params)
+	if err != nil {
+		return nil, err
+	}
+	logging.FromContext(ctx).Info("resuming interrupted run", "run_id", rec.ID)
+	return s.runPipeline(ctx, &pb.PipelineRequest{
+		FlowName:      rec.Flow,
+		Params:        params,
+		ModelProvider: rec.ModelProvider,
+		AccountId:     rec.AccountID,
+		Priority:      rec.Priority,
+	}, rec.ID)
+}
+
+// resumeParams rebuilds the params of an interrupted run from its record.
+// Redacted values must be supplied again in overrides. For account runs the
+// params the account supplies are dropped, since the account fills them in
+// again and its credentials cannot be overridden.
+func (s *OrchestratorService) resumeParams(rec *runs.Record, overrides map[string]string) (map[string]string, error) {
+	params := make(map[string]string, len(rec.Params))
+	var missing []string
+	for k, v := range rec.Params {
+		if strings.Contains(v, redact.Placeholder) {
+			if _, ok := overrides[k]; !ok {
+				missing = append(missing, k)
+			}
+			continue
+		}
+		params[k] = v
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return nil, status.Errorf(codes.InvalidArgument, "params %s were redacted in run %s; pass them again to resume", strings.Join(missing, ", "), rec.ID)
+	}
+	if rec.AccountID != "" && s.tenants != nil {
+		if acct, err := s.tenants.Account(rec.AccountID); err == nil {
+			for k, v := range acct.RunParams() {
+				if params[k] == v {
+					delete(params, k)
+				}
+			}
+		}
+	}
+	for k, v := range overrides {
+		params[k] = v
+	}
+	return params, nil
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	pb "github.com/Optiq-CTO/orchestrator/api/proto"
+	"github.com/Optiq-CTO/orchestrator/internal/auth"
+	"github.com/Optiq-CTO/orchestrator/internal/secrets"
+	"github.com/Optiq-CTO/orchestrator/internal/tenant"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *OrchestratorService) ListAccounts(ctx context.Context, req *pb.ListAccountsRequest) (*pb.ListAccountsResponse, error) {
+	if s.tenants == nil {
+		return nil, status.Error(codes.FailedPrecondition, "no tenants are configured")
+	}
+	tenantID := req.TenantId
+	if p, ok := auth.FromContext(ctx); ok && p.Tenant != "" {
+		if tenantID != "" && tenantID != p.Tenant {
+			return &pb.ListAccountsResponse{}, nil
+		}
+		tenantID = p.Tenant
+	}
+	res := &pb.ListAccountsResponse{}
+	for _, a := range s.tenants.Accounts(tenantID) {
+		if canSeeAccount(ctx, a.ID) {
+			res.Accounts = append(res.Accounts, accountToProto(a))
+		}
+	}
+	return res, nil
+}
+
+// expandAccount turns a request naming a registered account into a plain
+// one: the account's params and credential references are filled in, and
+// its model provider is the default. Request params override account params
+// but not credentials. Requests without an account are returned unchanged.
+func (s *OrchestratorService) expandAccount(ctx context.Context, req *pb.PipelineRequest) (*pb.PipelineRequest, *tenant.Account, error) {
+	if req.AccountId == "" {
+		return req, nil, nil
+	}
+	if s.tenants == nil {
+		return nil, nil, status.Error(codes.FailedPrecondition, "account_id given but no tenants are configured")
+	}
+	acct, err := s.tenants.ForRun(req.AccountId, req.FlowName)
+	// Accounts of other tenants look the same as unknown ones.
+	if p, ok := auth.FromContext(ctx); ok && err == nil && !p.AllowsTenant(acct.TenantID) {
+		err = tenant.ErrUnknownAccount
+	}
+	switch {
+	case errors.Is(err, tenant.ErrUnknownAccount):
+		return nil, nil, status.Errorf(codes.NotFound, "account %s not found", req.AccountId)
+	case err != nil:
+		return nil, nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+
+	params := acct.RunParams()
+	for k, v := range req.Params {
+		if _, ok := acct.Credentials[k]; ok {
+			return nil, nil, status.Errorf(codes.InvalidArgument, "param %s is a stored credential of account %s and cannot be overridden", k, acct.ID)
+		}
+		// Account runs act with the account's own credentials; a run may
+		// not point them at other credential store accounts.
+		if _, _, isRef, _ := secrets.ParseRef(v); (isRef || k == "target_account") && params[k] != v {
+			return nil, nil, status.Errorf(codes.InvalidArgument, "param %s must come from the config of account %s", k, acct.ID)
+		}
+		params[k] = v
+	}
+	modelProvider := req.ModelProvider
+	if modelProvider == "" {
+		modelProvider = acct.ModelProvider
+	}
+	return &pb.PipelineRequest{
+		FlowName:      req.FlowName,
+		Params:        params,
+		ModelProvider: modelProvider,
+		AccountId:     acct.ID,
+		Priority:      req.Priority,
+	}, acct, nil
+}
+
+func accountToProto(a *tenant.Account) *pb.Account {
+	return &pb.Account{
+		AccountId:     a.ID,
+		TenantId:      a.TenantID,
+		Name:          a.Name,
+		Platform:      a.Platform,
+		Flows:         a.Flows,
+		ModelProvider: a.ModelProvider,
+		Params:        a.Params,
+		Credentials:   a.Credentials,
+		BrandRules:    a.BrandRules,
+	}
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	pb "github.com/Optiq-CTO/orchestrator/api/proto"
+	"github.com/Optiq-CTO/orchestrator/internal/secrets"
+	"github.com/Optiq-CTO/orchestrator/internal/tokens"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *OrchestratorService) ExchangeMetaToken(ctx context.Context, req *pb.ExchangeMetaTokenRequest) (*pb.AccountToken, error) {
+	if s.tokens == nil {
+		return nil, status.Error(codes.FailedPrecondition, "token management is not enabled")
+	}
+	if req.Account == "" || req.PageId == "" || req.ShortLivedToken == "" {
+		return nil, status.Error(codes.InvalidArgument, "missing fields: account, page_id, short_lived_token")
+	}
+	if err := authorizeAccount(ctx, req.Account); err != nil {
+		return nil, err
+	}
+	st, err := s.tokens.ExchangeMeta(ctx, req.Account, req.PageId, req.ShortLivedToken)
+	if err != nil {
+		return nil, tokenError(err)
+	}
+	return accountTokenToProto(st), nil
+}
+
+func (s *OrchestratorService) RefreshAccountToken(ctx context.Context, req *pb.RefreshAccountTokenRequest) (*pb.AccountToken, error) {
+	if s.tokens == nil {
+		return nil, status.Error(codes.FailedPrecondition, "token management is not enabled")
+	}
+	if req.Account == "" {
+		return nil, status.Error(codes.InvalidArgument, "missing field: account")
+	}
+	if err := authorizeAccount(ctx, req.Account); err != nil {
+		return nil, err
+	}
+	st, err := s.tokens.Refresh(ctx, req.Account, req.Platform)
+	if err != nil {
+		return nil, tokenError(err)
+	}
+	return accountTokenToProto(st), nil
+}
+
+func (s *OrchestratorService) ListAccountTokens(ctx context.Context, _ *pb.ListAccountTokensRequest) (*pb.ListAccountTokensResponse, error) {
+	if s.tokens == nil {
+		return nil, status.Error(codes.FailedPrecondition, "token management is not enabled")
+	}
+	states, err := s.tokens.States(ctx)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "listing token state: %v", err)
+	}
+	res := &pb.ListAccountTokensResponse{}
+	for _, st := range states {
+		if !canSeeAccount(ctx, st.Account) {
+			continue
+		}
+		res.Tokens = append(res.Tokens, accountTokenToProto(st))
+	}
+	return res, nil
+}
+
+// checkAccounts blocks runs that would use an account needing reauthorization.
+func (s *OrchestratorService) checkAccounts(ctx context.Context, accounts []string) error {
+	if s.tokens == nil {
+		return nil
+	}
+	return s.tokens.Check(ctx, accounts)
+}
+
+// runAccounts lists the credential store accounts a run uses.
+func runAccounts(params map[string]string) []string {
+	accounts := secrets.ReferencedAccounts(params)
+	if target := params["target_account"]; target != "" {
+		accounts = append(accounts, target)
+	}
+	return accounts
+}
+
+// reportAuthFailure marks the stored accounts whose credentials made a
+// failed call to platform as needing reauthorization, if the platform
+// rejected them. Credentials passed as plain values belong to no stored
+// account.
+func (s *OrchestratorService) reportAuthFailure(ctx context.Context, platform string, creds map[string]string, err error) {
+	if s.tokens == nil || err == nil {
+		return
+	}
+	refs := credentialRefs(ctx)
+	var accounts []string
+	seen := make(map[string]bool)
+	for _, v := range creds {
+		if account, _, ok, _ := secrets.ParseRef(refs[v]); ok && !seen[account] {
+			seen[account] = true
+			accounts = append(accounts, account)
+		}
+	}
+	s.tokens.ReportFailure(ctx, platform, accounts, err)
+}
+
+func tokenError(err error) error {
+	var apiErr *tokens.APIError
+	switch {
+	case errors.Is(err, secrets.ErrNotFound):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.As(err, &apiErr) && apiErr.Auth:
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.As(err, &apiErr):
+		return status.Error(codes.Unavailable, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
+
+func accountTokenToProto(st tokens.State) *pb.AccountToken {
+	t := &pb.AccountToken{
+		Account:     st.Account,
+		Platform:    st.Platform,
+		NeedsReauth: st.NeedsReauth,
+		Reason:      st.Reason,
+	}
+	if !st.ExpiresAt.IsZero() {
+		t.ExpiresAt = st.ExpiresAt.Format(time.RFC3339)
+	}
+	if !st.RefreshedAt.IsZero() {
+		t.RefreshedAt = st.RefreshedAt.Format(time.RFC3339)
+	}
+	return t
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	pb "github.com/Optiq-CTO/orchestrator/api/proto"
+	"github.com/Optiq-CTO/orchestrator/internal/auth"
+	"github.com/Optiq-CTO/orchestrator/internal/logging"
+	"github.com/Optiq-CTO/orchestrator/internal/metrics"
+	"github.com/Optiq-CTO/orchestrator/internal/runs"
+	"github.com/Optiq-CTO/orchestrator/internal/tenant"
+	"github.com/Optiq-CTO/orchestrator/internal/usage"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *OrchestratorService) GetUsage(ctx context.Context, req *pb.GetUsageRequest) (*pb.GetUsageResponse, error) {
+	if s.usage == nil {
+		return nil, status.Error(codes.FailedPrecondition, "usage tracking is not enabled")
+	}
+	period := req.Period
+	if period == "" {
+		period = usage.PeriodDay
+	}
+	if period != usage.PeriodDay && period != usage.PeriodMonth {
+		return nil, status.Errorf(codes.InvalidArgument, "period must be %q or %q", usage.PeriodDay, usage.PeriodMonth)
+	}
+	now := time.Now()
+	res := &pb.GetUsageResponse{
+		Period:      period,
+		PeriodStart: usage.PeriodStart(period, now).Format(time.RFC3339),
+	}
+
+	filter := usage.Filter{
+		Tenant:   req.TenantId,
+		Account:  req.AccountId,
+		Provider: req.ModelProvider,
+		Period:   period,
+		At:       now,
+	}
+	p, authed := auth.FromContext(ctx)
+	if authed && p.Tenant != "" {
+		if filter.Tenant != "" && filter.Tenant != p.Tenant {
+			return res, nil
+		}
+		filter.Tenant = p.Tenant
+	}
+	for _, e := range s.usage.Usage(filter) {
+		if canSeeAccount(ctx, e.Account) {
+			res.Usage = append(res.Usage, usageEntryToProto(e))
+		}
+	}
+	for _, b := range s.usage.Budgets() {
+		// Restricted keys only see budgets scoped to what they can see.
+		if authed && p.Tenant != "" && b.Tenant != p.Tenant {
+			continue
+		}
+		if authed && p.RestrictsAccounts() && (b.Account == "" || !p.AllowsAccount(b.Account)) {
+			continue
+		}
+		res.Budgets = append(res.Budgets, budgetStatusToProto(b))
+	}
+	return res, nil
+}
+
+// usageKey attributes a run's AI calls. Runs without a registered account
+// are attributed to the first credential store account they use, or failing
+// that to the user the batch runner sends.
+func usageKey(req *pb.PipelineRequest, acct *tenant.Account, accounts []string) usage.Key {
+	k := usage.Key{Flow: req.FlowName, Provider: req.ModelProvider}
+	switch {
+	case acct != nil:
+		k.Tenant, k.Account = acct.TenantID, acct.ID
+	case len(accounts) > 0:
+		k.Account = accounts[0]
+	default:
+		k.Account = runUser(req.Params)
+	}
+	return k
+}
+
+// applyBudget checks the run against AI budgets and attributes its calls.
+// A downgrade changes the provider the run uses and records it.
+func (s *OrchestratorService) applyBudget(ctx context.Context, req *pb.PipelineRequest, rec *runs.Record, key usage.Key) (context.Context, error) {
+	if s.usage == nil {
+		return ctx, nil
+	}
+	d, err := s.usage.Check(key)
+	if err != nil {
+		metrics.BudgetDecisions.Inc(usage.ActionBlock)
+		return ctx, err
+	}
+	if d.DowngradedBy != "" {
+		metrics.BudgetDecisions.Inc(usage.ActionDowngrade)
+		logging.FromContext(ctx).Warn("ai budget exhausted, downgrading model provider",
+			"budget", d.DowngradedBy, "from", req.ModelProvider, "to", d.Provider)
+		req.ModelProvider = d.Provider
+		rec.ModelProvider = d.Provider
+		key.Provider = d.Provider
+	}
+	return usage.WithKey(ctx, key), nil
+}
+
+func usageEntryToProto(e usage.Entry) *pb.UsageEntry {
+	return &pb.UsageEntry{
+		TenantId:      e.Tenant,
+		AccountId:     e.Account,
+		FlowName:      e.Flow,
+		ModelProvider: e.Provider,
+		Calls:         e.Calls,
+		InputTokens:   e.InputTokens,
+		OutputTokens:  e.OutputTokens,
+	}
+}
+
+func budgetStatusToProto(b usage.BudgetStatus) *pb.BudgetStatus {
+	return &pb.BudgetStatus{
+		Name:          b.Name,
+		TenantId:      b.Tenant,
+		AccountId:     b.Account,
+		FlowName:      b.Flow,
+		ModelProvider: b.ModelProvider,
+		Period:        b.Period,
+		MaxCalls:      b.MaxCalls,
+		MaxTokens:     b.MaxTokens,
+		UsedCalls:     b.Used.Calls,
+		UsedTokens:    b.Used.Tokens(),
+		Exhausted:     b.Exhausted,
+		OnExhausted:   b.OnExhausted,
+		DowngradeTo:   b.DowngradeTo,
+	}
+}
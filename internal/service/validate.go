@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	pb "github.com/Optiq-CTO/orchestrator/api/proto"
+	fetcher "github.com/Optiq-CTO/orchestrator/api/proto/external/fetcher"
+	"github.com/Optiq-CTO/orchestrator/internal/logging"
+	"github.com/Optiq-CTO/orchestrator/internal/redact"
+	"github.com/Optiq-CTO/orchestrator/internal/secrets"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// requiredParams lists the params each flow needs to run end to end,
+// including the publish credentials the flow itself only checks late.
+var requiredParams = map[string][]string{
+	"cross_pollinator": {"query", "target_platform"},
+	"facebook_echo":    {"page_id", "access_token"},
+	"twitter_echo": {
+		"twitter_user_id", "twitter_bearer_token",
+		"twitter_api_key", "twitter_api_secret", "twitter_access_token", "twitter_access_token_secret",
+	},
+}
+
+// publishCredentialKeys lists the credentials the publisher needs per platform.
+var publishCredentialKeys = map[string][]string{
+	"facebook": {"page_id", "access_token"},
+	"twitter":  {"twitter_api_key", "twitter_api_secret", "twitter_access_token", "twitter_access_token_secret"},
+}
+
+// ValidateAccount runs the preflight checks for a flow: required params,
+// secret resolution, token state and a read-only fetch with the account's
+// credentials. Nothing is generated or published.
+func (s *OrchestratorService) ValidateAccount(ctx context.Context, req *pb.PipelineRequest) (*pb.ValidateAccountResponse, error) {
+	required, ok := requiredParams[req.FlowName]
+	if !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "cannot validate flow: %s", req.FlowName)
+	}
+	req, acct, err := s.expandAccount(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	accounts := runAccounts(req.Params)
+	if err := s.authorizeRun(ctx, req.FlowName, acct, accounts); err != nil {
+		return nil, err
+	}
+	logger := s.logger.With("flow", req.FlowName, "user", runUser(req.Params))
+	ctx = logging.WithLogger(ctx, logger)
+
+	res := &pb.ValidateAccountResponse{Ready: true}
+	check := func(name string, err error, okMessage string) bool {
+		c := &pb.ValidationCheck{Name: name, Passed: err == nil, Message: okMessage}
+		if err != nil {
+			c.Message = redact.String(err.Error())
+			res.Ready = false
+		}
+		res.Checks = append(res.Checks, c)
+		return err == nil
+	}
+
+	if !check("params", missingParams(req.Params, required), "all required params present") {
+		return res, nil
+	}
+	params, err := s.resolveParams(ctx, req.Params)
+	if !check("secrets", statusMessage(err), "secret references resolved") {
+		return res, nil
+	}
+	ctx = withCredentialRefs(ctx, paramRefs(req.Params, params))
+	if !check("token", s.checkAccounts(ctx, accounts), "no account needs reauthorization") {
+		return res, nil
+	}
+
+	switch req.FlowName {
+	case "facebook_echo":
+		err := s.probeFetch(ctx, &fetcher.FetchRequest{
+			Platform:    "meta",
+			Query:       params["page_id"],
+			Credentials: map[string]string{"access_token": params["access_token"]},
+		}, req.ModelProvider)
+		check("fetch", err, "page feed readable with access_token")
+	case "twitter_echo":
+		err := s.probeFetch(ctx, &fetcher.FetchRequest{
+			Platform:    "twitter",
+			Query:       "id:" + params["twitter_user_id"],
+			Credentials: map[string]string{"twitter_bearer_token": params["twitter_bearer_token"]},
+		}, req.ModelProvider)
+		check("fetch", err, "timeline readable with twitter_bearer_token")
+		check("publish_credentials", nil, "OAuth 1.0a keys present (not verified without publishing)")
+	case "cross_pollinator":
+		if params["target_account"] == "" {
+			check("publish_credentials", nil, "publishing with the publisher's own credentials")
+			break
+		}
+		check("publish_credentials", s.checkTargetAccount(ctx, params["target_account"], params["target_platform"]),
+			"target account holds credentials for "+params["target_platform"])
+	}
+
+	logger.Info("account validated", "ready", res.Ready)
+	return res, nil
+}
+
+// probeFetch confirms credentials work with the smallest possible fetch.
+// It asks the fetcher to skip analysis; nothing is generated or published.
+func (s *OrchestratorService) probeFetch(ctx context.Context, req *fetcher.FetchRequest, modelProvider string) error {
+	ctx = metadata.AppendToOutgoingContext(ctx, skipAnalysisHeader, "true")
+	req.ModelProvider = modelProvider
+	req.Limit = 1
+	if _, err := s.fetcher.FetchContent(ctx, req); err != nil {
+		s.reportAuthFailure(ctx, req.Platform, req.Credentials, err)
+		return fmt.Errorf("fetch failed: %s", status.Convert(err).Message())
+	}
+	return nil
+}
+
+// checkTargetAccount confirms a stored account holds every credential the
+// publisher needs for platform.
+func (s *OrchestratorService) checkTargetAccount(ctx context.Context, account, platform string) error {
+	if s.secrets == nil {
+		return secrets.ErrNoStore
+	}
+	creds, err := s.secrets.Credentials(ctx, account)
+	if err != nil {
+		return err
+	}
+	return missingParams(creds, publishCredentialKeys[platform])
+}
+
+func missingParams(params map[string]string, required []string) error {
+	var missing []string
+	for _, k := range required {
+		if params[k] == "" {
+			missing = append(missing, k)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// statusMessage strips the gRPC framing from err so check messages read
+// cleanly.
+func statusMessage(err error) error {
+	if err == nil {
+		return nil
+	}
+	return errors.New(status.Convert(err).Message())
+}
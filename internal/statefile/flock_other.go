@@ -0,0 +1,12 @@
+//go:build !unix
+
+package statefile
+
+import (
+	"errors"
+	"os"
+)
+
+func lockFile(*os.File) error { return errors.New("file locks are not supported on this platform") }
+
+func unlockFile(*os.File) error { return nil }
@@ -0,0 +1,81 @@
+// Package statefile lets the processes on one host share small state files.
+// Every access takes an OS lock on the file and re-reads it, so no process
+// writes a stale copy back over another's change.
+package statefile
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Lock takes an exclusive lock on path + ".lock" and returns the function
+// that releases it.
+func Lock(path string) (unlock func(), err error) {
+	f, err := os.OpenFile(path+".lock", os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("opening lock file: %w", err)
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("locking %s: %w", path, err)
+	}
+	return func() {
+		unlockFile(f)
+		f.Close()
+	}, nil
+}
+
+// Read returns the contents of path, or nil if it does not exist. Callers
+// hold the lock.
+func Read(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	return data, nil
+}
+
+// Write replaces path with data through a temp file and a rename, so a
+// crash never leaves it half written. Callers hold the lock.
+func Write(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("writing %s: %w", path, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("writing %s: %w", path, err)
+	}
+	return nil
+}
+
+// Update locks path, decodes the JSON in it into v, and runs fn. If fn
+// reports a change, v is written back before the lock is released. v should
+// start empty; a missing or empty file leaves it so.
+func Update(path string, v any, fn func() bool) error {
+	unlock, err := Lock(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	data, err := Read(path)
+	if err != nil {
+		return err
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, v); err != nil {
+			return fmt.Errorf("decoding %s: %w", path, err)
+		}
+	}
+	if !fn() {
+		return nil
+	}
+	if data, err = json.MarshalIndent(v, "", "  "); err != nil {
+		return fmt.Errorf("encoding %s: %w", path, err)
+	}
+	return Write(path, data)
+}
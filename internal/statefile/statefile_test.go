@@ -0,0 +1,74 @@
+package statefile
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestUpdate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	tests := []struct {
+		name    string
+		change  bool
+		want    int
+		wantRaw bool // whether the file exists afterwards
+	}{
+		{"no change leaves no file", false, 0, false},
+		{"change writes", true, 1, true},
+		{"no change keeps the file", false, 1, true},
+		{"changes add up", true, 2, true},
+	}
+	for _, tt := range tests {
+		var n int
+		if err := Update(path, &n, func() bool {
+			if tt.change {
+				n++
+			}
+			return tt.change
+		}); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if n != tt.want {
+			t.Errorf("%s: n = %d, want %d", tt.name, n, tt.want)
+		}
+		if _, err := os.Stat(path); (err == nil) != tt.wantRaw {
+			t.Errorf("%s: file exists = %v, want %v", tt.name, err == nil, tt.wantRaw)
+		}
+	}
+}
+
+func TestUpdateConcurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var n int
+			if err := Update(path, &n, func() bool { n++; return true }); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	var n int
+	if err := Update(path, &n, func() bool { return false }); err != nil {
+		t.Fatal(err)
+	}
+	if n != 20 {
+		t.Errorf("n = %d after 20 increments; updates were lost", n)
+	}
+}
+
+func TestUpdateRejectsCorruptFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	if err := os.WriteFile(path, []byte("{"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	var v map[string]int
+	if err := Update(path, &v, func() bool { return true }); err == nil {
+		t.Error("Update() decoded a corrupt file")
+	}
+}
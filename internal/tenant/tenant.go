@@ -0,0 +1,229 @@
+// Package tenant models who owns what in the orchestrator: tenants own
+// platform accounts, and each account carries the params, credential
+// references, enabled flows and default model provider its runs use.
+package tenant
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/Optiq-CTO/orchestrator/internal/secrets"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// ErrUnknownAccount is returned for account IDs not in the registry.
+	ErrUnknownAccount = errors.New("unknown account")
+	// ErrFlowDisabled is returned when an account has not enabled a flow.
+	ErrFlowDisabled = errors.New("flow not enabled for account")
+)
+
+// Config is the tenants section of the server configuration.
+//
+//	tenants:
+//	  - id: acme
+//	    name: Acme Corp
+//	    accounts:
+//	      - id: acme-health-page
+//	        platform: facebook
+//	        params:
+//	          page_id: "123456789"
+//	        credentials:
+//	          access_token: secret://acme-health-page/access_token
+//	        flows: [facebook_echo]
+//	        model_provider: gemini
+//	        brand_rules:
+//	          - No medical claims.
+//	          - At most two emojis.
+type Config struct {
+	Tenants []Tenant `yaml:"tenants"`
+}
+
+// Tenant is an isolated customer or workspace.
+type Tenant struct {
+	ID       string    `yaml:"id"`
+	Name     string    `yaml:"name"`
+	Accounts []Account `yaml:"accounts"`
+}
+
+// Account is one platform account (page, X user, ...) owned by a tenant.
+// Account IDs are unique across tenants and double as the credential store
+// account name by convention.
+type Account struct {
+	ID       string `yaml:"id"`
+	TenantID string `yaml:"-"`
+	Name     string `yaml:"name"`
+	Platform string `yaml:"platform"`
+	// Params are non-secret flow params, e.g. page_id or query.
+	Params map[string]string `yaml:"params"`
+	// Credentials map flow params to secret:// references.
+	Credentials   map[string]string `yaml:"credentials"`
+	Flows         []string          `yaml:"flows"`
+	ModelProvider string            `yaml:"model_provider"`
+	// BrandRules guide the critique-and-revise loop, one rule per entry.
+	BrandRules []string `yaml:"brand_rules"`
+}
+
+// AllowsFlow reports whether the account has enabled flow.
+func (a *Account) AllowsFlow(flow string) bool {
+	for _, f := range a.Flows {
+		if f == flow {
+			return true
+		}
+	}
+	return false
+}
+
+// RunParams returns the params a run for this account starts from: its
+// params, its credential references and its brand rules.
+func (a *Account) RunParams() map[string]string {
+	out := make(map[string]string, len(a.Params)+len(a.Credentials)+1)
+	if len(a.BrandRules) > 0 {
+		out["brand_rules"] = strings.Join(a.BrandRules, "\n")
+	}
+	for k, v := range a.Params {
+		out[k] = v
+	}
+	for k, v := range a.Credentials {
+		out[k] = v
+	}
+	return out
+}
+
+// CredentialAccounts returns the credential store accounts the account's
+// config uses: one named like the account, those its credentials reference
+// and its target_account.
+func (a *Account) CredentialAccounts() []string {
+	out := []string{a.ID}
+	for _, v := range a.Credentials {
+		if store, _, ok, err := secrets.ParseRef(v); ok && err == nil {
+			out = append(out, store)
+		}
+	}
+	if target := a.Params["target_account"]; target != "" {
+		out = append(out, target)
+	}
+	return out
+}
+
+// Registry indexes tenants and accounts.
+type Registry struct {
+	accounts map[string]*Account
+}
+
+// LoadConfig reads a tenants file.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading tenants file: %w", err)
+	}
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parsing tenants file: %w", err)
+	}
+	return &cfg, nil
+}
+
+// NewRegistry validates cfg and indexes its accounts.
+func NewRegistry(cfg *Config) (*Registry, error) {
+	r := &Registry{accounts: make(map[string]*Account)}
+	tenantIDs := make(map[string]bool)
+	for ti := range cfg.Tenants {
+		t := &cfg.Tenants[ti]
+		if t.ID == "" {
+			return nil, fmt.Errorf("tenants[%d]: id is required", ti)
+		}
+		if tenantIDs[t.ID] {
+			return nil, fmt.Errorf("duplicate tenant %q", t.ID)
+		}
+		tenantIDs[t.ID] = true
+		for ai := range t.Accounts {
+			a := &t.Accounts[ai]
+			a.TenantID = t.ID
+			if err := validateAccount(a); err != nil {
+				return nil, fmt.Errorf("tenant %q: %w", t.ID, err)
+			}
+			if _, dup := r.accounts[a.ID]; dup {
+				return nil, fmt.Errorf("account %q is defined more than once", a.ID)
+			}
+			r.accounts[a.ID] = a
+		}
+	}
+	return r, nil
+}
+
+func validateAccount(a *Account) error {
+	if err := secrets.ValidateName("account id", a.ID); err != nil {
+		return err
+	}
+	if a.Platform == "" {
+		return fmt.Errorf("account %q: platform is required", a.ID)
+	}
+	if len(a.Flows) == 0 {
+		return fmt.Errorf("account %q: no flows enabled", a.ID)
+	}
+	for k, v := range a.Credentials {
+		if _, _, ok, err := secrets.ParseRef(v); !ok || err != nil {
+			return fmt.Errorf("account %q: credential %s must be a %saccount/key reference", a.ID, k, secrets.RefPrefix)
+		}
+	}
+	for k := range a.Params {
+		if _, dup := a.Credentials[k]; dup {
+			return fmt.Errorf("account %q: %s is both a param and a credential", a.ID, k)
+		}
+	}
+	return nil
+}
+
+// Account returns the account with the given ID.
+func (r *Registry) Account(id string) (*Account, error) {
+	a, ok := r.accounts[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownAccount, id)
+	}
+	return a, nil
+}
+
+// ForRun returns the account for a run of flow, failing if the flow is not
+// enabled for it.
+func (r *Registry) ForRun(accountID, flow string) (*Account, error) {
+	a, err := r.Account(accountID)
+	if err != nil {
+		return nil, err
+	}
+	if !a.AllowsFlow(flow) {
+		return nil, fmt.Errorf("%w: %s on %s (enabled: %s)", ErrFlowDisabled, flow, a.ID, strings.Join(a.Flows, ", "))
+	}
+	return a, nil
+}
+
+// OwnsCredentials reports whether a registered account of tenantID uses the
+// credential store account store.
+func (r *Registry) OwnsCredentials(tenantID, store string) bool {
+	for _, a := range r.accounts {
+		if a.TenantID != tenantID {
+			continue
+		}
+		for _, c := range a.CredentialAccounts() {
+			if c == store {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Accounts lists accounts sorted by ID, optionally only those of one tenant.
+func (r *Registry) Accounts(tenantID string) []*Account {
+	var out []*Account
+	for _, a := range r.accounts {
+		if tenantID == "" || a.TenantID == tenantID {
+			out = append(out, a)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
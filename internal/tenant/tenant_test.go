@@ -0,0 +1,146 @@
+package tenant
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func testConfig() *Config {
+	return &Config{Tenants: []Tenant{
+		{ID: "acme", Accounts: []Account{
+			{
+				ID:          "acme-page",
+				Platform:    "facebook",
+				Params:      map[string]string{"page_id": "1"},
+				Credentials: map[string]string{"access_token": "secret://acme-page/access_token"},
+				Flows:       []string{"facebook_echo"},
+			},
+			{
+				ID:       "acme-x",
+				Platform: "twitter",
+				Params:   map[string]string{"target_account": "acme-fb"},
+				Credentials: map[string]string{
+					"twitter_bearer_token": "secret://acme-shared/twitter_bearer_token",
+				},
+				Flows: []string{"twitter_echo", "cross_pollinator"},
+			},
+		}},
+		{ID: "beta", Accounts: []Account{
+			{ID: "beta-page", Platform: "facebook", Flows: []string{"facebook_echo"}},
+		}},
+	}}
+}
+
+func TestNewRegistry(t *testing.T) {
+	tests := []struct {
+		name    string
+		edit    func(*Config)
+		wantErr string
+	}{
+		{"valid", func(*Config) {}, ""},
+		{"tenant without id", func(c *Config) { c.Tenants[1].ID = "" }, "id is required"},
+		{"duplicate tenant", func(c *Config) { c.Tenants[1].ID = "acme" }, "duplicate tenant"},
+		{"duplicate account", func(c *Config) { c.Tenants[1].Accounts[0].ID = "acme-page" }, "more than once"},
+		{"bad account id", func(c *Config) { c.Tenants[1].Accounts[0].ID = "beta/page" }, "must not contain"},
+		{"no platform", func(c *Config) { c.Tenants[1].Accounts[0].Platform = "" }, "platform is required"},
+		{"no flows", func(c *Config) { c.Tenants[1].Accounts[0].Flows = nil }, "no flows enabled"},
+		{"plain credential", func(c *Config) {
+			c.Tenants[0].Accounts[0].Credentials["access_token"] = "EAAraw"
+		}, "must be a secret://"},
+		{"param and credential", func(c *Config) {
+			c.Tenants[0].Accounts[0].Params["access_token"] = "x"
+		}, "both a param and a credential"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig()
+			tt.edit(cfg)
+			_, err := NewRegistry(cfg)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("NewRegistry = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestForRun(t *testing.T) {
+	r, err := NewRegistry(testConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		account, flow string
+		wantErr       error
+		wantTenant    string
+	}{
+		{"acme-page", "facebook_echo", nil, "acme"},
+		{"acme-page", "twitter_echo", ErrFlowDisabled, ""},
+		{"nobody", "facebook_echo", ErrUnknownAccount, ""},
+		{"beta-page", "facebook_echo", nil, "beta"},
+	}
+	for _, tt := range tests {
+		a, err := r.ForRun(tt.account, tt.flow)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("ForRun(%s, %s) error = %v, want %v", tt.account, tt.flow, err, tt.wantErr)
+			continue
+		}
+		if err == nil && a.TenantID != tt.wantTenant {
+			t.Errorf("ForRun(%s, %s) tenant = %q, want %q", tt.account, tt.flow, a.TenantID, tt.wantTenant)
+		}
+	}
+	if got := r.Accounts("acme"); len(got) != 2 || got[0].ID != "acme-page" {
+		t.Errorf("Accounts(acme) = %v", got)
+	}
+	if got := r.Accounts(""); len(got) != 3 {
+		t.Errorf("Accounts() returned %d accounts, want 3", len(got))
+	}
+}
+
+func TestRunParams(t *testing.T) {
+	a := testConfig().Tenants[0].Accounts[0]
+	a.BrandRules = []string{"No medical claims.", "At most two emojis."}
+	got := a.RunParams()
+	want := map[string]string{
+		"page_id":      "1",
+		"access_token": "secret://acme-page/access_token",
+		"brand_rules":  "No medical claims.\nAt most two emojis.",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("RunParams = %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("RunParams[%s] = %q, want %q", k, got[k], v)
+		}
+	}
+}
+
+func TestOwnsCredentials(t *testing.T) {
+	r, err := NewRegistry(testConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		tenant, store string
+		want          bool
+	}{
+		{"acme", "acme-page", true},   // named like the account
+		{"acme", "acme-shared", true}, // referenced by a credential
+		{"acme", "acme-fb", true},     // target_account
+		{"acme", "beta-page", false},
+		{"beta", "acme-page", false},
+		{"beta", "beta-page", true},
+	}
+	for _, tt := range tests {
+		if got := r.OwnsCredentials(tt.tenant, tt.store); got != tt.want {
+			t.Errorf("OwnsCredentials(%s, %s) = %v, want %v", tt.tenant, tt.store, got, tt.want)
+		}
+	}
+}
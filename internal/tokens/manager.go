@@ -0,0 +1,280 @@
+package tokens
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Optiq-CTO/orchestrator/internal/logging"
+	"github.com/Optiq-CTO/orchestrator/internal/redact"
+	"github.com/Optiq-CTO/orchestrator/internal/secrets"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Credential store keys read and written by the manager.
+const (
+	KeyMetaAppID        = "app_id"
+	KeyMetaAppSecret    = "app_secret"
+	KeyMetaUserToken    = "user_access_token" // long-lived user token
+	KeyMetaPageToken    = "access_token"      // page token used by facebook_echo
+	KeyMetaPageID       = "page_id"
+	KeyXClientID        = "twitter_client_id"
+	KeyXClientSecret    = "twitter_client_secret"
+	KeyXRefreshToken    = "twitter_refresh_token"
+	KeyXUserAccessToken = "twitter_bearer_token" // OAuth2 user token, used as the fetch bearer
+)
+
+// Config holds the token endpoints and refresh windows.
+type Config struct {
+	// MetaGraphURL is the Graph API base URL including version.
+	MetaGraphURL string
+	// XAPIURL is the X API base URL.
+	XAPIURL string
+	// MetaRefreshBefore is how long before expiry Meta tokens are refreshed.
+	MetaRefreshBefore time.Duration
+	// XRefreshBefore is how long before expiry X tokens are refreshed.
+	XRefreshBefore time.Duration
+	// HTTPClient is used for token endpoint calls.
+	HTTPClient *http.Client
+}
+
+func (c Config) withDefaults() Config {
+	if c.MetaGraphURL == "" {
+		c.MetaGraphURL = "https://graph.facebook.com/v19.0"
+	}
+	if c.XAPIURL == "" {
+		c.XAPIURL = "https://api.x.com"
+	}
+	if c.MetaRefreshBefore == 0 {
+		c.MetaRefreshBefore = 7 * 24 * time.Hour
+	}
+	if c.XRefreshBefore == 0 {
+		c.XRefreshBefore = 10 * time.Minute
+	}
+	if c.HTTPClient == nil {
+		c.HTTPClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	c.MetaGraphURL = strings.TrimRight(c.MetaGraphURL, "/")
+	c.XAPIURL = strings.TrimRight(c.XAPIURL, "/")
+	return c
+}
+
+// ReauthError is returned when a run needs an account that must be
+// re-authorized by a human before it can be used again.
+type ReauthError struct {
+	Account string
+	Reason  string
+}
+
+func (e *ReauthError) Error() string {
+	return fmt.Sprintf("account %s needs reauthorization: %s", e.Account, e.Reason)
+}
+
+// GRPCStatus lets the error cross the API boundary as FailedPrecondition.
+func (e *ReauthError) GRPCStatus() *status.Status {
+	return status.New(codes.FailedPrecondition, e.Error())
+}
+
+// Manager refreshes tokens and tracks which accounts need reauthorization.
+type Manager struct {
+	secrets secrets.Store
+	states  StateStore
+	cfg     Config
+	now     func() time.Time
+}
+
+// NewManager returns a manager storing token values in sec and lifecycle
+// metadata in states.
+func NewManager(sec secrets.Store, states StateStore, cfg Config) *Manager {
+	return &Manager{secrets: sec, states: states, cfg: cfg.withDefaults(), now: time.Now}
+}
+
+// States returns the tracked state of every account.
+func (m *Manager) States(ctx context.Context) ([]State, error) {
+	return m.states.List(ctx)
+}
+
+// Check returns a *ReauthError for the first account that needs
+// reauthorization. Accounts without tracked state pass.
+func (m *Manager) Check(ctx context.Context, accounts []string) error {
+	for _, account := range accounts {
+		st, err := m.states.Get(ctx, account)
+		if errors.Is(err, ErrUnknownAccount) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if st.NeedsReauth {
+			return &ReauthError{Account: account, Reason: st.Reason}
+		}
+	}
+	return nil
+}
+
+// ReportFailure inspects a failed call to platform made with the
+// credentials of accounts and marks them as needing reauthorization if the
+// platform rejected their token. Accounts not yet tracked are tracked under
+// platform. It reports whether any account was marked.
+func (m *Manager) ReportFailure(ctx context.Context, platform string, accounts []string, err error) bool {
+	if !IsAuthFailure(err) {
+		return false
+	}
+	if platform == "meta" {
+		platform = PlatformMeta
+	}
+	marked := false
+	for _, account := range accounts {
+		st, getErr := m.states.Get(ctx, account)
+		if getErr != nil && !errors.Is(getErr, ErrUnknownAccount) {
+			logging.FromContext(ctx).Error("failed to load token state", "account", account, "error", getErr)
+			continue
+		}
+		st.Account = account
+		if st.Platform == "" {
+			st.Platform = platform
+		}
+		if m.markReauth(ctx, st, "platform rejected token: "+redact.String(err.Error())) {
+			marked = true
+		}
+	}
+	return marked
+}
+
+// ClearReauth clears the reauthorization flag of an account whose
+// credentials were replaced.
+func (m *Manager) ClearReauth(ctx context.Context, account string) error {
+	st, err := m.states.Get(ctx, account)
+	if errors.Is(err, ErrUnknownAccount) {
+		return nil
+	}
+	if err != nil || !st.NeedsReauth {
+		return err
+	}
+	st.NeedsReauth = false
+	st.Reason = ""
+	return m.states.Put(ctx, st)
+}
+
+// IsAuthFailure reports whether err means a platform rejected a token, e.g.
+// "meta api error [190]: Invalid OAuth access token".
+func IsAuthFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	if status.Code(err) == codes.Unauthenticated {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	for _, marker := range []string{"[190]", "invalid oauth access token", "invalid_token", "invalid_grant", "session has expired"} {
+		if strings.Contains(msg, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Manager) markReauth(ctx context.Context, st State, reason string) bool {
+	st.NeedsReauth = true
+	st.Reason = reason
+	if err := m.states.Put(ctx, st); err != nil {
+		logging.FromContext(ctx).Error("failed to save token state", "account", st.Account, "error", err)
+		return false
+	}
+	logging.FromContext(ctx).Warn("account needs reauthorization", "account", st.Account, "platform", st.Platform, "reason", reason)
+	return true
+}
+
+// Refresh refreshes the token of an account now. platform is required the
+// first time an account is refreshed, or while its platform is unknown, and
+// must match afterwards.
+func (m *Manager) Refresh(ctx context.Context, account, platform string) (State, error) {
+	st, err := m.states.Get(ctx, account)
+	if err != nil && !errors.Is(err, ErrUnknownAccount) {
+		return State{}, err
+	}
+	if st.Account == "" {
+		st = State{Account: account}
+	}
+	if st.Platform == "" {
+		st.Platform = platform
+	}
+	if platform != "" && st.Platform != platform {
+		return State{}, fmt.Errorf("account %s is tracked as %s, not %s", account, st.Platform, platform)
+	}
+
+	var refreshErr error
+	switch st.Platform {
+	case PlatformMeta:
+		st, refreshErr = m.refreshMeta(ctx, st)
+	case PlatformTwitter:
+		st, refreshErr = m.refreshX(ctx, st)
+	default:
+		return State{}, fmt.Errorf("token refresh is not supported for platform %q", st.Platform)
+	}
+	if refreshErr != nil {
+		var apiErr *APIError
+		if errors.As(refreshErr, &apiErr) && apiErr.Auth {
+			m.markReauth(ctx, st, refreshErr.Error())
+		}
+		return st, refreshErr
+	}
+	return st, m.states.Put(ctx, st)
+}
+
+// RefreshDue refreshes every account whose token expires within its
+// platform's refresh window. Failures are logged; accounts whose refresh
+// was rejected are marked as needing reauthorization.
+func (m *Manager) RefreshDue(ctx context.Context) {
+	logger := logging.FromContext(ctx)
+	states, err := m.states.List(ctx)
+	if err != nil {
+		logger.Error("listing token state failed", "error", err)
+		return
+	}
+	for _, st := range states {
+		if st.NeedsReauth || st.ExpiresAt.IsZero() {
+			continue
+		}
+		window := m.cfg.MetaRefreshBefore
+		if st.Platform == PlatformTwitter {
+			window = m.cfg.XRefreshBefore
+		}
+		if m.now().Add(window).Before(st.ExpiresAt) {
+			continue
+		}
+		if _, err := m.Refresh(ctx, st.Account, st.Platform); err != nil {
+			logger.Error("token refresh failed", "account", st.Account, "platform", st.Platform, "error", err)
+			continue
+		}
+		logger.Info("token refreshed", "account", st.Account, "platform", st.Platform)
+	}
+}
+
+// Run calls RefreshDue every interval until ctx is done.
+func (m *Manager) Run(ctx context.Context, interval time.Duration) {
+	m.RefreshDue(ctx)
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			m.RefreshDue(ctx)
+		}
+	}
+}
+
+// expiry converts an expires_in value in seconds to an absolute time; zero
+// means the token does not expire.
+func (m *Manager) expiry(expiresIn int64) time.Time {
+	if expiresIn <= 0 {
+		return time.Time{}
+	}
+	return m.now().UTC().Add(time.Duration(expiresIn) * time.Second)
+}
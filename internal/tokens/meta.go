@@ -0,0 +1,156 @@
+package tokens
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// APIError is a failed call to a platform token endpoint. Auth is set when
+// the platform rejected the credentials themselves, as opposed to a
+// transient or configuration failure.
+type APIError struct {
+	Platform string
+	Status   int
+	Code     string
+	Message  string
+	Auth     bool
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s token endpoint error (http %d, %s): %s", e.Platform, e.Status, e.Code, e.Message)
+}
+
+type metaTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+type metaErrorResponse struct {
+	Error struct {
+		Message string `json:"message"`
+		Code    int    `json:"code"`
+	} `json:"error"`
+}
+
+// ExchangeMeta exchanges a short-lived user token (e.g. from Graph API
+// Explorer) for a long-lived one, derives the page token for pageID from it
+// and stores both under account. The account must already hold app_id and
+// app_secret. A successful exchange clears any reauthorization flag.
+func (m *Manager) ExchangeMeta(ctx context.Context, account, pageID, shortLivedToken string) (State, error) {
+	if err := m.secrets.Put(ctx, account, KeyMetaPageID, pageID); err != nil {
+		return State{}, err
+	}
+	st := State{Account: account, Platform: PlatformMeta}
+	st, err := m.exchangeMeta(ctx, st, shortLivedToken)
+	if err != nil {
+		return State{}, err
+	}
+	return st, m.states.Put(ctx, st)
+}
+
+// refreshMeta re-exchanges the stored long-lived user token, which extends
+// it while it is still valid, and re-derives the page token.
+func (m *Manager) refreshMeta(ctx context.Context, st State) (State, error) {
+	userToken, err := m.secrets.Get(ctx, st.Account, KeyMetaUserToken)
+	if err != nil {
+		return st, err
+	}
+	return m.exchangeMeta(ctx, st, userToken)
+}
+
+func (m *Manager) exchangeMeta(ctx context.Context, st State, userToken string) (State, error) {
+	appID, err := m.secrets.Get(ctx, st.Account, KeyMetaAppID)
+	if err != nil {
+		return st, err
+	}
+	appSecret, err := m.secrets.Get(ctx, st.Account, KeyMetaAppSecret)
+	if err != nil {
+		return st, err
+	}
+	pageID, err := m.secrets.Get(ctx, st.Account, KeyMetaPageID)
+	if err != nil {
+		return st, err
+	}
+
+	var long metaTokenResponse
+	q := url.Values{
+		"grant_type":        {"fb_exchange_token"},
+		"client_id":         {appID},
+		"client_secret":     {appSecret},
+		"fb_exchange_token": {userToken},
+	}
+	if err := m.metaGet(ctx, "/oauth/access_token?"+q.Encode(), &long); err != nil {
+		return st, err
+	}
+
+	// Page tokens derived from a long-lived user token do not expire on their
+	// own, so the user token's expiry is what drives refreshes.
+	var page metaTokenResponse
+	q = url.Values{"fields": {"access_token"}, "access_token": {long.AccessToken}}
+	if err := m.metaGet(ctx, "/"+url.PathEscape(pageID)+"?"+q.Encode(), &page); err != nil {
+		return st, err
+	}
+	if page.AccessToken == "" {
+		return st, &APIError{Platform: PlatformMeta, Status: http.StatusOK, Code: "no_page_token",
+			Message: "no page token returned; is the user an admin of page " + pageID + "?", Auth: true}
+	}
+
+	if err := m.secrets.Put(ctx, st.Account, KeyMetaUserToken, long.AccessToken); err != nil {
+		return st, err
+	}
+	if err := m.secrets.Put(ctx, st.Account, KeyMetaPageToken, page.AccessToken); err != nil {
+		return st, err
+	}
+	st.ExpiresAt = m.expiry(long.ExpiresIn)
+	st.RefreshedAt = m.now().UTC()
+	st.NeedsReauth = false
+	st.Reason = ""
+	return st, nil
+}
+
+func (m *Manager) metaGet(ctx context.Context, pathAndQuery string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, m.cfg.MetaGraphURL+pathAndQuery, nil)
+	if err != nil {
+		return err
+	}
+	res, err := m.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("meta token endpoint unreachable: %w", stripURL(err))
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(res.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("reading meta token response: %w", err)
+	}
+	if res.StatusCode != http.StatusOK {
+		var e metaErrorResponse
+		_ = json.Unmarshal(body, &e)
+		return &APIError{
+			Platform: PlatformMeta,
+			Status:   res.StatusCode,
+			Code:     fmt.Sprint(e.Error.Code),
+			Message:  e.Error.Message,
+			// 190 is Meta's invalid/expired token code; 102 is an invalid session.
+			Auth: e.Error.Code == 190 || e.Error.Code == 102,
+		}
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("decoding meta token response: %w", err)
+	}
+	return nil
+}
+
+// stripURL unwraps *url.Error, whose message contains the full request URL
+// including client_secret and tokens.
+func stripURL(err error) error {
+	var uerr *url.Error
+	if errors.As(err, &uerr) {
+		return uerr.Err
+	}
+	return err
+}
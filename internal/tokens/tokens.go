@@ -0,0 +1,154 @@
+// Package tokens tracks the lifecycle of platform OAuth tokens kept in the
+// credential store: when they expire, refreshing them ahead of time, and
+// flagging accounts whose tokens can no longer be refreshed so that flows
+// stop using them until someone re-authorizes the account.
+package tokens
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Optiq-CTO/orchestrator/internal/statefile"
+)
+
+// Platforms with managed tokens.
+const (
+	PlatformMeta    = "facebook"
+	PlatformTwitter = "twitter"
+)
+
+// ErrUnknownAccount is returned for accounts without tracked token state.
+var ErrUnknownAccount = errors.New("no token state for account")
+
+// State is the lifecycle metadata of one account's token. It never holds
+// the token itself; values live in the credential store.
+type State struct {
+	Account     string    `json:"account"`
+	Platform    string    `json:"platform"`
+	ExpiresAt   time.Time `json:"expires_at,omitempty"` // zero if the token does not expire
+	RefreshedAt time.Time `json:"refreshed_at,omitempty"`
+	NeedsReauth bool      `json:"needs_reauth"`
+	Reason      string    `json:"reason,omitempty"`
+}
+
+// StateStore persists token state by account.
+type StateStore interface {
+	Get(ctx context.Context, account string) (State, error)
+	Put(ctx context.Context, st State) error
+	List(ctx context.Context) ([]State, error)
+}
+
+// MemoryStateStore keeps token state in memory.
+type MemoryStateStore struct {
+	mu     sync.Mutex
+	states map[string]State
+}
+
+// NewMemoryStateStore returns an empty in-memory store.
+func NewMemoryStateStore() *MemoryStateStore {
+	return &MemoryStateStore{states: make(map[string]State)}
+}
+
+// Get implements StateStore.
+func (m *MemoryStateStore) Get(_ context.Context, account string) (State, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	st, ok := m.states[account]
+	if !ok {
+		return State{}, fmt.Errorf("%w: %s", ErrUnknownAccount, account)
+	}
+	return st, nil
+}
+
+// Put implements StateStore.
+func (m *MemoryStateStore) Put(_ context.Context, st State) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.states[st.Account] = st
+	return nil
+}
+
+// List implements StateStore.
+func (m *MemoryStateStore) List(_ context.Context) ([]State, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return sortStates(m.states), nil
+}
+
+// FileStateStore keeps token state in a JSON file. The file holds only
+// metadata, so it is not encrypted. Every call re-reads the file under its
+// lock, so the processes on one host may share it.
+type FileStateStore struct {
+	mu   sync.Mutex
+	path string
+}
+
+// OpenFileStateStore loads the state file at path if it exists.
+func OpenFileStateStore(path string) (*FileStateStore, error) {
+	f := &FileStateStore{path: path}
+	if err := f.update(func(map[string]State) bool { return false }); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// update re-reads the state file under its lock, runs fn on its states and
+// writes them back if fn reports a change.
+func (f *FileStateStore) update(fn func(states map[string]State) bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	states := make(map[string]State)
+	if err := statefile.Update(f.path, &states, func() bool { return fn(states) }); err != nil {
+		return fmt.Errorf("token state: %w", err)
+	}
+	return nil
+}
+
+// Get implements StateStore.
+func (f *FileStateStore) Get(_ context.Context, account string) (State, error) {
+	var (
+		st State
+		ok bool
+	)
+	if err := f.update(func(states map[string]State) bool {
+		st, ok = states[account]
+		return false
+	}); err != nil {
+		return State{}, err
+	}
+	if !ok {
+		return State{}, fmt.Errorf("%w: %s", ErrUnknownAccount, account)
+	}
+	return st, nil
+}
+
+// Put implements StateStore.
+func (f *FileStateStore) Put(_ context.Context, st State) error {
+	return f.update(func(states map[string]State) bool {
+		states[st.Account] = st
+		return true
+	})
+}
+
+// List implements StateStore.
+func (f *FileStateStore) List(_ context.Context) ([]State, error) {
+	var out []State
+	err := f.update(func(states map[string]State) bool {
+		out = sortStates(states)
+		return false
+	})
+	return out, err
+}
+
+func sortStates(m map[string]State) []State {
+	out := make([]State, 0, len(m))
+	for _, st := range m {
+		out = append(out, st)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Account < out[j].Account })
+	return out
+}
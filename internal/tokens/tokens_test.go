@@ -0,0 +1,272 @@
+package tokens
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Optiq-CTO/orchestrator/internal/secrets"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func newTestManager(t *testing.T, handler http.HandlerFunc) (*Manager, secrets.Store) {
+	t.Helper()
+	sec, err := secrets.OpenFileStore(filepath.Join(t.TempDir(), "secrets.json"), bytes.Repeat([]byte{1}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	m := NewManager(sec, NewMemoryStateStore(), Config{MetaGraphURL: srv.URL, XAPIURL: srv.URL})
+	m.now = func() time.Time { return time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC) }
+	return m, sec
+}
+
+func put(t *testing.T, sec secrets.Store, account string, kv ...string) {
+	t.Helper()
+	for i := 0; i+1 < len(kv); i += 2 {
+		if err := sec.Put(context.Background(), account, kv[i], kv[i+1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestIsAuthFailure(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{errors.New("meta api error [190]: Invalid OAuth access token"), true},
+		{errors.New("invalid_grant"), true},
+		{status.Error(codes.Unauthenticated, "bad token"), true},
+		{status.Error(codes.Unavailable, "connection refused"), false},
+		{errors.New("rate limited"), false},
+	}
+	for _, tt := range tests {
+		if got := IsAuthFailure(tt.err); got != tt.want {
+			t.Errorf("IsAuthFailure(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestReportFailureAndCheck(t *testing.T) {
+	ctx := context.Background()
+	m, _ := newTestManager(t, nil)
+	if m.ReportFailure(ctx, "meta", []string{"acme"}, errors.New("timeout")) {
+		t.Error("a transient failure marked the account")
+	}
+	if m.ReportFailure(ctx, "meta", nil, status.Error(codes.Unauthenticated, "token expired")) {
+		t.Error("a failure with no stored accounts reported a mark")
+	}
+	if err := m.Check(ctx, []string{"acme"}); err != nil {
+		t.Fatalf("Check = %v", err)
+	}
+	if !m.ReportFailure(ctx, "meta", []string{"acme"}, status.Error(codes.Unauthenticated, "token expired")) {
+		t.Fatal("an auth failure did not mark the account")
+	}
+	err := m.Check(ctx, []string{"other", "acme"})
+	var reauth *ReauthError
+	if !errors.As(err, &reauth) || reauth.Account != "acme" {
+		t.Fatalf("Check = %v, want a ReauthError for acme", err)
+	}
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("Check code = %v, want FailedPrecondition", status.Code(err))
+	}
+	st, _ := m.states.Get(ctx, "acme")
+	if st.Platform != PlatformMeta {
+		t.Errorf("marked account platform = %q, want %q", st.Platform, PlatformMeta)
+	}
+
+	if err := m.ClearReauth(ctx, "acme"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Check(ctx, []string{"acme"}); err != nil {
+		t.Errorf("Check after ClearReauth = %v", err)
+	}
+	if err := m.ClearReauth(ctx, "unknown"); err != nil {
+		t.Errorf("ClearReauth(unknown) = %v", err)
+	}
+}
+
+func TestRefreshAdoptsPlatform(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name     string
+		stored   string
+		platform string
+		wantErr  string
+	}{
+		{"empty stored platform is adopted", "", PlatformTwitter, ""},
+		{"mismatch is refused", PlatformMeta, PlatformTwitter, "tracked as facebook"},
+		{"unknown platform", "", "", "not supported"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, _ := newTestManager(t, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			})
+			if err := m.states.Put(ctx, State{Account: "acme", Platform: tt.stored, NeedsReauth: true}); err != nil {
+				t.Fatal(err)
+			}
+			_, err := m.Refresh(ctx, "acme", tt.platform)
+			if err == nil {
+				t.Fatal("Refresh succeeded without credentials")
+			}
+			if tt.wantErr != "" {
+				if !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Refresh = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if strings.Contains(err.Error(), "tracked as") || strings.Contains(err.Error(), "not supported") {
+				t.Errorf("Refresh did not adopt %s: %v", tt.platform, err)
+			}
+		})
+	}
+}
+
+func TestRefreshX(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name        string
+		status      int
+		body        any
+		wantErr     bool
+		wantReauth  bool
+		wantRefresh string
+	}{
+		{"rotates tokens", http.StatusOK, map[string]any{"access_token": "new-access", "refresh_token": "new-refresh", "expires_in": 7200}, false, false, "new-refresh"},
+		{"rejected", http.StatusBadRequest, map[string]any{"error": "invalid_grant", "error_description": "revoked"}, true, true, "old-refresh"},
+		{"unavailable", http.StatusServiceUnavailable, map[string]any{}, true, false, "old-refresh"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, sec := newTestManager(t, func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/2/oauth2/token" || r.FormValue("refresh_token") != "old-refresh" {
+					http.Error(w, "unexpected request", http.StatusNotFound)
+					return
+				}
+				w.WriteHeader(tt.status)
+				json.NewEncoder(w).Encode(tt.body)
+			})
+			put(t, sec, "acme", KeyXClientID, "client", KeyXRefreshToken, "old-refresh")
+			st, err := m.Refresh(ctx, "acme", PlatformTwitter)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Refresh error = %v, want error %v", err, tt.wantErr)
+			}
+			if got, _ := sec.Get(ctx, "acme", KeyXRefreshToken); got != tt.wantRefresh {
+				t.Errorf("refresh token = %q, want %q", got, tt.wantRefresh)
+			}
+			if !tt.wantErr && !st.ExpiresAt.Equal(m.now().Add(2*time.Hour)) {
+				t.Errorf("ExpiresAt = %v", st.ExpiresAt)
+			}
+			if got := m.Check(ctx, []string{"acme"}) != nil; got != tt.wantReauth {
+				t.Errorf("needs reauth = %v, want %v", got, tt.wantReauth)
+			}
+		})
+	}
+}
+
+func TestRefreshPlatformMismatch(t *testing.T) {
+	ctx := context.Background()
+	m, _ := newTestManager(t, nil)
+	m.states.Put(ctx, State{Account: "acme", Platform: PlatformMeta})
+	if _, err := m.Refresh(ctx, "acme", PlatformTwitter); err == nil {
+		t.Error("Refresh accepted a platform other than the tracked one")
+	}
+	if _, err := m.Refresh(ctx, "new", "myspace"); err == nil {
+		t.Error("Refresh accepted an unsupported platform")
+	}
+}
+
+func TestExchangeMeta(t *testing.T) {
+	ctx := context.Background()
+	m, sec := newTestManager(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/oauth/access_token":
+			if r.URL.Query().Get("fb_exchange_token") != "short" {
+				http.Error(w, `{"error":{"message":"Invalid OAuth access token","code":190}}`, http.StatusBadRequest)
+				return
+			}
+			fmt.Fprint(w, `{"access_token":"long-user","expires_in":5184000}`)
+		case "/page-1":
+			fmt.Fprint(w, `{"access_token":"page-token"}`)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	put(t, sec, "acme", KeyMetaAppID, "app", KeyMetaAppSecret, "shh")
+	m.states.Put(ctx, State{Account: "acme", Platform: PlatformMeta, NeedsReauth: true, Reason: "expired"})
+
+	st, err := m.ExchangeMeta(ctx, "acme", "page-1", "short")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.NeedsReauth || st.ExpiresAt.IsZero() {
+		t.Errorf("state after exchange = %+v", st)
+	}
+	if got, _ := sec.Get(ctx, "acme", KeyMetaPageToken); got != "page-token" {
+		t.Errorf("page token = %q", got)
+	}
+	if _, err := m.ExchangeMeta(ctx, "acme", "page-1", "wrong"); err == nil {
+		t.Error("exchange with a rejected token succeeded")
+	}
+}
+
+func TestRefreshDue(t *testing.T) {
+	ctx := context.Background()
+	calls := 0
+	m, sec := newTestManager(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		fmt.Fprint(w, `{"access_token":"a","refresh_token":"r","expires_in":7200}`)
+	})
+	now := m.now()
+	for _, st := range []State{
+		{Account: "due", Platform: PlatformTwitter, ExpiresAt: now.Add(time.Minute)},
+		{Account: "later", Platform: PlatformTwitter, ExpiresAt: now.Add(time.Hour)},
+		{Account: "never", Platform: PlatformTwitter},
+		{Account: "blocked", Platform: PlatformTwitter, ExpiresAt: now, NeedsReauth: true},
+	} {
+		put(t, sec, st.Account, KeyXClientID, "c", KeyXRefreshToken, "old")
+		m.states.Put(ctx, st)
+	}
+	m.RefreshDue(ctx)
+	if calls != 1 {
+		t.Errorf("refreshed %d accounts, want 1", calls)
+	}
+	if st, _ := m.states.Get(ctx, "due"); !st.ExpiresAt.Equal(now.Add(2 * time.Hour)) {
+		t.Errorf("due account not refreshed: %+v", st)
+	}
+}
+
+func TestFileStateStore(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "tokens.json")
+	s, err := OpenFileStateStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get(ctx, "acme"); !errors.Is(err, ErrUnknownAccount) {
+		t.Errorf("Get(unknown) = %v", err)
+	}
+	s.Put(ctx, State{Account: "b", Platform: PlatformMeta})
+	s.Put(ctx, State{Account: "a", Platform: PlatformTwitter, NeedsReauth: true})
+	reopened, err := OpenFileStateStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	list, _ := reopened.List(ctx)
+	if len(list) != 2 || list[0].Account != "a" || !list[0].NeedsReauth {
+		t.Errorf("List = %+v", list)
+	}
+}
@@ -0,0 +1,91 @@
+package tokens
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type xTokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+type xErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// refreshX exchanges the stored OAuth2 refresh token for a new user access
+// token. X rotates refresh tokens, so the new one replaces the old.
+func (m *Manager) refreshX(ctx context.Context, st State) (State, error) {
+	clientID, err := m.secrets.Get(ctx, st.Account, KeyXClientID)
+	if err != nil {
+		return st, err
+	}
+	refreshToken, err := m.secrets.Get(ctx, st.Account, KeyXRefreshToken)
+	if err != nil {
+		return st, err
+	}
+	// Confidential clients authenticate with basic auth; public clients
+	// send only client_id.
+	clientSecret, _ := m.secrets.Get(ctx, st.Account, KeyXClientSecret)
+
+	form := url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {refreshToken},
+		"client_id":     {clientID},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.cfg.XAPIURL+"/2/oauth2/token", strings.NewReader(form.Encode()))
+	if err != nil {
+		return st, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if clientSecret != "" {
+		req.SetBasicAuth(clientID, clientSecret)
+	}
+
+	res, err := m.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return st, fmt.Errorf("x token endpoint unreachable: %w", stripURL(err))
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(res.Body, 1<<20))
+	if err != nil {
+		return st, fmt.Errorf("reading x token response: %w", err)
+	}
+	if res.StatusCode != http.StatusOK {
+		var e xErrorResponse
+		_ = json.Unmarshal(body, &e)
+		return st, &APIError{
+			Platform: PlatformTwitter,
+			Status:   res.StatusCode,
+			Code:     e.Error,
+			Message:  e.ErrorDescription,
+			Auth:     e.Error == "invalid_grant" || e.Error == "invalid_client" || res.StatusCode == http.StatusUnauthorized,
+		}
+	}
+	var tok xTokenResponse
+	if err := json.Unmarshal(body, &tok); err != nil {
+		return st, fmt.Errorf("decoding x token response: %w", err)
+	}
+
+	if err := m.secrets.Put(ctx, st.Account, KeyXUserAccessToken, tok.AccessToken); err != nil {
+		return st, err
+	}
+	if tok.RefreshToken != "" {
+		if err := m.secrets.Put(ctx, st.Account, KeyXRefreshToken, tok.RefreshToken); err != nil {
+			return st, err
+		}
+	}
+	st.ExpiresAt = m.expiry(tok.ExpiresIn)
+	st.RefreshedAt = m.now().UTC()
+	st.NeedsReauth = false
+	st.Reason = ""
+	return st, nil
+}
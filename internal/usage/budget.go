@@ -0,0 +1,200 @@
+package usage
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/yaml.v3"
+)
+
+// What happens to runs once a budget is exhausted.
+const (
+	ActionBlock     = "block"
+	ActionDowngrade = "downgrade"
+)
+
+// Config is the budgets section of the server configuration.
+//
+//	budgets:
+//	  - name: acme-openai-daily
+//	    tenant: acme
+//	    model_provider: openai
+//	    period: day
+//	    max_calls: 200
+//	    on_exhausted: downgrade
+//	    downgrade_to: gemini
+//	  - name: health-page-monthly
+//	    account: health-page
+//	    period: month
+//	    max_tokens: 2000000
+type Config struct {
+	Budgets []Budget `yaml:"budgets"`
+}
+
+// Budget caps the usage of everything matching its scope within one day or
+// month. Empty scope fields match everything, and usage is summed across
+// all matches: a budget naming only a provider caps that provider for the
+// whole server.
+type Budget struct {
+	Name          string `yaml:"name"`
+	Tenant        string `yaml:"tenant"`
+	Account       string `yaml:"account"`
+	Flow          string `yaml:"flow"`
+	ModelProvider string `yaml:"model_provider"`
+	Period        string `yaml:"period"`
+	// Zero limits are not enforced.
+	MaxCalls    int64  `yaml:"max_calls"`
+	MaxTokens   int64  `yaml:"max_tokens"`
+	OnExhausted string `yaml:"on_exhausted"` // "block" (default) or "downgrade"
+	DowngradeTo string `yaml:"downgrade_to"`
+}
+
+func (b Budget) covers(k Key) bool {
+	return (b.Tenant == "" || b.Tenant == k.Tenant) &&
+		(b.Account == "" || b.Account == k.Account) &&
+		(b.Flow == "" || b.Flow == k.Flow) &&
+		(b.ModelProvider == "" || b.ModelProvider == k.Provider)
+}
+
+func (b Budget) exhausted(c Counts) bool {
+	return (b.MaxCalls > 0 && c.Calls >= b.MaxCalls) ||
+		(b.MaxTokens > 0 && c.Tokens() >= b.MaxTokens)
+}
+
+// LoadConfig reads and validates a budgets file.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading budgets file: %w", err)
+	}
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parsing budgets file: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// Validate checks every budget and fills in defaults.
+func (c *Config) Validate() error {
+	names := make(map[string]bool)
+	for i := range c.Budgets {
+		b := &c.Budgets[i]
+		if b.Name == "" {
+			return fmt.Errorf("budgets[%d]: name is required", i)
+		}
+		if names[b.Name] {
+			return fmt.Errorf("duplicate budget %q", b.Name)
+		}
+		names[b.Name] = true
+		if b.Period != PeriodDay && b.Period != PeriodMonth {
+			return fmt.Errorf("budget %q: period must be %q or %q", b.Name, PeriodDay, PeriodMonth)
+		}
+		if b.MaxCalls <= 0 && b.MaxTokens <= 0 {
+			return fmt.Errorf("budget %q: set max_calls, max_tokens or both", b.Name)
+		}
+		switch b.OnExhausted {
+		case "":
+			b.OnExhausted = ActionBlock
+		case ActionBlock:
+		case ActionDowngrade:
+			if b.DowngradeTo == "" {
+				return fmt.Errorf("budget %q: downgrade needs downgrade_to", b.Name)
+			}
+			if b.DowngradeTo == b.ModelProvider {
+				return fmt.Errorf("budget %q: cannot downgrade %s to itself", b.Name, b.DowngradeTo)
+			}
+		default:
+			return fmt.Errorf("budget %q: on_exhausted must be %q or %q", b.Name, ActionBlock, ActionDowngrade)
+		}
+	}
+	return nil
+}
+
+// ExhaustedError is returned for runs blocked by a budget.
+type ExhaustedError struct {
+	Budget string
+	Period string
+	Used   Counts
+}
+
+func (e *ExhaustedError) Error() string {
+	return fmt.Sprintf("ai budget %s exhausted for this %s (%d calls, %d tokens used)",
+		e.Budget, e.Period, e.Used.Calls, e.Used.Tokens())
+}
+
+// GRPCStatus lets the error cross the API boundary as ResourceExhausted.
+func (e *ExhaustedError) GRPCStatus() *status.Status {
+	return status.New(codes.ResourceExhausted, e.Error())
+}
+
+// Decision is the outcome of a budget check.
+type Decision struct {
+	// Provider is the model provider the run should use.
+	Provider string
+	// DowngradedBy names the budget that moved the run off the requested
+	// provider, if any.
+	DowngradedBy string
+}
+
+// Check decides whether a run attributed to k may start. Runs matching an
+// exhausted downgrade budget move to its cheaper provider, which is checked
+// in turn; runs matching an exhausted block budget fail with
+// *ExhaustedError. Budgets are checked before a run, so one run may take
+// usage slightly past a limit.
+func (t *Tracker) Check(k Key) (Decision, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.now()
+	d := Decision{Provider: k.Provider}
+	tried := map[string]bool{k.Provider: true}
+	for {
+		b, used, ok := t.firstExhausted(k, now)
+		if !ok {
+			return d, nil
+		}
+		if b.OnExhausted != ActionDowngrade || tried[b.DowngradeTo] {
+			return d, &ExhaustedError{Budget: b.Name, Period: b.Period, Used: used}
+		}
+		tried[b.DowngradeTo] = true
+		k.Provider = b.DowngradeTo
+		d = Decision{Provider: b.DowngradeTo, DowngradedBy: b.Name}
+	}
+}
+
+func (t *Tracker) firstExhausted(k Key, now time.Time) (Budget, Counts, bool) {
+	for _, b := range t.budgets {
+		if !b.covers(k) {
+			continue
+		}
+		if used := t.sum(b, now); b.exhausted(used) {
+			return b, used, true
+		}
+	}
+	return Budget{}, Counts{}, false
+}
+
+// BudgetStatus is a budget with its usage in the current period.
+type BudgetStatus struct {
+	Budget
+	Used      Counts
+	Exhausted bool
+}
+
+// Budgets reports every budget's current usage.
+func (t *Tracker) Budgets() []BudgetStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.now()
+	out := make([]BudgetStatus, 0, len(t.budgets))
+	for _, b := range t.budgets {
+		used := t.sum(b, now)
+		out = append(out, BudgetStatus{Budget: b, Used: used, Exhausted: b.exhausted(used)})
+	}
+	return out
+}
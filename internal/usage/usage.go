@@ -0,0 +1,292 @@
+// Package usage accounts for AI calls (creator generation and the analysis
+// fetcher runs on fetched items) per tenant, account, flow and model
+// provider, and enforces daily and monthly budgets on them.
+package usage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Optiq-CTO/orchestrator/internal/metrics"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// Periods usage is bucketed and budgeted by, in UTC.
+const (
+	PeriodDay   = "day"
+	PeriodMonth = "month"
+)
+
+// Response metadata keys downstreams may set to report token counts. Calls
+// without them are counted with zero tokens.
+const (
+	InputTokensHeader  = "x-usage-input-tokens"
+	OutputTokensHeader = "x-usage-output-tokens"
+)
+
+// Key attributes usage to whoever caused it.
+type Key struct {
+	Tenant   string `json:"tenant,omitempty"`
+	Account  string `json:"account,omitempty"`
+	Flow     string `json:"flow"`
+	Provider string `json:"model_provider"`
+}
+
+// Counts is the usage accumulated under one key in one period.
+type Counts struct {
+	Calls        int64 `json:"calls"`
+	InputTokens  int64 `json:"input_tokens,omitempty"`
+	OutputTokens int64 `json:"output_tokens,omitempty"`
+}
+
+// Tokens is the total token count.
+func (c Counts) Tokens() int64 { return c.InputTokens + c.OutputTokens }
+
+func (c *Counts) add(o Counts) {
+	c.Calls += o.Calls
+	c.InputTokens += o.InputTokens
+	c.OutputTokens += o.OutputTokens
+}
+
+// Entry is one row of a usage report.
+type Entry struct {
+	Key
+	Counts
+	// Period is "day" or "month"; Start is the first day of the period.
+	Period string    `json:"period"`
+	Start  time.Time `json:"start"`
+}
+
+// PeriodStart returns the start of the period containing t, in UTC.
+func PeriodStart(period string, t time.Time) time.Time {
+	t = t.UTC()
+	if period == PeriodMonth {
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+type bucket struct {
+	Key
+	Period string    `json:"period"`
+	Start  time.Time `json:"start"`
+}
+
+// Tracker accumulates usage and checks it against budgets. It is safe for
+// concurrent use.
+type Tracker struct {
+	mu      sync.Mutex
+	path    string
+	counts  map[bucket]*Counts
+	budgets []Budget
+	now     func() time.Time
+}
+
+// NewTracker returns a tracker that keeps usage in memory only.
+func NewTracker(budgets []Budget) *Tracker {
+	return &Tracker{counts: make(map[bucket]*Counts), budgets: budgets, now: time.Now}
+}
+
+// OpenTracker returns a tracker that persists usage to a JSON file at path,
+// loading it first if it exists, so budgets survive restarts.
+func OpenTracker(path string, budgets []Budget) (*Tracker, error) {
+	t := NewTracker(budgets)
+	t.path = path
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return t, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading usage file: %w", err)
+	}
+	var entries []Entry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("decoding usage file: %w", err)
+	}
+	for _, e := range entries {
+		c := e.Counts
+		t.counts[bucket{Key: e.Key, Period: e.Period, Start: e.Start}] = &c
+	}
+	return t, nil
+}
+
+// Record adds usage under k to the current day and month.
+func (t *Tracker) Record(k Key, c Counts) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.now()
+	for _, period := range []string{PeriodDay, PeriodMonth} {
+		b := bucket{Key: k, Period: period, Start: PeriodStart(period, now)}
+		cur, ok := t.counts[b]
+		if !ok {
+			cur = &Counts{}
+			t.counts[b] = cur
+		}
+		cur.add(c)
+	}
+	return t.persist(now)
+}
+
+// persist prunes buckets no budget can look at any more and writes the rest.
+// Callers hold t.mu.
+func (t *Tracker) persist(now time.Time) error {
+	cutoff := PeriodStart(PeriodMonth, now).AddDate(0, -1, 0)
+	for b := range t.counts {
+		if b.Start.Before(cutoff) {
+			delete(t.counts, b)
+		}
+	}
+	if t.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(t.entries(Filter{}), "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding usage: %w", err)
+	}
+	tmp := t.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("writing usage file: %w", err)
+	}
+	if err := os.Rename(tmp, t.path); err != nil {
+		return fmt.Errorf("writing usage file: %w", err)
+	}
+	return nil
+}
+
+// Filter narrows usage reports. Zero fields match everything.
+type Filter struct {
+	Tenant   string
+	Account  string
+	Provider string
+	Period   string
+	// At selects the period containing this time; zero means all retained
+	// periods.
+	At time.Time
+}
+
+func (f Filter) match(b bucket) bool {
+	return (f.Tenant == "" || b.Tenant == f.Tenant) &&
+		(f.Account == "" || b.Account == f.Account) &&
+		(f.Provider == "" || b.Provider == f.Provider) &&
+		(f.Period == "" || b.Period == f.Period) &&
+		(f.At.IsZero() || f.Period == "" || b.Start.Equal(PeriodStart(f.Period, f.At)))
+}
+
+// Usage lists matching usage, newest period first.
+func (t *Tracker) Usage(f Filter) []Entry {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.entries(f)
+}
+
+func (t *Tracker) entries(f Filter) []Entry {
+	var out []Entry
+	for b, c := range t.counts {
+		if f.match(b) {
+			out = append(out, Entry{Key: b.Key, Counts: *c, Period: b.Period, Start: b.Start})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if !a.Start.Equal(b.Start) {
+			return a.Start.After(b.Start)
+		}
+		if a.Period != b.Period {
+			return a.Period < b.Period
+		}
+		if a.Tenant != b.Tenant {
+			return a.Tenant < b.Tenant
+		}
+		if a.Account != b.Account {
+			return a.Account < b.Account
+		}
+		if a.Flow != b.Flow {
+			return a.Flow < b.Flow
+		}
+		return a.Provider < b.Provider
+	})
+	return out
+}
+
+// sum totals usage in the current period matching a budget's scope.
+// Callers hold t.mu.
+func (t *Tracker) sum(bg Budget, now time.Time) Counts {
+	start := PeriodStart(bg.Period, now)
+	var total Counts
+	for b, c := range t.counts {
+		if b.Period == bg.Period && b.Start.Equal(start) && bg.covers(b.Key) {
+			total.add(*c)
+		}
+	}
+	return total
+}
+
+type ctxKey struct{}
+
+// WithKey attributes AI calls made with the returned context to k.
+func WithKey(ctx context.Context, k Key) context.Context {
+	return context.WithValue(ctx, ctxKey{}, k)
+}
+
+// KeyFromContext returns the attribution set by WithKey.
+func KeyFromContext(ctx context.Context) (Key, bool) {
+	k, ok := ctx.Value(ctxKey{}).(Key)
+	return k, ok
+}
+
+// WithoutKey returns a context whose calls are not attributed, for calls
+// whose usage the caller records itself.
+func WithoutKey(ctx context.Context) context.Context {
+	return context.WithValue(ctx, ctxKey{}, nil)
+}
+
+// Observe records usage under k and exports it as metrics.
+func (t *Tracker) Observe(k Key, c Counts) {
+	// A failed write only loses persistence, not the in-memory count.
+	_ = t.Record(k, c)
+	metrics.AICalls.Add(float64(c.Calls), k.Flow, k.Provider)
+	if n := c.Tokens(); n > 0 {
+		metrics.AITokens.Add(float64(n), k.Flow, k.Provider)
+	}
+}
+
+// UnaryClientInterceptor records every call made on a downstream connection
+// whose calls cost AI usage. Calls without an attributed context, such as
+// preflight probes, are not recorded. Token counts are read from response
+// headers or trailers when the downstream reports them.
+func (t *Tracker) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		k, ok := KeyFromContext(ctx)
+		if !ok {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+		var header, trailer metadata.MD
+		opts = append(opts, grpc.Header(&header), grpc.Trailer(&trailer))
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		t.Observe(k, Counts{
+			Calls:        1,
+			InputTokens:  mdInt(header, trailer, InputTokensHeader),
+			OutputTokens: mdInt(header, trailer, OutputTokensHeader),
+		})
+		return err
+	}
+}
+
+func mdInt(header, trailer metadata.MD, key string) int64 {
+	for _, md := range []metadata.MD{header, trailer} {
+		if v := md.Get(key); len(v) > 0 {
+			if n, err := strconv.ParseInt(v[0], 10, 64); err == nil && n > 0 {
+				return n
+			}
+		}
+	}
+	return 0
+}
@@ -0,0 +1,180 @@
+package usage
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		budget  Budget
+		wantErr string
+	}{
+		{"valid", Budget{Name: "b", Period: PeriodDay, MaxCalls: 1}, ""},
+		{"no name", Budget{Period: PeriodDay, MaxCalls: 1}, "name is required"},
+		{"bad period", Budget{Name: "b", Period: "week", MaxCalls: 1}, "period must be"},
+		{"no limits", Budget{Name: "b", Period: PeriodMonth}, "set max_calls, max_tokens or both"},
+		{"downgrade without target", Budget{Name: "b", Period: PeriodDay, MaxCalls: 1, OnExhausted: ActionDowngrade}, "needs downgrade_to"},
+		{"downgrade to itself", Budget{Name: "b", Period: PeriodDay, MaxCalls: 1, ModelProvider: "openai", OnExhausted: ActionDowngrade, DowngradeTo: "openai"}, "to itself"},
+		{"bad action", Budget{Name: "b", Period: PeriodDay, MaxCalls: 1, OnExhausted: "warn"}, "on_exhausted must be"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{Budgets: []Budget{tt.budget}}
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if cfg.Budgets[0].OnExhausted != ActionBlock {
+					t.Errorf("OnExhausted defaulted to %q, want block", cfg.Budgets[0].OnExhausted)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Validate = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+	dup := &Config{Budgets: []Budget{{Name: "b", Period: PeriodDay, MaxCalls: 1}, {Name: "b", Period: PeriodDay, MaxCalls: 1}}}
+	if err := dup.Validate(); err == nil {
+		t.Error("duplicate budget names were accepted")
+	}
+}
+
+func TestCheck(t *testing.T) {
+	budgets := []Budget{
+		{Name: "acme-openai", Tenant: "acme", ModelProvider: "openai", Period: PeriodDay, MaxCalls: 2, OnExhausted: ActionDowngrade, DowngradeTo: "gemini"},
+		{Name: "acme-gemini", Tenant: "acme", ModelProvider: "gemini", Period: PeriodDay, MaxCalls: 1, OnExhausted: ActionBlock},
+		{Name: "page-tokens", Account: "page", Period: PeriodMonth, MaxTokens: 100, OnExhausted: ActionBlock},
+	}
+	openai := Key{Tenant: "acme", Account: "x", Flow: "twitter_echo", Provider: "openai"}
+	gemini := Key{Tenant: "acme", Account: "x", Flow: "twitter_echo", Provider: "gemini"}
+	page := Key{Tenant: "beta", Account: "page", Flow: "facebook_echo", Provider: "openai"}
+	tests := []struct {
+		name         string
+		record       map[Key]Counts
+		key          Key
+		wantProvider string
+		wantBy       string
+		wantErr      string
+	}{
+		{"within budget", nil, openai, "openai", "", ""},
+		{"downgraded", map[Key]Counts{openai: {Calls: 2}}, openai, "gemini", "acme-openai", ""},
+		{"downgrade target exhausted", map[Key]Counts{openai: {Calls: 2}, gemini: {Calls: 1}}, openai, "", "", "acme-gemini"},
+		{"tokens exhausted", map[Key]Counts{page: {Calls: 1, InputTokens: 60, OutputTokens: 40}}, page, "", "", "page-tokens"},
+		{"other tenant unaffected", map[Key]Counts{openai: {Calls: 5}}, Key{Tenant: "beta", Provider: "openai"}, "openai", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := NewTracker(budgets)
+			for k, c := range tt.record {
+				tr.Record(k, c)
+			}
+			d, err := tr.Check(tt.key)
+			if tt.wantErr != "" {
+				var ex *ExhaustedError
+				if !errors.As(err, &ex) || ex.Budget != tt.wantErr {
+					t.Fatalf("Check error = %v, want budget %s exhausted", err, tt.wantErr)
+				}
+				if status.Code(err) != codes.ResourceExhausted {
+					t.Errorf("code = %v, want ResourceExhausted", status.Code(err))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d.Provider != tt.wantProvider || d.DowngradedBy != tt.wantBy {
+				t.Errorf("Check = %+v, want provider %s downgraded by %q", d, tt.wantProvider, tt.wantBy)
+			}
+		})
+	}
+}
+
+func TestPeriods(t *testing.T) {
+	tr := NewTracker([]Budget{{Name: "daily", Period: PeriodDay, MaxCalls: 1}})
+	now := time.Date(2026, 3, 31, 23, 0, 0, 0, time.UTC)
+	tr.now = func() time.Time { return now }
+	k := Key{Flow: "twitter_echo", Provider: "gemini"}
+	tr.Record(k, Counts{Calls: 1})
+	if _, err := tr.Check(k); err == nil {
+		t.Fatal("daily budget not exhausted")
+	}
+	now = now.Add(2 * time.Hour) // April 1st
+	if _, err := tr.Check(k); err != nil {
+		t.Fatalf("daily budget not reset the next day: %v", err)
+	}
+	if got := tr.Usage(Filter{Period: PeriodMonth, At: now}); len(got) != 0 {
+		t.Errorf("usage for April = %+v, want none", got)
+	}
+	if got := tr.Usage(Filter{Period: PeriodMonth}); len(got) != 1 || got[0].Start.Month() != time.March {
+		t.Errorf("monthly usage = %+v", got)
+	}
+}
+
+func TestOpenTrackerPersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "usage.json")
+	tr, err := OpenTracker(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k := Key{Tenant: "acme", Flow: "twitter_echo", Provider: "gemini"}
+	tr.Record(k, Counts{Calls: 1, InputTokens: 10})
+	tr.Record(k, Counts{Calls: 1, OutputTokens: 5})
+	reopened, err := OpenTracker(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := reopened.Usage(Filter{Tenant: "acme", Period: PeriodDay})
+	if len(got) != 1 || got[0].Calls != 2 || got[0].Tokens() != 15 {
+		t.Errorf("reloaded usage = %+v", got)
+	}
+}
+
+func TestUnaryClientInterceptor(t *testing.T) {
+	tr := NewTracker(nil)
+	intercept := tr.UnaryClientInterceptor()
+	invoke := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		for _, o := range opts {
+			if h, ok := o.(grpc.HeaderCallOption); ok {
+				*h.HeaderAddr = metadata.Pairs(InputTokensHeader, "7", OutputTokensHeader, "3")
+			}
+		}
+		return nil
+	}
+	k := Key{Account: "acme", Flow: "twitter_echo", Provider: "gemini"}
+	if err := intercept(context.Background(), "/m", nil, nil, nil, invoke); err != nil {
+		t.Fatal(err)
+	}
+	if got := tr.Usage(Filter{}); len(got) != 0 {
+		t.Fatalf("an unattributed call was recorded: %+v", got)
+	}
+	if err := intercept(WithKey(context.Background(), k), "/m", nil, nil, nil, invoke); err != nil {
+		t.Fatal(err)
+	}
+	got := tr.Usage(Filter{Account: "acme", Period: PeriodDay})
+	if len(got) != 1 || got[0].Calls != 1 || got[0].InputTokens != 7 || got[0].OutputTokens != 3 {
+		t.Errorf("recorded usage = %+v", got)
+	}
+
+	// Calls the caller accounts for itself are left to Observe.
+	if err := intercept(WithoutKey(WithKey(context.Background(), k)), "/m", nil, nil, nil, invoke); err != nil {
+		t.Fatal(err)
+	}
+	tr.Observe(k, Counts{Calls: 2})
+	got = tr.Usage(Filter{Account: "acme", Period: PeriodDay})
+	if len(got) != 1 || got[0].Calls != 3 || got[0].InputTokens != 7 {
+		t.Errorf("usage after Observe = %+v", got)
+	}
+}